@@ -2,8 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"mtuci-task-manager/bot"
 )
@@ -13,48 +21,415 @@ import (
 // Содержит ссылку на общее хранилище задач и токен бота
 // ============================================================
 type Server struct {
-	storage  *bot.Storage // Общее хранилище задач (то же, что использует бот)
-	botToken string       // Токен бота (для валидации initData)
+	storage  bot.TaskStore  // Общее хранилище задач (то же, что использует бот)
+	botToken string         // Токен бота (для валидации initData)
+	notifier Notifier       // Уведомления в чат (может быть nil)
+	loc      *time.Location // Часовой пояс: правила повторения без TZID, сроки в истории
+
+	trashRetention time.Duration // Сколько задачи лежат в корзине (для purge_at)
+	botUsername    string        // Имя бота для ссылок-приглашений в команду
+
+	files    *bot.FileVault // Файлы, загруженные через Mini App (может быть nil)
+	telegram TelegramFiles  // Файлы, присланные боту (может быть nil)
+}
+
+// Notifier — отправка уведомлений пользователю в Telegram
+// Реализуется *bot.Bot; API пользуется им, когда изменение из Mini App
+// касается не только самой задачи (например, разблокирует другие)
+type Notifier interface {
+	NotifyUnblocked(space int64, tasks []bot.Task)
+	NotifyAssigned(space int64, task bot.Task)
+	NotifyComment(space int64, task bot.Task, comment bot.Comment)
+}
+
+// TelegramFiles — ссылки на файлы, присланные боту (вложения с file_id)
+// Реализуется *bot.Bot; ссылка содержит токен бота, поэтому API
+// не отдаёт её клиенту, а скачивает файл сам
+type TelegramFiles interface {
+	FileURL(fileID string) (string, error)
+}
+
+// Options — необязательные настройки API-сервера
+type Options struct {
+	Notifier Notifier       // nil — уведомления не отправляются
+	Location *time.Location // Часовой пояс (nil — время сервера), как у бота
+
+	// Срок хранения в корзине, как у бота (0 — bot.DefaultTrashRetention)
+	TrashRetention time.Duration
+
+	// Имя бота без "@" — для ссылок-приглашений в команду
+	// (пусто — в ответах только код приглашения)
+	BotUsername string
+
+	// Хранилище файлов для загрузки через Mini App (nil — загрузка отключена)
+	Files *bot.FileVault
+
+	// Скачивание вложений, присланных боту (nil — такие вложения не скачать)
+	Telegram TelegramFiles
 }
 
 // NewServer создаёт новый API-сервер
-func NewServer(storage *bot.Storage, botToken string) *Server {
+func NewServer(storage bot.TaskStore, botToken string, opts Options) *Server {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	retention := opts.TrashRetention
+	if retention <= 0 {
+		retention = bot.DefaultTrashRetention
+	}
 	return &Server{
 		storage:  storage,
 		botToken: botToken,
+		notifier: opts.Notifier,
+		loc:      loc,
+
+		trashRetention: retention,
+		botUsername:    opts.BotUsername,
+
+		files:    opts.Files,
+		telegram: opts.Telegram,
 	}
 }
 
+// ============================================================
+// taskResponse — задача в ответах API
+// Поля задачи перечислены явно: служебное состояние планировщика
+// напоминаний (reminders_sent, snoozed_until) наружу не отдаём.
+// Кроме самих полей задачи — подписи для отображения:
+// "status" — стабильный код (new/progress/done или свой код пользователя),
+// "status_category" — категория статуса (todo/doing/done),
+// "status_label" — текст с эмодзи из набора пользователя ("🆕 Новая"),
+// "priority_label" — подпись приоритета ("🔴 Срочный"),
+// "checklist_progress" — {"done": 3, "total": 7}, если есть чек-лист,
+// "recurrence_label" — правило повторения по-русски ("каждую неделю по пн")
+// ============================================================
+type taskResponse struct {
+	ID          int                 `json:"id"`
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Status      string              `json:"status"`
+	Category    string              `json:"status_category"`
+	Priority    string              `json:"priority"`
+	CreatedAt   time.Time           `json:"created_at"`
+	Deadline    *time.Time          `json:"deadline,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Checklist   []bot.ChecklistItem `json:"checklist,omitempty"`
+	BlockedBy   []int               `json:"blocked_by,omitempty"`
+	Recurrence  *bot.Recurrence     `json:"recurrence,omitempty"`
+	ProjectID   int                 `json:"project_id"`
+	Assignee    int64               `json:"assignee,omitempty"`
+	AssignedBy  int64               `json:"assigned_by,omitempty"`
+	Source      *bot.TaskSource     `json:"source,omitempty"`
+	DeletedAt   *time.Time          `json:"deleted_at,omitempty"`
+
+	StatusLabel       string                 `json:"status_label"`
+	PriorityLabel     string                 `json:"priority_label"`
+	ChecklistProgress *bot.ChecklistProgress `json:"checklist_progress,omitempty"`
+	RecurrenceLabel   string                 `json:"recurrence_label,omitempty"`
+}
+
+// newTaskResponse заполняет подписи и прогресс чек-листа для задачи
+// wf — набор статусов владельца задачи (подпись статуса)
+func newTaskResponse(task bot.Task, wf bot.Workflow) taskResponse {
+	resp := taskResponse{
+		ID:            task.ID,
+		Title:         task.Title,
+		Description:   task.Description,
+		Status:        task.Status,
+		Category:      task.Category,
+		Priority:      task.Priority,
+		CreatedAt:     task.CreatedAt,
+		Deadline:      task.Deadline,
+		Tags:          task.Tags,
+		Checklist:     task.Checklist,
+		BlockedBy:     task.BlockedBy,
+		Recurrence:    task.Recurrence,
+		ProjectID:     task.ProjectID,
+		Assignee:      task.Assignee,
+		AssignedBy:    task.AssignedBy,
+		Source:        task.Source,
+		DeletedAt:     task.DeletedAt,
+		StatusLabel:   wf.Label(task.Status),
+		PriorityLabel: bot.PriorityLabel(task.Priority),
+	}
+	if progress := task.Progress(); progress.Total > 0 {
+		resp.ChecklistProgress = &progress
+	}
+	if task.Recurrence != nil {
+		resp.RecurrenceLabel = task.Recurrence.Describe()
+	}
+	return resp
+}
+
 // ============================================================
 // handleGetTasks — GET /api/tasks
 // Возвращает все задачи текущего пользователя
+// Параметры (необязательные):
+// ?sort=priority — сначала важные, затем по сроку (без параметра — в порядке создания)
+// ?tag=матан — только задачи с тегом ("#матан" тоже подходит)
+// ?project=3 — только задачи проекта (0 — «Входящие»)
 // ============================================================
 func (s *Server) handleGetTasks(w http.ResponseWriter, r *http.Request) {
-	user := r.Context().Value(userContextKey).(*TelegramUser)
+	space := spaceFrom(r)
+
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy != "" && sortBy != "priority" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверная сортировка (допустимые: priority)",
+		})
+		return
+	}
 
-	tasks := s.storage.GetTasks(user.ID)
+	projectID := -1 // Все проекты
+	if value := r.URL.Query().Get("project"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "неверный ID проекта",
+			})
+			return
+		}
+		projectID = id
+	}
+
+	tasks, err := s.storage.GetTasks(space)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		tasks = bot.FilterByTag(tasks, tag)
+	}
+	if projectID >= 0 {
+		tasks = bot.FilterByProject(tasks, projectID)
+	}
+	if sortBy == "priority" {
+		bot.SortByPriority(tasks)
+	}
 
-	// Если задач нет — возвращаем пустой массив (не null)
-	if tasks == nil {
-		tasks = []bot.Task{}
+	// make гарантирует пустой массив (не null), если задач нет
+	wf := s.workflow(space)
+	resp := make([]taskResponse, 0, len(tasks))
+	for _, task := range tasks {
+		resp = append(resp, newTaskResponse(task, wf))
 	}
 
-	writeJSON(w, http.StatusOK, tasks)
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // ============================================================
-// handleCreateTask — POST /api/tasks
-// Создаёт новую задачу
-// Тело запроса: {"title": "...", "description": "..."}
+// handleSearchTasks — GET /api/tasks/search?q=лабораторная
+// Ищет задачи по словам в названии и описании
+// (формы слова и начало слова тоже подходят: "лаб" → "Лабораторная")
+// Самые подходящие задачи — первыми
 // ============================================================
-func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleSearchTasks(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "пустой поисковый запрос (параметр q)",
+		})
+		return
+	}
+
+	tasks, err := bot.SearchTasks(s.storage, space, query)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	wf := s.workflow(space)
+	resp := make([]taskResponse, 0, len(tasks))
+	for _, task := range tasks {
+		resp = append(resp, newTaskResponse(task, wf))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ============================================================
+// handleGetAssigned — GET /api/tasks/assigned
+// Задачи из всех пространств пользователя (личного и команд), которые
+// поручены ему (?view=to_me, по умолчанию) или которые он поручил
+// другим (?view=by_me). Сначала личные, затем задачи команд;
+// "space" — куда слать X-Workspace, чтобы открыть задачу
+// ============================================================
+type assignedTaskResponse struct {
+	taskResponse
+	Space int64 `json:"space"`
+}
+
+func (s *Server) handleGetAssigned(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value(userContextKey).(*TelegramUser)
 
-	var req struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
+	find := bot.AssignedTo
+	switch r.URL.Query().Get("view") {
+	case "", "to_me":
+	case "by_me":
+		find = bot.AssignedBy
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "view — to_me или by_me",
+		})
+		return
+	}
+
+	tasks, err := find(s.storage, user.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	workflows := make(map[int64]bot.Workflow)
+	resp := make([]assignedTaskResponse, 0, len(tasks))
+	for _, st := range tasks {
+		wf, ok := workflows[st.Space]
+		if !ok {
+			wf = s.workflow(st.Space)
+			workflows[st.Space] = wf
+		}
+		resp = append(resp, assignedTaskResponse{taskResponse: newTaskResponse(st.Task, wf), Space: st.Space})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ============================================================
+// handleGetStatuses — GET /api/statuses
+// Возвращает набор статусов пользователя в порядке показа,
+// чтобы фронтенд не держал свою копию таблицы статусов:
+// [{"code": "new", "emoji": "🆕", "name": "Новая", "category": "todo",
+// "next": ["progress"], "label": "🆕 Новая"}, ...]
+// "next" — куда можно перейти (нет поля — в любой статус)
+// ============================================================
+type statusResponse struct {
+	bot.StatusDef
+	Label string `json:"label"`
+}
+
+func (s *Server) handleGetStatuses(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	wf, err := s.storage.GetWorkflow(space)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newStatusesResponse(wf))
+}
+
+// ============================================================
+// handleSetStatuses — PUT /api/statuses
+// Заменяет набор статусов пользователя
+// Тело запроса — массив статусов, как в ответе GET /api/statuses
+// (без "label"). Первый статус получают новые задачи.
+// Убрать статус, в котором есть задачи, нельзя → 409
+// ============================================================
+func (s *Server) handleSetStatuses(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	var statuses []bot.StatusDef
+	if err := json.NewDecoder(r.Body).Decode(&statuses); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный формат запроса",
+		})
+		return
+	}
+
+	wf := bot.Workflow{Statuses: statuses}
+	if err := s.storage.SetWorkflow(space, wf); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newStatusesResponse(wf))
+}
+
+// newStatusesResponse — статусы набора с подписями
+func newStatusesResponse(wf bot.Workflow) []statusResponse {
+	resp := make([]statusResponse, len(wf.Statuses))
+	for i, status := range wf.Statuses {
+		resp[i] = statusResponse{StatusDef: status, Label: status.Label()}
+	}
+	return resp
+}
+
+// ============================================================
+// handleGetPriorities — GET /api/priorities
+// Возвращает список приоритетов (код + значок + подпись)
+// от низкого к срочному
+// ============================================================
+func (s *Server) handleGetPriorities(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, bot.Priorities)
+}
+
+// ============================================================
+// handleGetTags — GET /api/tags
+// Возвращает теги пользователя с количеством задач:
+// [{"name": "матан", "count": 3}, ...] — популярные первыми
+// ============================================================
+func (s *Server) handleGetTags(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	tags, err := s.storage.GetTags(space)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if tags == nil {
+		tags = []bot.TagInfo{} // Пустой массив, а не null
+	}
+	writeJSON(w, http.StatusOK, tags)
+}
+
+// ============================================================
+// handleGetProjects — GET /api/projects
+// Возвращает проекты пользователя; первым — «Входящие» (id 0):
+// [{"id": 0, "name": "Входящие", "emoji": "📥", "archived": false,
+// "task_count": 2, ...}, ...]
+// Проекты в архиве тоже в списке (с "archived": true)
+// ============================================================
+type projectResponse struct {
+	bot.Project
+	Label     string `json:"label"`
+	TaskCount int    `json:"task_count"`
+}
+
+func (s *Server) handleGetProjects(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	projects, err := s.storage.GetProjects(space)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	tasks, err := s.storage.GetTasks(space)
+	if err != nil {
+		writeStoreError(w, err)
+		return
 	}
 
+	counts := bot.CountByProject(tasks)
+	resp := make([]projectResponse, 0, len(projects)+1)
+	for _, project := range append([]bot.Project{bot.InboxProject()}, projects...) {
+		resp = append(resp, projectResponse{Project: project, Label: project.Label(), TaskCount: counts[project.ID]})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ============================================================
+// handleCreateProject — POST /api/projects
+// Создаёт проект
+// Тело запроса: {"name": "Учёба", "emoji": "📚"} (emoji необязателен)
+// ============================================================
+func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	var req struct {
+		Name  string `json:"name"`
+		Emoji string `json:"emoji"`
+	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{
 			"error": "неверный формат запроса",
@@ -62,39 +437,148 @@ func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Title == "" {
+	project, err := s.storage.AddProject(space, bot.ProjectDraft{Name: req.Name, Emoji: req.Emoji})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, projectResponse{Project: project, Label: project.Label()})
+}
+
+// ============================================================
+// handleUpdateProject — PATCH /api/projects/{id}
+// Частично изменяет проект: {"name": "...", "emoji": "...", "archived": true}
+// «Входящие» (id 0) изменить нельзя
+// ============================================================
+func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	projectID, ok := projectIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name     *string `json:"name"`
+		Emoji    *string `json:"emoji"`
+		Archived *bool   `json:"archived"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "название задачи обязательно",
+			"error": "неверный формат запроса",
 		})
 		return
 	}
 
-	task := s.storage.AddTask(user.ID, req.Title, req.Description)
-	writeJSON(w, http.StatusCreated, task)
+	project, err := s.storage.UpdateProject(space, projectID, bot.ProjectPatch{
+		Name:     req.Name,
+		Emoji:    req.Emoji,
+		Archived: req.Archived,
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, projectResponse{Project: project, Label: project.Label()})
 }
 
 // ============================================================
-// handleUpdateStatus — PATCH /api/tasks/{id}/status
-// Обновляет статус задачи
-// Тело запроса: {"status": "new" | "progress" | "done"}
+// handleDeleteProject — DELETE /api/projects/{id}
+// Удаляет проект; его задачи переходят во «Входящие»
 // ============================================================
-func (s *Server) handleUpdateStatus(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	projectID, ok := projectIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.storage.DeleteProject(space, projectID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// ============================================================
+// handleGetWorkspaces — GET /api/workspaces
+// Команды пользователя в порядке создания. ID команды — значение
+// заголовка X-Workspace для запросов к её задачам; без заголовка
+// запросы работают с личными задачами. role — роль самого пользователя;
+// приглашение видят только те, кому можно звать людей (см. bot/permissions.go)
+// ============================================================
+type workspaceResponse struct {
+	bot.Workspace
+	Role       bot.Role `json:"role"`
+	InviteLink string   `json:"invite_link,omitempty"`
+}
+
+func (s *Server) newWorkspaceResponse(ws bot.Workspace, userID int64) workspaceResponse {
+	resp := workspaceResponse{Workspace: ws}
+	resp.Role, _ = ws.Role(userID)
+	if !resp.Role.Can(bot.PermManage) {
+		resp.InviteCode = ""
+	} else if s.botUsername != "" {
+		resp.InviteLink = bot.InviteLink(s.botUsername, ws.InviteCode)
+	}
+	return resp
+}
+
+func (s *Server) handleGetWorkspaces(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value(userContextKey).(*TelegramUser)
 
-	// Извлекаем ID задачи из URL (Go 1.22+ поддерживает {id} в путях)
-	idStr := r.PathValue("id")
-	taskID, err := strconv.Atoi(idStr)
+	workspaces, err := s.storage.GetWorkspaces(user.ID)
 	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	resp := make([]workspaceResponse, 0, len(workspaces))
+	for _, ws := range workspaces {
+		resp = append(resp, s.newWorkspaceResponse(ws, user.ID))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ============================================================
+// handleCreateWorkspace — POST /api/workspaces
+// Создаёт команду, пользователь становится её первым участником
+// Тело запроса: {"name": "Курсовой проект"}
+// ============================================================
+func (s *Server) handleCreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userContextKey).(*TelegramUser)
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "неверный ID задачи",
+			"error": "неверный формат запроса",
 		})
 		return
 	}
 
-	var req struct {
-		Status string `json:"status"`
+	ws, err := s.storage.CreateWorkspace(user.member(), req.Name)
+	if err != nil {
+		writeStoreError(w, err)
+		return
 	}
+	writeJSON(w, http.StatusCreated, s.newWorkspaceResponse(ws, user.ID))
+}
+
+// ============================================================
+// handleJoinWorkspace — POST /api/workspaces/join
+// Вступление в команду по коду из ссылки-приглашения
+// Тело запроса: {"code": "3fa85f64c2e1"} (можно и "join_3fa85f64c2e1")
+// Повторное вступление ничего не меняет
+// ============================================================
+func (s *Server) handleJoinWorkspace(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userContextKey).(*TelegramUser)
 
+	var req struct {
+		Code string `json:"code"`
+	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{
 			"error": "неверный формат запроса",
@@ -102,54 +586,1424 @@ func (s *Server) handleUpdateStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Преобразуем короткий ключ статуса в полный
-	var fullStatus string
-	switch req.Status {
-	case "new":
-		fullStatus = bot.StatusNew
-	case "progress":
-		fullStatus = bot.StatusInProgress
-	case "done":
-		fullStatus = bot.StatusDone
-	default:
+	code := strings.TrimSpace(req.Code)
+	if payload, ok := bot.ParseJoinPayload(code); ok {
+		code = payload
+	}
+
+	ws, err := s.storage.JoinWorkspace(user.member(), code)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.newWorkspaceResponse(ws, user.ID))
+}
+
+// ============================================================
+// handleLeaveWorkspace — POST /api/workspaces/{id}/leave
+// Выход из команды; её задачи остаются у остальных участников
+// ============================================================
+func (s *Server) handleLeaveWorkspace(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userContextKey).(*TelegramUser)
+
+	workspaceID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "неверный статус (допустимые: new, progress, done)",
+			"error": "неверный ID команды",
 		})
 		return
 	}
 
-	if s.storage.UpdateStatus(user.ID, taskID, fullStatus) {
-		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
-	} else {
-		writeJSON(w, http.StatusNotFound, map[string]string{
-			"error": "задача не найдена",
-		})
+	if err := s.storage.LeaveWorkspace(user.ID, workspaceID); err != nil {
+		writeStoreError(w, err)
+		return
 	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
 }
 
 // ============================================================
-// handleDeleteTask — DELETE /api/tasks/{id}
-// Удаляет задачу по ID
+// handleSetMemberRole — PATCH /api/workspaces/{id}/members/{user}
+// Меняет роль участника команды
+// Тело запроса: {"role": "admin"} (owner, admin, member или viewer;
+// "owner" — передать команду, сам пользователь станет администратором)
+// Не хватает прав — 403
 // ============================================================
-func (s *Server) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleSetMemberRole(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value(userContextKey).(*TelegramUser)
 
-	idStr := r.PathValue("id")
-	taskID, err := strconv.Atoi(idStr)
+	workspaceID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "неверный ID задачи",
+			"error": "неверный ID команды",
+		})
+		return
+	}
+	memberID, err := strconv.ParseInt(r.PathValue("user"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный ID участника",
 		})
 		return
 	}
 
-	if s.storage.DeleteTask(user.ID, taskID) {
-		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
-	} else {
-		writeJSON(w, http.StatusNotFound, map[string]string{
-			"error": "задача не найдена",
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный формат запроса",
+		})
+		return
+	}
+	role, err := bot.ParseRole(req.Role)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	ws, err := s.storage.SetMemberRole(user.ID, workspaceID, memberID, role)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.newWorkspaceResponse(ws, user.ID))
+}
+
+// ============================================================
+// handleCreateTask — POST /api/tasks
+// Создаёт новую задачу
+// Тело запроса: {"title": "...", "description": "...",
+// "deadline": "2026-12-25T18:00:00+03:00", "priority": "high",
+// "tags": ["матан", "работа"], "recurrence": "FREQ=WEEKLY;BYDAY=MO,WE",
+// "project_id": 3}
+// deadline необязателен и передаётся в формате RFC 3339,
+// priority необязателен (по умолчанию "normal"), tags — тоже,
+// recurrence — правило повторения в стиле RRULE (см. bot/recurrence.go),
+// project_id — проект (по умолчанию 0 — «Входящие»)
+// ============================================================
+func (s *Server) handleCreateTask(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	var req struct {
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Deadline    string   `json:"deadline"`
+		Priority    string   `json:"priority"`
+		Tags        []string `json:"tags"`
+		Recurrence  string   `json:"recurrence"`
+		ProjectID   int      `json:"project_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный формат запроса",
 		})
+		return
+	}
+
+	if req.Title == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "название задачи обязательно",
+		})
+		return
+	}
+
+	draft := bot.TaskDraft{
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    req.Priority,
+		Tags:        req.Tags,
+		ProjectID:   req.ProjectID,
+	}
+	if req.Deadline != "" {
+		deadline, err := parseDeadline(req.Deadline)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		draft.Deadline = &deadline
+	}
+	if req.Recurrence != "" {
+		rec, err := bot.ParseRecurrence(req.Recurrence, s.loc)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		draft.Recurrence = rec
+	}
+
+	task, err := s.storage.AddTask(space, actorFrom(r), draft)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newTaskResponse(task, s.workflow(space)))
+}
+
+// ============================================================
+// handleGetTask — GET /api/tasks/{id}
+// Возвращает одну задачу
+// ============================================================
+func (s *Server) handleGetTask(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	task, err := s.storage.GetTask(space, taskID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTaskResponse(task, s.workflow(space)))
+}
+
+// ============================================================
+// handleUpdateTask — PATCH /api/tasks/{id}
+// Частично изменяет задачу: меняются только переданные поля
+// Тело запроса (любое подмножество):
+// {"title": "...", "description": "...", "deadline": "<RFC 3339>",
+// "priority": "low" | "normal" | "high" | "urgent", "tags": [...],
+// "recurrence": "FREQ=DAILY;INTERVAL=2", "project_id": 3,
+// "assignee_id": 123456789}
+// "deadline": null убирает срок, "tags": [] убирает все теги,
+// "recurrence": null убирает повторение, "assignee_id": 0 снимает
+// исполнителя (назначить можно только участника команды)
+// ============================================================
+func (s *Server) handleUpdateTask(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userContextKey).(*TelegramUser)
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Title       *string         `json:"title"`
+		Description *string         `json:"description"`
+		Deadline    json.RawMessage `json:"deadline"` // Отсутствует / null / строка
+		Priority    *string         `json:"priority"`
+		Tags        *[]string       `json:"tags"`
+		Recurrence  json.RawMessage `json:"recurrence"` // Отсутствует / null / строка
+		ProjectID   *int            `json:"project_id"`
+		AssigneeID  *int64          `json:"assignee_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный формат запроса",
+		})
+		return
+	}
+
+	patch := bot.TaskPatch{
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    req.Priority,
+		Tags:        req.Tags,
+		ProjectID:   req.ProjectID,
+		Assignee:    req.AssigneeID,
+		AssignedBy:  user.ID,
+	}
+	switch {
+	case len(req.Deadline) == 0:
+		// Поле не передано — срок не меняем
+	case string(req.Deadline) == "null":
+		patch.ClearDeadline = true
+	default:
+		var raw string
+		if err := json.Unmarshal(req.Deadline, &raw); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "deadline должен быть строкой RFC 3339 или null",
+			})
+			return
+		}
+		deadline, err := parseDeadline(raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		patch.Deadline = &deadline
+	}
+	switch {
+	case len(req.Recurrence) == 0:
+		// Поле не передано — повторение не меняем
+	case string(req.Recurrence) == "null":
+		patch.ClearRecurrence = true
+	default:
+		var raw string
+		if err := json.Unmarshal(req.Recurrence, &raw); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "recurrence должен быть строкой вида FREQ=WEEKLY;BYDAY=MO или null",
+			})
+			return
+		}
+		rec, err := bot.ParseRecurrence(raw, s.loc)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		patch.Recurrence = rec
+	}
+
+	task, err := s.storage.UpdateTask(space, taskID, actorFrom(r), patch)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	// Новый исполнитель узнаёт о задаче в Telegram
+	if req.AssigneeID != nil && s.notifier != nil {
+		s.notifier.NotifyAssigned(space, task)
+	}
+	writeJSON(w, http.StatusOK, newTaskResponse(task, s.workflow(space)))
+}
+
+// ============================================================
+// handleUpdateStatus — PATCH /api/tasks/{id}/status
+// Обновляет статус задачи
+// Тело запроса: {"status": "<код статуса>"} — код из GET /api/statuses
+// Неизвестный статус → 400, запрещённый переход → 409
+// Если задачу ещё блокируют невыполненные задачи, завершающий статус
+// (категория done) даёт 409 Conflict: {"error": "...", "blocked_by": [2, 5]}
+// Для повторяющейся задачи завершение создаёт следующую — она
+// возвращается в поле "next_task"; остановленные при завершении
+// таймеры — в поле "stopped_timers"
+// ============================================================
+func (s *Server) handleUpdateStatus(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный формат запроса",
+		})
+		return
+	}
+
+	// Код статуса и переход проверяет хранилище по набору
+	// статусов пользователя (bot/status.go)
+	result, err := s.storage.UpdateStatus(space, taskID, actorFrom(r), req.Status)
+	var blocked *bot.BlockedError
+	if errors.As(err, &blocked) {
+		blockedBy := make([]int, len(blocked.Blockers))
+		for i, t := range blocked.Blockers {
+			blockedBy[i] = t.ID
+		}
+		writeJSON(w, http.StatusConflict, map[string]any{
+			"error":      blocked.Error(),
+			"blocked_by": blockedBy,
+		})
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if s.notifier != nil && len(result.Unblocked) > 0 {
+		s.notifier.NotifyUnblocked(space, result.Unblocked)
+	}
+	resp := map[string]any{"ok": true}
+	if result.Next != nil {
+		resp["next_task"] = newTaskResponse(*result.Next, s.workflow(space))
+	}
+	if len(result.StoppedTimers) > 0 {
+		members := s.members(space)
+		stopped := make([]timeEntryResponse, 0, len(result.StoppedTimers))
+		for _, e := range result.StoppedTimers {
+			stopped = append(stopped, newTimeEntryResponse(e, time.Now(), members))
+		}
+		resp["stopped_timers"] = stopped
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ============================================================
+// handleDeleteTask — DELETE /api/tasks/{id}
+// Убирает задачу в корзину; вернуть её — POST /api/tasks/{id}/restore
+// ============================================================
+func (s *Server) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.storage.DeleteTask(space, taskID, actorFrom(r)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// ============================================================
+// handleGetTrash — GET /api/trash
+// Задачи в корзине, недавно удалённые первыми
+// "purge_at" — когда задача удалится навсегда
+// ============================================================
+type trashResponse struct {
+	taskResponse
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+func (s *Server) handleGetTrash(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	tasks, err := s.storage.GetTrash(space)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	wf := s.workflow(space)
+	resp := make([]trashResponse, 0, len(tasks))
+	for _, task := range tasks {
+		resp = append(resp, trashResponse{
+			taskResponse: newTaskResponse(task, wf),
+			PurgeAt:      task.PurgeAt(s.trashRetention),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ============================================================
+// handleRestoreTask — POST /api/tasks/{id}/restore
+// Возвращает задачу из корзины; если в корзине её нет → 404
+// ============================================================
+func (s *Server) handleRestoreTask(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	task, err := s.storage.RestoreTask(space, taskID, actorFrom(r))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTaskResponse(task, s.workflow(space)))
+}
+
+// ============================================================
+// handleAddDependency — POST /api/tasks/{id}/dependencies
+// Отмечает, что задача заблокирована другой задачей
+// Тело запроса: {"blocker_id": 3}
+// Цикл зависимостей или зависимость от самой себя → 409
+// ============================================================
+func (s *Server) handleAddDependency(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		BlockerID int `json:"blocker_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный формат запроса",
+		})
+		return
+	}
+
+	task, err := s.storage.AddDependency(space, taskID, req.BlockerID, actorFrom(r))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTaskResponse(task, s.workflow(space)))
+}
+
+// ============================================================
+// handleRemoveDependency — DELETE /api/tasks/{id}/dependencies/{blocker}
+// Убирает задачу {blocker} из блокирующих
+// ============================================================
+func (s *Server) handleRemoveDependency(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	blockerID, err := strconv.Atoi(r.PathValue("blocker"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный ID блокирующей задачи",
+		})
+		return
+	}
+
+	task, err := s.storage.RemoveDependency(space, taskID, blockerID, actorFrom(r))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTaskResponse(task, s.workflow(space)))
+}
+
+// ============================================================
+// handleGetHistory — GET /api/tasks/{id}/history
+// История изменений задачи от старых событий к новым:
+// [{"id": 1, "type": "created", "at": "...", "actor": 123, "text": "задача создана"}, ...]
+// "text" — событие по-русски, как в боте
+// История удалённой задачи тоже доступна
+// ============================================================
+type eventResponse struct {
+	bot.TaskEvent
+	Text string `json:"text"`
+}
+
+func (s *Server) handleGetHistory(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	events, err := s.storage.GetHistory(space, taskID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	wf, members := s.workflow(space), s.members(space)
+	resp := make([]eventResponse, 0, len(events))
+	for _, e := range events {
+		resp = append(resp, eventResponse{TaskEvent: e, Text: e.Describe(s.loc, wf, members)})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ============================================================
+// handleGetComments — GET /api/tasks/{id}/comments
+// Комментарии к задаче от старых к новым:
+// [{"id": 1, "task_id": 3, "author": 123, "author_name": "Аня", "text": "...", "created_at": "..."}, ...]
+// author_name — имя участника команды (в личных задачах пусто)
+// ============================================================
+type commentResponse struct {
+	bot.Comment
+	AuthorName string `json:"author_name,omitempty"`
+}
+
+func newCommentResponse(c bot.Comment, members []bot.Member) commentResponse {
+	resp := commentResponse{Comment: c}
+	if members != nil {
+		resp.AuthorName = bot.MemberLabel(members, c.Author)
+	}
+	return resp
+}
+
+func (s *Server) handleGetComments(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	comments, err := s.storage.GetComments(space, taskID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	members := s.members(space)
+	resp := make([]commentResponse, 0, len(comments))
+	for _, c := range comments {
+		resp = append(resp, newCommentResponse(c, members))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ============================================================
+// handleAddComment — POST /api/tasks/{id}/comments
+// Добавляет комментарий от имени пользователя; остальные
+// участники команды получают его в Telegram
+// Тело запроса: {"text": "Сделал первую часть"}
+// ============================================================
+func (s *Server) handleAddComment(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userContextKey).(*TelegramUser)
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный формат запроса",
+		})
+		return
+	}
+
+	comment, err := s.storage.AddComment(space, taskID, user.ID, req.Text)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if s.notifier != nil {
+		if task, err := s.storage.GetTask(space, taskID); err == nil {
+			s.notifier.NotifyComment(space, task, comment)
+		}
+	}
+	writeJSON(w, http.StatusCreated, newCommentResponse(comment, s.members(space)))
+}
+
+// ============================================================
+// handleGetAttachments — GET /api/tasks/{id}/attachments
+// Вложения задачи от старых к новым:
+// [{"id": 1, "task_id": 3, "kind": "photo", "name": "photo.jpg", "size": 52311, "url": "/api/tasks/3/attachments/1", ...}, ...]
+// url — адрес для скачивания (с теми же заголовками, что и весь API);
+// author_name — имя участника команды (в личных задачах пусто)
+// ============================================================
+type attachmentResponse struct {
+	bot.Attachment
+	URL        string `json:"url"`
+	AuthorName string `json:"author_name,omitempty"`
+
+	// Где лежит файл — подробность хранения, наружу не отдаём:
+	// пустые поля перекрывают одноимённые поля bot.Attachment
+	FileID string `json:"file_id,omitempty"`
+	Blob   string `json:"blob,omitempty"`
+}
+
+func newAttachmentResponse(a bot.Attachment, members []bot.Member) attachmentResponse {
+	resp := attachmentResponse{
+		Attachment: a,
+		URL:        fmt.Sprintf("/api/tasks/%d/attachments/%d", a.TaskID, a.ID),
+	}
+	if members != nil {
+		resp.AuthorName = bot.MemberLabel(members, a.Author)
+	}
+	return resp
+}
+
+func (s *Server) handleGetAttachments(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	attachments, err := s.storage.GetAttachments(space, taskID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	members := s.members(space)
+	resp := make([]attachmentResponse, 0, len(attachments))
+	for _, a := range attachments {
+		resp = append(resp, newAttachmentResponse(a, members))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ============================================================
+// handleUploadAttachment — POST /api/tasks/{id}/attachments
+// Загружает файл: multipart/form-data с полем "file"
+// (и необязательным "caption" — подписью)
+// Файл хранится на диске сервера и занимает место в квоте того, кто
+// его загрузил: файл больше 20 МБ или сверх квоты — 413
+// ============================================================
+func (s *Server) handleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userContextKey).(*TelegramUser)
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	if s.files == nil {
+		writeStoreError(w, bot.ErrAttachmentsDisabled)
+		return
+	}
+	// Сначала задача: не записывать на диск файл, который некуда приложить
+	if _, err := s.storage.GetTask(space, taskID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	// Запас сверх размера файла — на заголовки частей и подпись
+	r.Body = http.MaxBytesReader(w, r.Body, bot.MaxAttachmentSize+64<<10)
+	// Больше мегабайта держим не в памяти, а во временном файле
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeStoreError(w, bot.ErrAttachmentTooLarge)
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "ожидается multipart/form-data с файлом в поле file",
+		})
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "нет файла в поле file",
+		})
+		return
+	}
+	defer file.Close()
+
+	key, size, err := s.files.Save(user.ID, space, taskID, file)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	mimeType := header.Header.Get("Content-Type")
+	kind := bot.AttachmentDocument
+	if strings.HasPrefix(mimeType, "image/") {
+		kind = bot.AttachmentPhoto
+	}
+	att, err := s.storage.AddAttachment(space, taskID, bot.Attachment{
+		Kind:     kind,
+		Name:     header.Filename,
+		MimeType: mimeType,
+		Size:     size,
+		Caption:  r.FormValue("caption"),
+		Blob:     key,
+		Author:   user.ID,
+	})
+	if err != nil {
+		// Вложение не сохранилось — файл занимал бы квоту зря
+		if err := s.files.Remove(key); err != nil {
+			log.Printf("❌ Ошибка удаления файла %q: %v", key, err)
+		}
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newAttachmentResponse(att, s.members(space)))
+}
+
+// ============================================================
+// handleDownloadAttachment — GET /api/tasks/{id}/attachments/{att}
+// Отдаёт сам файл: с диска сервера или (если его прислали боту)
+// из Telegram. Всегда как скачивание (Content-Disposition: attachment),
+// чтобы загруженный HTML не открылся страницей Mini App
+// ============================================================
+func (s *Server) handleDownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, attID, ok := attachmentFromPath(w, r)
+	if !ok {
+		return
+	}
+	attachments, err := s.storage.GetAttachments(space, taskID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	att, ok := bot.FindAttachment(attachments, attID)
+	if !ok {
+		writeStoreError(w, bot.ErrAttachmentNotFound)
+		return
+	}
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": att.Name})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	contentType := att.MimeType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if att.Blob != "" {
+		s.serveVaultFile(w, r, att)
+		return
+	}
+	s.serveTelegramFile(w, r, att)
+}
+
+// serveVaultFile отдаёт файл вложения с диска сервера
+func (s *Server) serveVaultFile(w http.ResponseWriter, r *http.Request, att bot.Attachment) {
+	if s.files == nil {
+		writeStoreError(w, bot.ErrAttachmentsDisabled)
+		return
+	}
+	f, err := s.files.Open(att.Blob)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	// ServeContent сам ответит на Range и If-Modified-Since
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+// serveTelegramFile скачивает файл вложения из Telegram и передаёт клиенту
+func (s *Server) serveTelegramFile(w http.ResponseWriter, r *http.Request, att bot.Attachment) {
+	if s.telegram == nil {
+		writeStoreError(w, bot.ErrAttachmentsDisabled)
+		return
+	}
+	link, err := s.telegram.FileURL(att.FileID)
+	if err != nil {
+		log.Printf("❌ Ошибка получения файла из Telegram: %v", err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{
+			"error": "не удалось получить файл из Telegram",
+		})
+		return
+	}
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, link, nil)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err == nil && resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		err = fmt.Errorf("ответ %s", resp.Status)
+	}
+	if err != nil {
+		// В ошибке с адресом был бы токен бота — в лог пишем без адреса
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		log.Printf("❌ Ошибка скачивания файла из Telegram: %v", err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{
+			"error": "не удалось получить файл из Telegram",
+		})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.ContentLength > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		log.Printf("❌ Ошибка передачи файла: %v", err)
+	}
+}
+
+// ============================================================
+// handleDeleteAttachment — DELETE /api/tasks/{id}/attachments/{att}
+// Убирает вложение; файл на диске сервера удаляется и освобождает квоту
+// ============================================================
+func (s *Server) handleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, attID, ok := attachmentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	att, err := s.storage.RemoveAttachment(space, taskID, attID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	// Не удалось стереть файл — его подчистит бот (FileVault.Prune)
+	if att.Blob != "" && s.files != nil {
+		if err := s.files.Remove(att.Blob); err != nil {
+			log.Printf("❌ Ошибка удаления файла %q: %v", att.Blob, err)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// ============================================================
+// handleGetFileUsage — GET /api/files/usage
+// Сколько места занимают файлы пользователя и сколько можно занять:
+// {"used": 3145728, "quota": 104857600}
+// Квота общая для всех пространств: считается по тому, кто загрузил файл
+// ============================================================
+func (s *Server) handleGetFileUsage(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userContextKey).(*TelegramUser)
+
+	if s.files == nil {
+		writeStoreError(w, bot.ErrAttachmentsDisabled)
+		return
+	}
+	used, err := s.files.Usage(user.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{
+		"used":  used,
+		"quota": s.files.Quota(),
+	})
+}
+
+// ============================================================
+// handleGetStats — GET /api/stats?from=2026-03-01&to=2026-03-31
+// Статистика текущего пространства (см. bot/stats.go) за дни from..to
+// включительно (ГГГГ-ММ-ДД, часовой пояс сервера). Без to — по сегодня,
+// без from — за 4 недели до to. Неверный период → 400
+// Ответ: {"from": "...", "to": "...", "created": 12, "completed": 9,
+// "avg_lead_time_seconds": 190800, "weeks": [{"week_start": "2026-03-02", "created": 3, "completed": 2}, ...],
+// "by_status": [{"status": "new", "label": "🆕 Новая", "category": "todo", "count": 4}, ...], "overdue": 2,
+// "by_member": [{"user_id": 111, "name": "Аня", "completed": 5, "open": 2}, ...]}
+// avg_lead_time_seconds — среднее время от создания до выполнения (0 — ничего не выполнено)
+// by_member — только в пространстве команды: completed — сколько участник
+// выполнил за период, open — сколько открытых задач, где он исполнитель
+// ============================================================
+type weekStatsResponse struct {
+	WeekStart string `json:"week_start"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+}
+
+type memberStatsResponse struct {
+	UserID    int64  `json:"user_id"`
+	Name      string `json:"name"`
+	Completed int    `json:"completed"`
+	Open      int    `json:"open"`
+}
+
+type statusCountResponse struct {
+	Status   string `json:"status"`
+	Label    string `json:"label"`
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+func (s *Server) handleGetStats(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+	now := time.Now().In(s.loc)
+
+	parseDay := func(name string, def time.Time) (time.Time, bool) {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			return def, true
+		}
+		day, err := time.ParseInLocation("2006-01-02", value, s.loc)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "неверная дата " + name + " (нужен формат ГГГГ-ММ-ДД)",
+			})
+			return time.Time{}, false
+		}
+		return day, true
+	}
+	to, ok := parseDay("to", now)
+	if !ok {
+		return
+	}
+	from, ok := parseDay("from", to.AddDate(0, 0, -7*bot.DefaultStatsWeeks+1))
+	if !ok {
+		return
+	}
+	from, to, err := bot.StatsPeriod(from, to, s.loc)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	stats, err := bot.LoadStats(s.storage, space, from, to, now, s.loc)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	weeks := make([]weekStatsResponse, 0, len(stats.Weeks))
+	for _, week := range stats.Weeks {
+		weeks = append(weeks, weekStatsResponse{
+			WeekStart: week.Start.Format("2006-01-02"),
+			Created:   week.Created,
+			Completed: week.Completed,
+		})
+	}
+	byStatus := make([]statusCountResponse, 0, len(stats.ByStatus))
+	for _, c := range stats.ByStatus {
+		byStatus = append(byStatus, statusCountResponse{
+			Status:   c.Status.Code,
+			Label:    c.Status.Label(),
+			Category: c.Status.Category,
+			Count:    c.Count,
+		})
+	}
+	response := map[string]any{
+		"from":                  from.Format("2006-01-02"),
+		"to":                    to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"created":               stats.Created,
+		"completed":             stats.Completed,
+		"avg_lead_time_seconds": int64(stats.LeadTime / time.Second),
+		"weeks":                 weeks,
+		"by_status":             byStatus,
+		"overdue":               stats.Overdue,
+	}
+	if len(stats.ByMember) > 0 {
+		ws, err := s.storage.GetWorkspace(space)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		byMember := make([]memberStatsResponse, 0, len(stats.ByMember))
+		for _, m := range stats.ByMember {
+			byMember = append(byMember, memberStatsResponse{
+				UserID:    m.UserID,
+				Name:      bot.MemberLabel(ws.Members, m.UserID),
+				Completed: m.Completed,
+				Open:      m.Open,
+			})
+		}
+		response["by_member"] = byMember
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ============================================================
+// handleGetTime — GET /api/tasks/{id}/time
+// Учёт времени задачи (см. bot/timetracking.go): всего, по дням
+// (в часовом поясе сервера) и сами записи от старых к новым:
+// {"total_seconds": 5400, "running": true, "days": [{"date": "2026-03-12", "seconds": 5400}],
+// "entries": [{"id": 1, "user_id": 123, "start": "...", "end": "...", "seconds": 3600}, ...]}
+// running — идёт ли таймер текущего пользователя; у идущей записи нет "end",
+// а seconds — сколько прошло к моменту запроса
+// ============================================================
+type timeEntryResponse struct {
+	bot.TimeEntry
+	Seconds  int64  `json:"seconds"`
+	UserName string `json:"user_name,omitempty"` // Имя участника команды (в личных задачах пусто)
+}
+
+func newTimeEntryResponse(e bot.TimeEntry, now time.Time, members []bot.Member) timeEntryResponse {
+	resp := timeEntryResponse{TimeEntry: e, Seconds: int64(e.Duration(now) / time.Second)}
+	if members != nil {
+		resp.UserName = bot.MemberLabel(members, e.UserID)
+	}
+	return resp
+}
+
+type dayTimeResponse struct {
+	Date    string `json:"date"`
+	Seconds int64  `json:"seconds"`
+}
+
+func (s *Server) handleGetTime(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userContextKey).(*TelegramUser)
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := s.storage.GetTimeEntries(space, taskID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	now := time.Now()
+	summary := bot.SummarizeTime(entries, now, s.loc)
+	days := make([]dayTimeResponse, 0, len(summary.Days))
+	for _, d := range summary.Days {
+		days = append(days, dayTimeResponse{Date: d.Date, Seconds: int64(d.Duration / time.Second)})
+	}
+	members := s.members(space)
+	list := make([]timeEntryResponse, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, newTimeEntryResponse(e, now, members))
+	}
+	_, running := bot.RunningTimer(entries, user.ID)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"total_seconds": int64(summary.Total / time.Second),
+		"running":       running,
+		"days":          days,
+		"entries":       list,
+	})
+}
+
+// ============================================================
+// handleStartTimer — POST /api/tasks/{id}/timer/start
+// handleStopTimer  — POST /api/tasks/{id}/timer/stop
+// Таймер текущего пользователя. Запуск уже идущего таймера,
+// остановка не запущенного и таймер выполненной задачи → 409
+// Ответ — запись времени (у остановленной есть "end" и "seconds")
+// ============================================================
+func (s *Server) handleStartTimer(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userContextKey).(*TelegramUser)
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := s.storage.StartTimer(space, taskID, user.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newTimeEntryResponse(entry, time.Now(), s.members(space)))
+}
+
+func (s *Server) handleStopTimer(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userContextKey).(*TelegramUser)
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := s.storage.StopTimer(space, taskID, user.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTimeEntryResponse(entry, time.Now(), s.members(space)))
+}
+
+// ============================================================
+// handleAddTime — POST /api/tasks/{id}/time
+// Добавляет время вручную от имени пользователя
+// Тело запроса: {"minutes": 90, "date": "2026-03-12", "note": "Оформил отчёт"}
+// date необязателен: без него (или за сегодня) время заканчивается
+// сейчас, за прошедший день — отсчитывается от его начала
+// От 1 минуты до 24 часов, иначе 400
+// ============================================================
+func (s *Server) handleAddTime(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(userContextKey).(*TelegramUser)
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Minutes int    `json:"minutes"`
+		Date    string `json:"date"`
+		Note    string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный формат запроса",
+		})
+		return
+	}
+
+	d := time.Duration(req.Minutes) * time.Minute
+	now := time.Now().In(s.loc)
+	end := now
+	if req.Date != "" && req.Date != now.Format("2006-01-02") {
+		day, err := time.ParseInLocation("2006-01-02", req.Date, s.loc)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": "неверная дата (нужен формат ГГГГ-ММ-ДД)",
+			})
+			return
+		}
+		end = day.Add(d) // День в будущем хранилище не примет
+	}
+
+	entry, err := s.storage.AddTimeEntry(space, taskID, bot.ManualEntry(user.ID, end, d, req.Note))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, newTimeEntryResponse(entry, time.Now(), s.members(space)))
+}
+
+// ============================================================
+// handleDeleteTime — DELETE /api/tasks/{id}/time/{entry}
+// Удаляет запись времени (в том числе идущий таймер)
+// ============================================================
+func (s *Server) handleDeleteTime(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	entryID, err := strconv.Atoi(r.PathValue("entry"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный ID записи времени",
+		})
+		return
+	}
+
+	if _, err := s.storage.RemoveTimeEntry(space, taskID, entryID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// ============================================================
+// handleAddChecklistItem — POST /api/tasks/{id}/checklist
+// Добавляет пункт в чек-лист задачи
+// Тело запроса: {"text": "Написать введение"}
+// ============================================================
+func (s *Server) handleAddChecklistItem(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный формат запроса",
+		})
+		return
+	}
+
+	item, err := s.storage.AddChecklistItem(space, taskID, actorFrom(r), req.Text)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, item)
+}
+
+// ============================================================
+// handleToggleChecklistItem — POST /api/tasks/{id}/checklist/{item}/toggle
+// Отмечает пункт выполненным или снимает отметку
+// Возвращает пункт в новом состоянии
+// ============================================================
+func (s *Server) handleToggleChecklistItem(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, itemID, ok := checklistItemFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	item, err := s.storage.ToggleChecklistItem(space, taskID, itemID, actorFrom(r))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, item)
+}
+
+// ============================================================
+// handleRemoveChecklistItem — DELETE /api/tasks/{id}/checklist/{item}
+// Удаляет пункт чек-листа
+// ============================================================
+func (s *Server) handleRemoveChecklistItem(w http.ResponseWriter, r *http.Request) {
+	space := spaceFrom(r)
+
+	taskID, itemID, ok := checklistItemFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.storage.RemoveChecklistItem(space, taskID, itemID, actorFrom(r)); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// workflow — набор статусов пространства для подписей в ответах
+// Если прочитать его не удалось — статусы по умолчанию (подпись не
+// повод отвечать ошибкой, сами задачи уже прочитаны)
+func (s *Server) workflow(space int64) bot.Workflow {
+	wf, err := s.storage.GetWorkflow(space)
+	if err != nil {
+		log.Printf("❌ Ошибка чтения статусов пространства %d: %v", space, err)
+		return bot.DefaultWorkflow()
+	}
+	return wf
+}
+
+// members — участники команды для имён в истории (для личных задач — nil)
+func (s *Server) members(space int64) []bot.Member {
+	if !bot.IsShared(space) {
+		return nil
+	}
+	ws, err := s.storage.GetWorkspace(space)
+	if err != nil {
+		log.Printf("❌ Ошибка чтения участников пространства %d: %v", space, err)
+		return nil
+	}
+	return ws.Members
+}
+
+// ============================================================
+// taskIDFromPath — извлекает ID задачи из URL (/api/tasks/{id}/...)
+// Go 1.22+ поддерживает {id} в путях. Если ID неверный —
+// сам отвечает 400 и возвращает false
+// ============================================================
+func taskIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	taskID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный ID задачи",
+		})
+		return 0, false
+	}
+	return taskID, true
+}
+
+// projectIDFromPath — ID проекта из URL (/api/projects/{id})
+func projectIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	projectID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный ID проекта",
+		})
+		return 0, false
+	}
+	return projectID, true
+}
+
+// checklistItemFromPath — ID задачи и пункта чек-листа из URL
+// (/api/tasks/{id}/checklist/{item})
+func checklistItemFromPath(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+	itemID, err := strconv.Atoi(r.PathValue("item"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный ID пункта чек-листа",
+		})
+		return 0, 0, false
+	}
+	return taskID, itemID, true
+}
+
+// attachmentFromPath — ID задачи и вложения из URL
+// (/api/tasks/{id}/attachments/{att})
+func attachmentFromPath(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+	attID, err := strconv.Atoi(r.PathValue("att"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": "неверный ID вложения",
+		})
+		return 0, 0, false
+	}
+	return taskID, attID, true
+}
+
+// ============================================================
+// parseDeadline — разбирает срок в формате RFC 3339
+// Например: "2026-12-25T18:00:00+03:00" или "2026-12-25T15:00:00Z"
+// Часовой пояс обязателен, поэтому срок однозначен
+// ============================================================
+func parseDeadline(value string) (time.Time, error) {
+	deadline, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("deadline должен быть в формате RFC 3339 (например, 2026-12-25T18:00:00+03:00)")
+	}
+	return deadline, nil
+}
+
+// ============================================================
+// writeStoreError — переводит ошибку хранилища в HTTP-ответ
+// ErrTaskNotFound, ErrItemNotFound, ErrProjectNotFound → 404, ошибки проверки данных → 400,
+// конфликты с зависимостями, статусами и архивом проектов → 409, файл больше лимита или квоты → 413,
+// всё остальное → 500 (подробности только в лог)
+// ============================================================
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, bot.ErrTaskNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": "задача не найдена",
+		})
+		return
+	}
+	if errors.Is(err, bot.ErrForbidden) {
+		writeJSON(w, http.StatusForbidden, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, bot.ErrItemNotFound) || errors.Is(err, bot.ErrProjectNotFound) ||
+		errors.Is(err, bot.ErrWorkspaceNotFound) || errors.Is(err, bot.ErrMemberNotFound) ||
+		errors.Is(err, bot.ErrAttachmentNotFound) || errors.Is(err, bot.ErrTimeEntryNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, bot.ErrEmptyTitle) || errors.Is(err, bot.ErrBadTag) ||
+		errors.Is(err, bot.ErrEmptyItem) || errors.Is(err, bot.ErrChecklistFull) ||
+		errors.Is(err, bot.ErrBadRecurrence) || errors.Is(err, bot.ErrUnknownStatus) ||
+		errors.Is(err, bot.ErrBadWorkflow) || errors.Is(err, bot.ErrBadProjectName) ||
+		errors.Is(err, bot.ErrBadInvite) || errors.Is(err, bot.ErrBadWorkspaceName) ||
+		errors.Is(err, bot.ErrBadAssignee) || errors.Is(err, bot.ErrBadRole) ||
+		errors.Is(err, bot.ErrEmptyComment) || errors.Is(err, bot.ErrCommentTooLong) ||
+		errors.Is(err, bot.ErrBadAttachment) || errors.Is(err, bot.ErrTooManyAttachments) ||
+		errors.Is(err, bot.ErrBadTimeEntry) || errors.Is(err, bot.ErrTooManyTimeEntries) ||
+		errors.Is(err, bot.ErrBadStatsPeriod) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, bot.ErrSelfDependency) || errors.Is(err, bot.ErrDependencyCycle) ||
+		errors.Is(err, bot.ErrTaskBlocked) || errors.Is(err, bot.ErrBadTransition) ||
+		errors.Is(err, bot.ErrStatusInUse) || errors.Is(err, bot.ErrProjectArchived) ||
+		errors.Is(err, bot.ErrOwnerLeave) || errors.Is(err, bot.ErrTimerRunning) ||
+		errors.Is(err, bot.ErrTimerNotRunning) || errors.Is(err, bot.ErrTimerTaskDone) {
+		writeJSON(w, http.StatusConflict, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, bot.ErrAttachmentTooLarge) || errors.Is(err, bot.ErrQuotaExceeded) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, bot.ErrAttachmentsDisabled) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, bot.ErrBadPriority) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error() + " (допустимые: " + strings.Join(bot.PriorityCodes(), ", ") + ")",
+		})
+		return
 	}
+	log.Printf("❌ Ошибка хранилища: %v", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]string{
+		"error": "ошибка хранилища",
+	})
 }
 
 // ============================================================
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"mtuci-task-manager/bot"
+)
+
+func TestTaskResponseHidesSchedulerState(t *testing.T) {
+	snoozed := time.Date(2027, time.January, 4, 9, 0, 0, 0, time.UTC)
+	task := bot.Task{
+		ID:            7,
+		Title:         "Сдать отчёт",
+		Status:        "new",
+		RemindersSent: []string{"1h"},
+		SnoozedUntil:  &snoozed,
+	}
+	data, err := json.Marshal(newTaskResponse(task, bot.DefaultWorkflow()))
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	for _, field := range []string{`"reminders_sent"`, `"snoozed_until"`} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("в ответе API есть служебное поле %s: %s", field, data)
+		}
+	}
+	for _, field := range []string{`"id":7`, `"title":"Сдать отчёт"`, `"status_label"`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("в ответе API нет %s: %s", field, data)
+		}
+	}
+}
@@ -6,19 +6,30 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+
+	"mtuci-task-manager/bot"
 )
 
 // contextKey — тип для ключей контекста (чтобы не было коллизий)
 type contextKey string
 
-const userContextKey contextKey = "user"
+const (
+	userContextKey  contextKey = "user"
+	spaceContextKey contextKey = "space" // int64 — пространство задач (см. withSpace)
+)
+
+// spaceHeader — заголовок с ID команды, задачами которой работает
+// Mini App; без него — личные задачи пользователя
+const spaceHeader = "X-Workspace"
 
 // TelegramUser — данные пользователя из Telegram initData
 type TelegramUser struct {
@@ -28,11 +39,26 @@ type TelegramUser struct {
 	Username  string `json:"username"`
 }
 
-// withAuth — middleware, проверяющий авторизацию через Telegram initData
+// member — пользователь как участник команды (имя — как в боте)
+func (u *TelegramUser) member() bot.Member {
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if name == "" && u.Username != "" {
+		name = "@" + u.Username
+	}
+	return bot.NewMember(u.ID, name)
+}
+
+// withAuth — middleware для маршрутов с задачами: авторизация
+// (withUser), пространство задач запроса и право perm в нём (withSpace)
+func (s *Server) withAuth(perm bot.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return s.withUser(s.withSpace(perm, next))
+}
+
+// withUser — middleware, проверяющий авторизацию через Telegram initData
 //
 // Заголовок запроса должен содержать: Authorization: tma <initData>
 // initData — строка, которую Telegram передаёт в WebApp
-func (s *Server) withAuth(next http.HandlerFunc) http.HandlerFunc {
+func (s *Server) withUser(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// ============================================================
 		// DEV_MODE — режим разработки (пропускаем проверку авторизации)
@@ -74,6 +100,55 @@ func (s *Server) withAuth(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// withSpace (после withUser) определяет пространство задач запроса по заголовку
+// X-Workspace и проверяет, что пользователь в нём состоит и его роль
+// позволяет действие perm (bot.Authorize — та же проверка, что в боте).
+// Чужая или несуществующая команда — 403, чтобы по ответу нельзя
+// было понять, существует ли она; не хватает прав — тоже 403, с пояснением
+func (s *Server) withSpace(perm bot.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(userContextKey).(*TelegramUser)
+
+		space := user.ID
+		if header := r.Header.Get(spaceHeader); header != "" {
+			id, err := strconv.ParseInt(header, 10, 64)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{
+					"error": "неверный заголовок " + spaceHeader,
+				})
+				return
+			}
+			space = id
+		}
+
+		if err := bot.Authorize(s.storage, user.ID, space, perm); err != nil {
+			if errors.Is(err, bot.ErrWorkspaceNotFound) {
+				writeJSON(w, http.StatusForbidden, map[string]string{
+					"error": "нет доступа к пространству",
+				})
+				return
+			}
+			writeStoreError(w, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), spaceContextKey, space)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// spaceFrom — пространство задач запроса: ID пользователя для
+// личных задач или отрицательный ID команды
+func spaceFrom(r *http.Request) int64 {
+	return r.Context().Value(spaceContextKey).(int64)
+}
+
+// actorFrom — кто делает запрос (ID пользователя Telegram); в задачах
+// команды это не то же, что spaceFrom, а в историю пишем именно его
+func actorFrom(r *http.Request) int64 {
+	return r.Context().Value(userContextKey).(*TelegramUser).ID
+}
+
 // ============================================================
 // validateInitData проверяет подпись initData от Telegram
 //
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"mtuci-task-manager/bot"
+)
+
+func TestWithSpace(t *testing.T) {
+	const ownerID, viewerID, strangerID = 1, 2, 9
+
+	store := bot.NewStorage()
+	ws, err := store.CreateWorkspace(bot.Member{UserID: ownerID}, "Команда")
+	if err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	if _, err := store.JoinWorkspace(bot.Member{UserID: viewerID}, ws.InviteCode); err != nil {
+		t.Fatalf("JoinWorkspace: %v", err)
+	}
+	if _, err := store.SetMemberRole(ownerID, ws.ID, viewerID, bot.RoleViewer); err != nil {
+		t.Fatalf("SetMemberRole: %v", err)
+	}
+	s := NewServer(store, "", Options{})
+	team := strconv.FormatInt(ws.ID, 10)
+
+	tests := []struct {
+		name      string
+		userID    int64
+		header    string // Значение X-Workspace ("" — без заголовка)
+		perm      bot.Permission
+		wantCode  int
+		wantSpace int64 // Пространство, которое увидит обработчик (при 200)
+	}{
+		{"без заголовка — личные задачи", strangerID, "", bot.PermManage, http.StatusOK, strangerID},
+		{"участник команды", viewerID, team, bot.PermView, http.StatusOK, ws.ID},
+		{"владелец команды", ownerID, team, bot.PermManage, http.StatusOK, ws.ID},
+		{"не участник команды", strangerID, team, bot.PermView, http.StatusForbidden, 0},
+		{"чужое личное пространство", strangerID, strconv.Itoa(ownerID), bot.PermView, http.StatusForbidden, 0},
+		{"несуществующая команда", ownerID, "-100500", bot.PermView, http.StatusForbidden, 0},
+		{"не хватает роли", viewerID, team, bot.PermEdit, http.StatusForbidden, 0},
+		{"заголовок не число", ownerID, "team", bot.PermView, http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotSpace int64
+			handler := s.withSpace(tt.perm, func(w http.ResponseWriter, r *http.Request) {
+				gotSpace = spaceFrom(r)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+			if tt.header != "" {
+				r.Header.Set(spaceHeader, tt.header)
+			}
+			user := &TelegramUser{ID: tt.userID}
+			r = r.WithContext(context.WithValue(r.Context(), userContextKey, user))
+			rec := httptest.NewRecorder()
+			handler(rec, r)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("код ответа %d, ожидался %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if gotSpace != tt.wantSpace {
+				t.Fatalf("пространство запроса %d, ожидалось %d", gotSpace, tt.wantSpace)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"mtuci-task-manager/bot"
 )
 
 // Router создаёт и настраивает HTTP-маршрутизатор
@@ -22,11 +24,53 @@ func (s *Server) Router() http.Handler {
 
 	// ============================================================
 	// API-маршруты (требуют авторизации)
+	// Для задач команды — ещё и роли с нужным правом: смотреть,
+	// менять, удалять или настраивать (см. bot/permissions.go)
 	// ============================================================
-	mux.HandleFunc("GET /api/tasks", s.withAuth(s.handleGetTasks))
-	mux.HandleFunc("POST /api/tasks", s.withAuth(s.handleCreateTask))
-	mux.HandleFunc("PATCH /api/tasks/{id}/status", s.withAuth(s.handleUpdateStatus))
-	mux.HandleFunc("DELETE /api/tasks/{id}", s.withAuth(s.handleDeleteTask))
+	mux.HandleFunc("GET /api/statuses", s.withAuth(bot.PermView, s.handleGetStatuses))
+	mux.HandleFunc("PUT /api/statuses", s.withAuth(bot.PermManage, s.handleSetStatuses))
+	mux.HandleFunc("GET /api/priorities", s.withAuth(bot.PermView, s.handleGetPriorities))
+	mux.HandleFunc("GET /api/tags", s.withAuth(bot.PermView, s.handleGetTags))
+	mux.HandleFunc("GET /api/stats", s.withAuth(bot.PermView, s.handleGetStats))
+	mux.HandleFunc("GET /api/projects", s.withAuth(bot.PermView, s.handleGetProjects))
+	mux.HandleFunc("POST /api/projects", s.withAuth(bot.PermManage, s.handleCreateProject))
+	mux.HandleFunc("PATCH /api/projects/{id}", s.withAuth(bot.PermManage, s.handleUpdateProject))
+	mux.HandleFunc("DELETE /api/projects/{id}", s.withAuth(bot.PermManage, s.handleDeleteProject))
+	// Команды, «Назначено мне/мной» и место под файлы — без X-Workspace:
+	// они про все пространства пользователя сразу
+	mux.HandleFunc("GET /api/workspaces", s.withUser(s.handleGetWorkspaces))
+	mux.HandleFunc("POST /api/workspaces", s.withUser(s.handleCreateWorkspace))
+	mux.HandleFunc("POST /api/workspaces/join", s.withUser(s.handleJoinWorkspace))
+	mux.HandleFunc("POST /api/workspaces/{id}/leave", s.withUser(s.handleLeaveWorkspace))
+	mux.HandleFunc("PATCH /api/workspaces/{id}/members/{user}", s.withUser(s.handleSetMemberRole))
+	mux.HandleFunc("GET /api/tasks/assigned", s.withUser(s.handleGetAssigned))
+	mux.HandleFunc("GET /api/files/usage", s.withUser(s.handleGetFileUsage))
+	mux.HandleFunc("GET /api/tasks", s.withAuth(bot.PermView, s.handleGetTasks))
+	mux.HandleFunc("POST /api/tasks", s.withAuth(bot.PermEdit, s.handleCreateTask))
+	mux.HandleFunc("GET /api/tasks/search", s.withAuth(bot.PermView, s.handleSearchTasks))
+	mux.HandleFunc("GET /api/tasks/{id}", s.withAuth(bot.PermView, s.handleGetTask))
+	mux.HandleFunc("PATCH /api/tasks/{id}", s.withAuth(bot.PermEdit, s.handleUpdateTask))
+	mux.HandleFunc("PATCH /api/tasks/{id}/status", s.withAuth(bot.PermEdit, s.handleUpdateStatus))
+	mux.HandleFunc("DELETE /api/tasks/{id}", s.withAuth(bot.PermDelete, s.handleDeleteTask))
+	mux.HandleFunc("POST /api/tasks/{id}/restore", s.withAuth(bot.PermDelete, s.handleRestoreTask))
+	mux.HandleFunc("GET /api/trash", s.withAuth(bot.PermView, s.handleGetTrash))
+	mux.HandleFunc("GET /api/tasks/{id}/history", s.withAuth(bot.PermView, s.handleGetHistory))
+	mux.HandleFunc("GET /api/tasks/{id}/comments", s.withAuth(bot.PermView, s.handleGetComments))
+	mux.HandleFunc("POST /api/tasks/{id}/comments", s.withAuth(bot.PermEdit, s.handleAddComment))
+	mux.HandleFunc("GET /api/tasks/{id}/attachments", s.withAuth(bot.PermView, s.handleGetAttachments))
+	mux.HandleFunc("POST /api/tasks/{id}/attachments", s.withAuth(bot.PermEdit, s.handleUploadAttachment))
+	mux.HandleFunc("GET /api/tasks/{id}/attachments/{att}", s.withAuth(bot.PermView, s.handleDownloadAttachment))
+	mux.HandleFunc("DELETE /api/tasks/{id}/attachments/{att}", s.withAuth(bot.PermEdit, s.handleDeleteAttachment))
+	mux.HandleFunc("GET /api/tasks/{id}/time", s.withAuth(bot.PermView, s.handleGetTime))
+	mux.HandleFunc("POST /api/tasks/{id}/time", s.withAuth(bot.PermEdit, s.handleAddTime))
+	mux.HandleFunc("DELETE /api/tasks/{id}/time/{entry}", s.withAuth(bot.PermEdit, s.handleDeleteTime))
+	mux.HandleFunc("POST /api/tasks/{id}/timer/start", s.withAuth(bot.PermEdit, s.handleStartTimer))
+	mux.HandleFunc("POST /api/tasks/{id}/timer/stop", s.withAuth(bot.PermEdit, s.handleStopTimer))
+	mux.HandleFunc("POST /api/tasks/{id}/dependencies", s.withAuth(bot.PermEdit, s.handleAddDependency))
+	mux.HandleFunc("DELETE /api/tasks/{id}/dependencies/{blocker}", s.withAuth(bot.PermEdit, s.handleRemoveDependency))
+	mux.HandleFunc("POST /api/tasks/{id}/checklist", s.withAuth(bot.PermEdit, s.handleAddChecklistItem))
+	mux.HandleFunc("POST /api/tasks/{id}/checklist/{item}/toggle", s.withAuth(bot.PermEdit, s.handleToggleChecklistItem))
+	mux.HandleFunc("DELETE /api/tasks/{id}/checklist/{item}", s.withAuth(bot.PermEdit, s.handleRemoveChecklistItem))
 
 	// ============================================================
 	// Статические файлы (Mini App фронтенд)
@@ -60,8 +104,8 @@ func loggingMiddleware(next http.Handler) http.Handler {
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, ngrok-skip-browser-warning")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, ngrok-skip-browser-warning, "+spaceHeader)
 
 		// Preflight-запрос — браузер спрашивает, можно ли отправить запрос
 		if r.Method == "OPTIONS" {
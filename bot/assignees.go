@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// ============================================================
+// ИСПОЛНИТЕЛИ ЗАДАЧ
+//
+// Задачу команды можно поручить любому её участнику (Task.Assignee).
+// В личных задачах «участник» один — сам владелец. Кто назначил
+// исполнителя, хранится в Task.AssignedBy: по нему строится
+// список «Назначено мной».
+//
+// Списки «Назначено мне» и «Назначено мной» собирают задачи из всех
+// пространств пользователя — личного и команд, где он состоит
+// ============================================================
+
+// NoAssignee — исполнитель не назначен
+const NoAssignee int64 = 0
+
+// ErrBadAssignee — исполнитель не состоит в пространстве задачи
+var ErrBadAssignee = errors.New("исполнителем можно назначить только участника команды")
+
+// checkAssignee проверяет, что исполнителя можно назначить:
+// members — участники пространства задачи
+func checkAssignee(members []int64, assignee int64) error {
+	if assignee == NoAssignee || slices.Contains(members, assignee) {
+		return nil
+	}
+	return ErrBadAssignee
+}
+
+// assigneeText — исполнитель для истории изменений ("" — не назначен)
+func assigneeText(assignee int64) string {
+	if assignee == NoAssignee {
+		return ""
+	}
+	return strconv.FormatInt(assignee, 10)
+}
+
+// MemberLabel — имя пользователя для сообщений: из списка участников
+// пространства, а если его там нет (вышел из команды) — ID
+func MemberLabel(members []Member, userID int64) string {
+	if i := slices.IndexFunc(members, func(m Member) bool { return m.UserID == userID }); i >= 0 {
+		return members[i].Label()
+	}
+	return fmt.Sprintf("id%d", userID)
+}
+
+// SpaceTask — задача вместе с пространством, в котором она лежит
+type SpaceTask struct {
+	Space int64
+	Task  Task
+}
+
+// AssignedTo — задачи, исполнитель которых — пользователь
+// («Назначено мне»): сначала личные, затем задачи команд
+func AssignedTo(store TaskStore, userID int64) ([]SpaceTask, error) {
+	return assignedTasks(store, userID, func(t Task) bool {
+		return t.Assignee == userID
+	})
+}
+
+// AssignedBy — задачи, которые пользователь поручил другим
+// («Назначено мной»); назначенные самому себе сюда не попадают
+func AssignedBy(store TaskStore, userID int64) ([]SpaceTask, error) {
+	return assignedTasks(store, userID, func(t Task) bool {
+		return t.AssignedBy == userID && t.Assignee != NoAssignee && t.Assignee != userID
+	})
+}
+
+// assignedTasks обходит пространства пользователя и отбирает задачи match
+func assignedTasks(store TaskStore, userID int64, match func(Task) bool) ([]SpaceTask, error) {
+	spaces := []int64{userID}
+	workspaces, err := store.GetWorkspaces(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range workspaces {
+		spaces = append(spaces, w.ID)
+	}
+
+	var result []SpaceTask
+	for _, space := range spaces {
+		tasks, err := store.GetTasks(space)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			if match(task) {
+				result = append(result, SpaceTask{Space: space, Task: task})
+			}
+		}
+	}
+	return result, nil
+}
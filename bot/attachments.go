@@ -0,0 +1,379 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ============================================================
+// ВЛОЖЕНИЯ ЗАДАЧ
+//
+// К задаче можно приложить фото и документы. Файл хранится одним из
+// двух способов:
+//   FileID — файл прислан боту: он лежит у Telegram, а мы помним
+//            только его file_id и пересылаем по нему
+//   Blob   — файл загружен через Mini App: он лежит на диске сервера
+//            (FileVault), и у каждого пользователя есть квота
+//
+// Номера вложений идут по порядку внутри задачи, как у комментариев.
+// Вложения переживают корзину, но удаляются вместе с задачей навсегда
+// (файлы на диске подчищает FileVault.Prune после очистки корзины)
+// ============================================================
+
+// Виды вложений
+const (
+	AttachmentPhoto    = "photo"
+	AttachmentDocument = "document"
+)
+
+// Ограничения вложений
+const (
+	maxAttachments       = 20   // Вложений у одной задачи
+	maxAttachmentName    = 200  // Длина имени файла
+	maxAttachmentCaption = 1024 // Длина подписи (как у подписи в Telegram)
+)
+
+// MaxAttachmentSize — наибольший размер файла, загружаемого на диск (20 МБ)
+const MaxAttachmentSize = 20 << 20
+
+// DefaultAttachmentQuota — сколько места на диске по умолчанию
+// занимают файлы одного пользователя
+const DefaultAttachmentQuota = 100 << 20
+
+// Ошибки вложений
+var (
+	ErrAttachmentNotFound  = errors.New("вложение не найдено")
+	ErrBadAttachment       = errors.New("у вложения нет файла")
+	ErrTooManyAttachments  = fmt.Errorf("у задачи может быть не больше %d вложений", maxAttachments)
+	ErrAttachmentTooLarge  = fmt.Errorf("файл больше %d МБ", MaxAttachmentSize>>20)
+	ErrQuotaExceeded       = errors.New("место для файлов закончилось: удали ненужные вложения")
+	ErrAttachmentsDisabled = errors.New("хранилище файлов не настроено")
+)
+
+// Attachment — файл, приложенный к задаче
+type Attachment struct {
+	ID        int       `json:"id"` // Номер в пределах задачи
+	TaskID    int       `json:"task_id"`
+	Kind      string    `json:"kind"` // AttachmentPhoto или AttachmentDocument
+	Name      string    `json:"name"` // Имя файла для скачивания
+	MimeType  string    `json:"mime_type,omitempty"`
+	Size      int64     `json:"size,omitempty"` // Байт (0 — неизвестно)
+	Caption   string    `json:"caption,omitempty"`
+	FileID    string    `json:"file_id,omitempty"` // file_id в Telegram
+	Blob      string    `json:"blob,omitempty"`    // Ключ файла в FileVault
+	Author    int64     `json:"author"`            // Telegram ID того, кто приложил файл
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// newAttachment проверяет вложение и приводит в порядок имя и подпись
+// (ID назначит хранилище)
+func newAttachment(taskID int, att Attachment, now time.Time) (Attachment, error) {
+	if att.FileID == "" && att.Blob == "" {
+		return Attachment{}, ErrBadAttachment
+	}
+	if att.Kind != AttachmentPhoto {
+		att.Kind = AttachmentDocument
+	}
+	att.TaskID = taskID
+	att.Name = cleanFileName(att.Name, att.Kind)
+	att.Caption = truncate(strings.TrimSpace(att.Caption), maxAttachmentCaption)
+	att.CreatedAt = now
+	return att, nil
+}
+
+// cleanFileName убирает из имени файла путь и управляющие символы
+// Пустое имя заменяется на "photo.jpg" или "file"
+func cleanFileName(name, kind string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || name == "." || name == "/" {
+		if kind == AttachmentPhoto {
+			return "photo.jpg"
+		}
+		return "file"
+	}
+	return truncate(name, maxAttachmentName)
+}
+
+// nextAttachmentID — номер следующего вложения задачи
+func nextAttachmentID(attachments []Attachment) int {
+	if len(attachments) == 0 {
+		return 1
+	}
+	return attachments[len(attachments)-1].ID + 1
+}
+
+// FindAttachment ищет вложение по номеру
+func FindAttachment(attachments []Attachment, id int) (Attachment, bool) {
+	for _, a := range attachments {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return Attachment{}, false
+}
+
+// FormatSize — размер файла для людей: "340 КБ", "2.5 МБ"
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return strconv.FormatFloat(float64(size)/(1<<20), 'f', 1, 64) + " МБ"
+	case size >= 1<<10:
+		return strconv.FormatInt(size>>10, 10) + " КБ"
+	}
+	return strconv.FormatInt(size, 10) + " Б"
+}
+
+// ============================================================
+// FileVault — файлы вложений на диске сервера
+//
+// Файлы лежат в папках владельцев: <dir>/<пользователь>/<ключ>.
+// Занятое пользователем место — сумма размеров файлов в его папке;
+// больше quota загрузить нельзя. Имя файла хранит пространство и
+// задачу ("<пространство>_<задача>_<случайная строка>"), чтобы Prune
+// мог найти файлы задач, удалённых навсегда
+// ============================================================
+type FileVault struct {
+	dir   string
+	quota int64
+
+	// mu держится от подсчёта занятого места до записи файла,
+	// чтобы две загрузки одновременно не превысили квоту
+	mu sync.Mutex
+}
+
+// pruneAge — файлы моложе этого не трогает Prune: вложение могли
+// ещё не успеть записать в хранилище задач
+const pruneAge = time.Hour
+
+// NewFileVault создаёт хранилище файлов в папке dir
+// quota — сколько байт может занять один пользователь (0 — DefaultAttachmentQuota)
+func NewFileVault(dir string, quota int64) (*FileVault, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if quota <= 0 {
+		quota = DefaultAttachmentQuota
+	}
+	return &FileVault{dir: dir, quota: quota}, nil
+}
+
+// Quota — сколько байт может занять один пользователь
+func (v *FileVault) Quota() int64 {
+	return v.quota
+}
+
+// Usage — сколько байт уже занимают файлы пользователя
+func (v *FileVault) Usage(owner int64) (int64, error) {
+	entries, err := os.ReadDir(v.ownerDir(owner))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue // Файл удалили, пока мы читали папку
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// Save записывает файл пользователя owner для задачи taskID пространства
+// space. Возвращает ключ файла (для Attachment.Blob) и его размер
+// Файл больше MaxAttachmentSize — ErrAttachmentTooLarge,
+// не помещается в квоту — ErrQuotaExceeded
+func (v *FileVault) Save(owner, space int64, taskID int, r io.Reader) (string, int64, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	used, err := v.Usage(owner)
+	if err != nil {
+		return "", 0, err
+	}
+	limit := min(int64(MaxAttachmentSize), v.quota-used)
+	if limit <= 0 {
+		return "", 0, ErrQuotaExceeded
+	}
+
+	if err := os.MkdirAll(v.ownerDir(owner), 0o755); err != nil {
+		return "", 0, err
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", 0, err
+	}
+	key := fmt.Sprintf("%d/%d_%d_%s", owner, space, taskID, hex.EncodeToString(suffix))
+
+	f, err := os.OpenFile(v.path(key), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", 0, err
+	}
+	// Читаем на байт больше лимита: так видно, что файл в него не влез
+	size, err := io.Copy(f, io.LimitReader(r, limit+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size > limit {
+		err = ErrQuotaExceeded
+		if limit == MaxAttachmentSize {
+			err = ErrAttachmentTooLarge
+		}
+	}
+	if err != nil {
+		os.Remove(v.path(key))
+		return "", 0, err
+	}
+	return key, size, nil
+}
+
+// Open открывает файл по ключу (закрыть должен вызывающий)
+func (v *FileVault) Open(key string) (*os.File, error) {
+	if !validBlobKey(key) {
+		return nil, ErrAttachmentNotFound
+	}
+	f, err := os.Open(v.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrAttachmentNotFound
+	}
+	return f, err
+}
+
+// Remove удаляет файл (если его уже нет — не ошибка)
+func (v *FileVault) Remove(key string) error {
+	if !validBlobKey(key) {
+		return nil
+	}
+	err := os.Remove(v.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Prune удаляет файлы, на которые больше не ссылается ни одно
+// вложение: задачу удалили навсегда или вложение убрали, а файл
+// стереть не удалось. Задачи в корзине свои файлы сохраняют
+// Возвращает количество удалённых файлов
+func (v *FileVault) Prune(store TaskStore) (int, error) {
+	owners, err := os.ReadDir(v.dir)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-pruneAge)
+	trash := make(map[int64][]Task) // Корзины пространств, которые уже прочитали
+	removed := 0
+	for _, owner := range owners {
+		if !owner.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(v.dir, owner.Name()))
+		if err != nil {
+			return removed, err
+		}
+		for _, file := range files {
+			key := owner.Name() + "/" + file.Name()
+			info, err := file.Info()
+			if err != nil || info.ModTime().After(cutoff) || !validBlobKey(key) {
+				continue
+			}
+			used, err := blobInUse(store, key, trash)
+			if err != nil {
+				return removed, err
+			}
+			if !used {
+				if err := v.Remove(key); err != nil {
+					return removed, err
+				}
+				removed++
+			}
+		}
+	}
+	return removed, nil
+}
+
+// blobInUse проверяет, ссылается ли на файл вложение его задачи
+// (задача в корзине — файл ещё нужен: её могут вернуть)
+func blobInUse(store TaskStore, key string, trash map[int64][]Task) (bool, error) {
+	space, taskID, _ := parseBlobKey(key)
+	attachments, err := store.GetAttachments(space, taskID)
+	if err == nil {
+		return slices.ContainsFunc(attachments, func(a Attachment) bool { return a.Blob == key }), nil
+	}
+	if !errors.Is(err, ErrTaskNotFound) {
+		return false, err
+	}
+	tasks, ok := trash[space]
+	if !ok {
+		if tasks, err = store.GetTrash(space); err != nil {
+			return false, err
+		}
+		trash[space] = tasks
+	}
+	_, inTrash := findTask(tasks, taskID)
+	return inTrash, nil
+}
+
+// parseBlobKey — пространство и задача из ключа файла
+// ("<владелец>/<пространство>_<задача>_<случайная строка>")
+func parseBlobKey(key string) (int64, int, bool) {
+	owner, name, ok := strings.Cut(key, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	if _, err := strconv.ParseInt(owner, 10, 64); err != nil {
+		return 0, 0, false
+	}
+	parts := strings.Split(name, "_")
+	if len(parts) != 3 || parts[2] == "" {
+		return 0, 0, false
+	}
+	space, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	taskID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	if _, err := hex.DecodeString(parts[2]); err != nil {
+		return 0, 0, false
+	}
+	return space, taskID, true
+}
+
+// validBlobKey — ключ собран Save, а не подставлен снаружи
+// (защита от путей вроде "../../etc/passwd")
+func validBlobKey(key string) bool {
+	_, _, ok := parseBlobKey(key)
+	return ok
+}
+
+func (v *FileVault) ownerDir(owner int64) string {
+	return filepath.Join(v.dir, strconv.FormatInt(owner, 10))
+}
+
+func (v *FileVault) path(key string) string {
+	return filepath.Join(v.dir, filepath.FromSlash(key))
+}
@@ -2,7 +2,9 @@ package bot
 
 import (
 	"log"
+	"strings"
 	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -13,8 +15,32 @@ import (
 // (например, ввод названия задачи)
 // ============================================================
 type UserState struct {
-	Step      string // Текущий шаг диалога (например, "waiting_title")
-	TempTitle string // Временное хранение названия при создании задачи
+	Step         string     // Текущий шаг диалога (например, "waiting_title")
+	TempTitle    string     // Временное хранение названия при создании задачи
+	TempDesc     string     // Временное хранение описания при создании задачи
+	TempDeadline *time.Time // Временное хранение срока при создании задачи
+	TempTags     []string   // Теги из #хештегов в названии новой задачи
+	TempTaskID   int        // ID задачи, которую пользователь сейчас редактирует
+	TempProject  int        // Проект новой задачи (0 — «Входящие»)
+}
+
+// ============================================================
+// Options — настройки бота
+// ============================================================
+type Options struct {
+	WebAppURL string         // URL Mini App (для кнопки «Открыть приложение»)
+	Location  *time.Location // Часовой пояс для дедлайнов (nil — время сервера)
+
+	// За сколько до срока присылать напоминания
+	// (nil — DefaultReminderOffsets, пустой срез — только о просрочке)
+	ReminderOffsets []time.Duration
+
+	// Сколько удалённые задачи лежат в корзине (0 — DefaultTrashRetention)
+	TrashRetention time.Duration
+
+	// Файлы вложений, загруженные через Mini App (nil — таких нет;
+	// файлы, присланные боту, хранит Telegram — см. attachments.go)
+	Files *FileVault
 }
 
 // ============================================================
@@ -23,19 +49,47 @@ type UserState struct {
 // ============================================================
 type Bot struct {
 	api       *tgbotapi.BotAPI     // API-клиент для общения с Telegram
-	storage   *Storage             // Хранилище задач (общее с HTTP API)
+	storage   TaskStore            // Хранилище задач (общее с HTTP API)
 	users     map[int64]*UserState // Состояние диалога каждого пользователя
 	mu        sync.Mutex           // Мьютекс — защищает users от одновременного доступа из горутин
 	webAppURL string               // URL Mini App (для кнопки в клавиатуре)
+	loc       *time.Location       // Часовой пояс, в котором понимаем и показываем даты
+	reminders *scheduler           // Планировщик напоминаний о сроках (scheduler.go)
+
+	trashRetention time.Duration // Срок хранения задач в корзине (trash.go)
+
+	// Последний поисковый запрос каждого пользователя (/find) — для листания
+	// страниц результатов. Отдельно от UserState: поиск переживает сброс диалога
+	searches map[int64]string
+
+	// Пространство, с которым сейчас работает каждый пользователь (см. workspaces.go)
+	// Нет записи — личные задачи. Как и поиск, хранится только в памяти
+	spaces map[int64]int64
+
+	// Задача, которую пользователь открыл последней: фото и документы
+	// без ответа на сообщение прикладываются к ней (см. handleFile)
+	viewing map[int64]viewedTask
+
+	files *FileVault // Файлы вложений на диске (может быть nil)
 }
 
+// viewedTask — открытая пользователем задача и когда её открыли
+type viewedTask struct {
+	space  int64
+	taskID int
+	at     time.Time
+}
+
+// viewingTTL — сколько после открытия задачи файлы прикладываются к ней
+const viewingTTL = 15 * time.Minute
+
 // ============================================================
 // New создаёт нового бота
 // token     — токен, полученный у @BotFather в Telegram
 // storage   — общее хранилище задач (используется и ботом, и HTTP API)
-// webAppURL — URL Mini App (для кнопки «Открыть приложение»)
+// opts      — настройки (URL Mini App, часовой пояс)
 // ============================================================
-func New(token string, storage *Storage, webAppURL string) (*Bot, error) {
+func New(token string, storage TaskStore, opts Options) (*Bot, error) {
 	// Создаём API-клиент
 	api, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
@@ -48,19 +102,41 @@ func New(token string, storage *Storage, webAppURL string) (*Bot, error) {
 
 	log.Printf("🤖 Авторизован как @%s", api.Self.UserName)
 
-	return &Bot{
+	loc := opts.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	b := &Bot{
 		api:       api,
 		storage:   storage,
 		users:     make(map[int64]*UserState),
-		webAppURL: webAppURL,
-	}, nil
+		webAppURL: opts.WebAppURL,
+		loc:       loc,
+		searches:  make(map[int64]string),
+		spaces:    make(map[int64]int64),
+		viewing:   make(map[int64]viewedTask),
+		files:     opts.Files,
+
+		trashRetention: opts.TrashRetention,
+	}
+	if b.trashRetention <= 0 {
+		b.trashRetention = DefaultTrashRetention
+	}
+	b.reminders = newScheduler(b, opts.ReminderOffsets)
+	return b, nil
 }
 
 // ============================================================
 // Start запускает бесконечный цикл получения обновлений
 // Использует Long Polling — бот "слушает" Telegram и получает новые сообщения
+// Рядом в отдельных горутинах работают планировщик напоминаний
+// и очистка корзины
 // ============================================================
 func (b *Bot) Start() {
+	go b.reminders.run()
+	go b.runTrashPurge()
+
 	// Настраиваем параметры получения обновлений
 	config := tgbotapi.NewUpdate(0)
 	config.Timeout = 30 // Ждём обновления до 30 секунд (long polling)
@@ -89,13 +165,14 @@ func (b *Bot) handleUpdate(update tgbotapi.Update) {
 		return
 	}
 
-	// 2. Message — пользователь отправил текстовое сообщение
+	// 2. Message — пользователь отправил сообщение: текст,
+	// а также фото или документ (вложение к задаче, см. handleFile)
 	if update.Message != nil {
 		b.handleMessage(update.Message)
 		return
 	}
 
-	// Другие типы обновлений (фото, стикеры и т.д.) пока игнорируем
+	// Другие типы обновлений (правки сообщений, опросы и т.д.) пока игнорируем
 }
 
 // ============================================================
@@ -118,6 +195,137 @@ func (b *Bot) getUserState(userID int64) *UserState {
 	return state
 }
 
+// now возвращает текущее время в часовом поясе бота
+func (b *Bot) now() time.Time {
+	return time.Now().In(b.loc)
+}
+
+// memberOf — участник команды из пользователя Telegram: имя и фамилия,
+// а если их нет — @username
+func memberOf(user *tgbotapi.User) Member {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" && user.UserName != "" {
+		name = "@" + user.UserName
+	}
+	return NewMember(user.ID, name)
+}
+
+// Username — имя бота без "@" (для ссылок-приглашений в команду)
+func (b *Bot) Username() string {
+	return b.api.Self.UserName
+}
+
+// space возвращает пространство, с которым сейчас работает пользователь:
+// его ID (личные задачи) или ID общего пространства команды
+func (b *Bot) space(userID int64) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if space, ok := b.spaces[userID]; ok {
+		return space
+	}
+	return userID
+}
+
+// setSpace переключает пользователя на другое пространство
+// Начатый диалог сбрасываем: он относится к задачам прежнего пространства
+func (b *Bot) setSpace(userID, space int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if space == userID {
+		delete(b.spaces, userID)
+	} else {
+		b.spaces[userID] = space
+	}
+	b.users[userID] = &UserState{}
+	delete(b.viewing, userID)
+}
+
+// setViewing запоминает задачу, которую пользователь сейчас смотрит
+func (b *Bot) setViewing(userID, space int64, taskID int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.viewing[userID] = viewedTask{space: space, taskID: taskID, at: time.Now()}
+}
+
+// forgetViewing — пользователь ушёл от задачи (написал что-то другое)
+func (b *Bot) forgetViewing(userID int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.viewing, userID)
+}
+
+// viewedTask возвращает задачу, которую пользователь открыл
+// не раньше viewingTTL назад
+func (b *Bot) viewedTask(userID int64) (int64, int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	v, ok := b.viewing[userID]
+	if !ok || time.Since(v.at) > viewingTTL {
+		return 0, 0, false
+	}
+	return v.space, v.taskID, true
+}
+
+// FileURL — ссылка для скачивания файла Telegram по file_id
+// (HTTP API отдаёт по ней вложения, присланные боту). Ссылка
+// содержит токен бота, поэтому наружу её не показываем
+func (b *Bot) FileURL(fileID string) (string, error) {
+	return b.api.GetFileDirectURL(fileID)
+}
+
+// recipients — кому отправлять уведомления о задачах пространства
+// (ошибку хранилища только логируем: уведомление не повод для сбоя)
+func (b *Bot) recipients(space int64) []int64 {
+	users, err := Recipients(b.storage, space)
+	if err != nil {
+		log.Printf("❌ Ошибка чтения участников пространства %d: %v", space, err)
+	}
+	return users
+}
+
+// members — участники команды с именами (для личных задач — nil)
+func (b *Bot) members(space int64) []Member {
+	if !IsShared(space) {
+		return nil
+	}
+	w, err := b.storage.GetWorkspace(space)
+	if err != nil {
+		log.Printf("❌ Ошибка чтения участников пространства %d: %v", space, err)
+		return nil
+	}
+	return w.Members
+}
+
+// workflow возвращает набор статусов пространства space
+// Если хранилище недоступно — статусы по умолчанию (чтобы хотя бы показать задачи)
+func (b *Bot) workflow(space int64) Workflow {
+	wf, err := b.storage.GetWorkflow(space)
+	if err != nil {
+		log.Printf("❌ Ошибка чтения статусов пространства %d: %v", space, err)
+		return DefaultWorkflow()
+	}
+	return wf
+}
+
+// project возвращает проект пространства по ID (для подписи в карточке задачи)
+// Если проект не нашёлся — «Входящие»: туда попадают задачи удалённых проектов
+func (b *Bot) project(space int64, projectID int) Project {
+	projects, err := b.storage.GetProjects(space)
+	if err != nil {
+		log.Printf("❌ Ошибка чтения проектов пространства %d: %v", space, err)
+		return InboxProject()
+	}
+	if project, ok := findProject(projects, projectID); ok {
+		return project
+	}
+	return InboxProject()
+}
+
 // resetUserState сбрасывает состояние пользователя в начальное
 func (b *Bot) resetUserState(userID int64) {
 	b.mu.Lock()
@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ============================================================
+// ЧЕК-ЛИСТ ЗАДАЧИ
+//
+// Большую задачу (курсовая, лабораторная) удобно разбить на шаги:
+//   ✅ Выбрать тему
+//   ✅ Найти литературу
+//   ⬜ Написать введение
+//
+// Пункты хранятся прямо в задаче (Task.Checklist). ID пункта
+// уникален только в пределах задачи — как ID задачи в пределах
+// пользователя, поэтому callback data "chk_<задача>_<пункт>" короткие.
+// ============================================================
+
+var (
+	ErrItemNotFound  = errors.New("пункт чек-листа не найден")
+	ErrEmptyItem     = errors.New("текст пункта не может быть пустым")
+	ErrChecklistFull = fmt.Errorf("в чек-листе может быть не больше %d пунктов", maxChecklistItems)
+)
+
+// maxChecklistItems — ограничение на число пунктов
+// (каждый пункт — отдельная кнопка, а у клавиатуры Telegram есть предел)
+const maxChecklistItems = 30
+
+// ChecklistItem — один пункт чек-листа
+type ChecklistItem struct {
+	ID   int    `json:"id"`   // Номер пункта внутри задачи
+	Text string `json:"text"` // Что нужно сделать
+	Done bool   `json:"done"` // Пункт выполнен
+}
+
+// ChecklistProgress — сколько пунктов выполнено из скольких
+type ChecklistProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// String — прогресс в виде "3/7"
+func (p ChecklistProgress) String() string {
+	return fmt.Sprintf("%d/%d", p.Done, p.Total)
+}
+
+// Progress считает выполненные пункты чек-листа
+func (t Task) Progress() ChecklistProgress {
+	p := ChecklistProgress{Total: len(t.Checklist)}
+	for _, item := range t.Checklist {
+		if item.Done {
+			p.Done++
+		}
+	}
+	return p
+}
+
+// ============================================================
+// Изменение чек-листа в памяти (используют Storage и FileStore)
+// ============================================================
+
+// addChecklistItem добавляет пункт в конец чек-листа
+func (t *Task) addChecklistItem(text string) (ChecklistItem, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ChecklistItem{}, ErrEmptyItem
+	}
+	if len(t.Checklist) >= maxChecklistItems {
+		return ChecklistItem{}, ErrChecklistFull
+	}
+
+	// Новый ID — на единицу больше максимального
+	// (ID удалённых пунктов не переиспользуются, пока есть пункты после них)
+	id := 1
+	for _, item := range t.Checklist {
+		if item.ID >= id {
+			id = item.ID + 1
+		}
+	}
+	item := ChecklistItem{ID: id, Text: text}
+	t.Checklist = append(t.Checklist, item)
+	return item, nil
+}
+
+// toggleChecklistItem отмечает пункт выполненным или снимает отметку
+func (t *Task) toggleChecklistItem(itemID int) (ChecklistItem, error) {
+	for i := range t.Checklist {
+		if t.Checklist[i].ID == itemID {
+			t.Checklist[i].Done = !t.Checklist[i].Done
+			return t.Checklist[i], nil
+		}
+	}
+	return ChecklistItem{}, ErrItemNotFound
+}
+
+// removeChecklistItem удаляет пункт
+func (t *Task) removeChecklistItem(itemID int) error {
+	for i := range t.Checklist {
+		if t.Checklist[i].ID == itemID {
+			t.Checklist = append(t.Checklist[:i], t.Checklist[i+1:]...)
+			return nil
+		}
+	}
+	return ErrItemNotFound
+}
@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ============================================================
+// КОММЕНТАРИИ К ЗАДАЧАМ
+//
+// У каждой задачи — своя лента комментариев: кто написал, когда и что.
+// Номера комментариев идут по порядку внутри задачи (1, 2, 3...),
+// как у событий истории. В отличие от истории, комментарии — часть
+// задачи: переживают корзину, но удаляются вместе с задачей навсегда.
+//
+// В боте комментарий оставляют ответом (reply) на сообщение с задачей;
+// остальные участники команды получают уведомление
+// ============================================================
+
+// maxCommentRunes — ограничение длины комментария
+const maxCommentRunes = 2000
+
+// Ошибки комментариев
+var (
+	ErrEmptyComment   = errors.New("комментарий не может быть пустым")
+	ErrCommentTooLong = fmt.Errorf("комментарий — не длиннее %d символов", maxCommentRunes)
+)
+
+// Comment — комментарий к задаче
+type Comment struct {
+	ID        int       `json:"id"` // Номер в пределах задачи
+	TaskID    int       `json:"task_id"`
+	Author    int64     `json:"author"` // Telegram ID автора
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// newComment проверяет текст и собирает комментарий (ID назначит хранилище)
+func newComment(taskID int, author int64, text string, now time.Time) (Comment, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Comment{}, ErrEmptyComment
+	}
+	if utf8.RuneCountInString(text) > maxCommentRunes {
+		return Comment{}, ErrCommentTooLong
+	}
+	return Comment{TaskID: taskID, Author: author, Text: text, CreatedAt: now}, nil
+}
+
+// nextCommentID — номер следующего комментария задачи
+func nextCommentID(comments []Comment) int {
+	if len(comments) == 0 {
+		return 1
+	}
+	return comments[len(comments)-1].ID + 1
+}
@@ -0,0 +1,349 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// РАЗБОР ДАТ НА РУССКОМ ЯЗЫКЕ
+//
+// ParseDeadline понимает то, что люди обычно пишут в чат:
+//   "завтра в 18:00", "сегодня 21:30", "послезавтра"
+//   "в пятницу", "во вторник в 10", "в субботу в 9 вечера"
+//   "через 3 дня", "через неделю", "через 2 часа", "через 30 минут"
+//   "25.12", "25.12.2026 18:00", "25 декабря", "2026-12-25"
+//   "18:00" (сегодня, а если время прошло — завтра)
+//
+// Если время не указано, дедлайн ставится на конец дня (23:59).
+// Все даты считаются в часовом поясе, в котором передано now.
+//
+// Срок в прошлом — ошибка (*PastDateError): "сегодня в 10:00",
+// отправленное вечером, скорее опечатка, чем просьба о завтрашнем
+// дне. Вперёд переносятся только время без даты (на завтра) и дата
+// без года — на ближайший год, где она ещё впереди и существует
+// ("29.02" — на ближайший високосный).
+// ============================================================
+
+// ErrBadDate — текст не удалось распознать как дату
+var ErrBadDate = errors.New("не удалось распознать дату")
+
+// ErrPastDate — дата распознана, но уже прошла
+// Сам момент передаёт *PastDateError
+var ErrPastDate = errors.New("срок уже прошёл")
+
+// PastDateError — распознанный срок At раньше текущего момента
+// errors.Is(err, ErrPastDate) для неё возвращает true
+type PastDateError struct {
+	At time.Time
+}
+
+func (e *PastDateError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrPastDate, e.At.Format("02.01.2006 15:04"))
+}
+
+func (e *PastDateError) Is(target error) bool {
+	return target == ErrPastDate
+}
+
+// Время по умолчанию, если в тексте указан только день
+const (
+	defaultDeadlineHour   = 23
+	defaultDeadlineMinute = 59
+)
+
+var (
+	// "через 2 часа", "через полчаса", "через 30 минут" — точное время от now
+	reInDuration = regexp.MustCompile(`^через\s+(?:(\d+)\s+)?(пол\s*часа|минут[уы]?|час(?:а|ов)?)$`)
+
+	// "через 3 дня", "через неделю", "через 2 месяца" — сдвиг по дням
+	reInDays = regexp.MustCompile(`^через\s+(?:(\d+)\s+)?(день|дня|дней|сутки|суток|недел[юиь]|месяц(?:а|ев)?)$`)
+
+	// Время: "18:00", "в 18:00", "в 9", "в 9 вечера"
+	reClock     = regexp.MustCompile(`(?:^|\s)(?:в\s+)?(\d{1,2}):(\d{2})(?:\s+(утра|дня|вечера|ночи))?(?:\s|$)`)
+	reHourOnly  = regexp.MustCompile(`(?:^|\s)в\s+(\d{1,2})(?:\s+(?:час(?:а|ов)?))?(?:\s+(утра|дня|вечера|ночи))?(?:\s|$)`)
+	reNumDate   = regexp.MustCompile(`^(\d{1,2})[./](\d{1,2})(?:[./](\d{2}|\d{4}))?$`)
+	reISODate   = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+	reWordDate  = regexp.MustCompile(`^(\d{1,2})\s+([а-я]+)(?:\s+(\d{4}))?$`)
+	reWeekday   = regexp.MustCompile(`^(?:(?:в|во)\s+)?(?:(?:следующ|ближайш)[а-я]*\s+)?([а-я]+)$`)
+	reSpaces    = regexp.MustCompile(`\s+`)
+	punctuation = strings.NewReplacer(",", " ", "!", " ", "?", " ")
+)
+
+// Дни недели: начало слова → день (подходит и "пятница", и "пятницу", и "пт")
+var weekdayStems = []struct {
+	stem string
+	day  time.Weekday
+}{
+	{"понедельник", time.Monday}, {"пн", time.Monday},
+	{"вторник", time.Tuesday}, {"вт", time.Tuesday},
+	{"сред", time.Wednesday}, {"ср", time.Wednesday},
+	{"четверг", time.Thursday}, {"чт", time.Thursday},
+	{"пятниц", time.Friday}, {"пт", time.Friday},
+	{"суббот", time.Saturday}, {"сб", time.Saturday},
+	{"воскресень", time.Sunday}, {"вс", time.Sunday},
+}
+
+// Месяцы: первые буквы названия → месяц ("декабря", "дек")
+var monthStems = []struct {
+	stem  string
+	month time.Month
+}{
+	{"янв", time.January}, {"фев", time.February}, {"мар", time.March},
+	{"апр", time.April}, {"мая", time.May}, {"май", time.May},
+	{"июн", time.June}, {"июл", time.July}, {"авг", time.August},
+	{"сен", time.September}, {"окт", time.October}, {"ноя", time.November},
+	{"дек", time.December},
+}
+
+// ParseDeadline превращает текст пользователя в момент времени
+func ParseDeadline(text string, now time.Time) (time.Time, error) {
+	s := normalizeDateText(text)
+	if s == "" {
+		return time.Time{}, ErrBadDate
+	}
+
+	// 1. "через N часов/минут" — считаем от текущего момента
+	if m := reInDuration.FindStringSubmatch(s); m != nil {
+		n := atoiDefault(m[1], 1)
+		switch {
+		case strings.HasPrefix(m[2], "пол"):
+			return now.Add(30 * time.Minute), nil
+		case strings.HasPrefix(m[2], "минут"):
+			return now.Add(time.Duration(n) * time.Minute), nil
+		default:
+			return now.Add(time.Duration(n) * time.Hour), nil
+		}
+	}
+
+	// 2. Отделяем время суток ("в 18:00") от даты ("завтра")
+	hour, minute, hasTime, rest, err := extractClock(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// 3. Разбираем дату
+	year, month, day := now.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	hasDate := rest != ""
+	if hasDate {
+		date, err = parseDatePart(rest, now)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if !hasTime {
+		hour, minute = defaultDeadlineHour, defaultDeadlineMinute
+	}
+	result := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, now.Location())
+
+	// Только время без даты: если оно уже прошло — значит, завтра
+	if !hasDate && !result.After(now) {
+		result = result.AddDate(0, 0, 1)
+	}
+	if result.Before(now) {
+		return time.Time{}, &PastDateError{At: result}
+	}
+	return result, nil
+}
+
+// extractClock находит в строке время суток и возвращает строку без него
+func extractClock(s string) (hour, minute int, found bool, rest string, err error) {
+	if m := reClock.FindStringSubmatchIndex(s); m != nil {
+		hour, _ = strconv.Atoi(s[m[2]:m[3]])
+		minute, _ = strconv.Atoi(s[m[4]:m[5]])
+		if m[6] >= 0 {
+			hour = applyDayPart(hour, s[m[6]:m[7]])
+		}
+		rest = strings.TrimSpace(s[:m[0]] + " " + s[m[1]:])
+		found = true
+	} else if m := reHourOnly.FindStringSubmatchIndex(s); m != nil {
+		hour, _ = strconv.Atoi(s[m[2]:m[3]])
+		if m[4] >= 0 {
+			hour = applyDayPart(hour, s[m[4]:m[5]])
+		}
+		rest = strings.TrimSpace(s[:m[0]] + " " + s[m[1]:])
+		found = true
+	} else {
+		return 0, 0, false, s, nil
+	}
+
+	if hour > 23 || minute > 59 {
+		return 0, 0, false, "", fmt.Errorf("%w: неверное время", ErrBadDate)
+	}
+	return hour, minute, found, reSpaces.ReplaceAllString(rest, " "), nil
+}
+
+// applyDayPart учитывает уточнение "утра/дня/вечера/ночи" ("в 6 вечера" → 18)
+func applyDayPart(hour int, part string) int {
+	switch part {
+	case "дня", "вечера":
+		if hour < 12 {
+			return hour + 12
+		}
+	case "ночи":
+		if hour == 12 {
+			return 0
+		}
+	}
+	return hour
+}
+
+// parseDatePart разбирает дату без времени суток
+func parseDatePart(s string, now time.Time) (time.Time, error) {
+	loc := now.Location()
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, loc)
+
+	switch s {
+	case "сегодня":
+		return today, nil
+	case "завтра":
+		return today.AddDate(0, 0, 1), nil
+	case "послезавтра":
+		return today.AddDate(0, 0, 2), nil
+	}
+
+	// "через 3 дня", "через неделю"
+	if m := reInDays.FindStringSubmatch(s); m != nil {
+		n := atoiDefault(m[1], 1)
+		switch {
+		case strings.HasPrefix(m[2], "недел"):
+			return today.AddDate(0, 0, 7*n), nil
+		case strings.HasPrefix(m[2], "месяц"):
+			return today.AddDate(0, n, 0), nil
+		default:
+			return today.AddDate(0, 0, n), nil
+		}
+	}
+
+	// "2026-12-25"
+	if m := reISODate.FindStringSubmatch(s); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		mon, _ := strconv.Atoi(m[2])
+		d, _ := strconv.Atoi(m[3])
+		return makeDate(y, mon, d, loc)
+	}
+
+	// "25.12", "25.12.26", "25/12/2026"
+	if m := reNumDate.FindStringSubmatch(s); m != nil {
+		d, _ := strconv.Atoi(m[1])
+		mon, _ := strconv.Atoi(m[2])
+		if m[3] == "" {
+			return nextDate(today, mon, d)
+		}
+		y, _ := strconv.Atoi(m[3])
+		if y < 100 {
+			y += 2000
+		}
+		return makeDate(y, mon, d, loc)
+	}
+
+	// "25 декабря", "25 декабря 2026"
+	if m := reWordDate.FindStringSubmatch(s); m != nil {
+		d, _ := strconv.Atoi(m[1])
+		mon, ok := matchMonth(m[2])
+		if !ok {
+			return time.Time{}, ErrBadDate
+		}
+		if m[3] == "" {
+			return nextDate(today, int(mon), d)
+		}
+		y, _ := strconv.Atoi(m[3])
+		return makeDate(y, int(mon), d, loc)
+	}
+
+	// "в пятницу", "в следующий вторник", "пт"
+	if m := reWeekday.FindStringSubmatch(s); m != nil {
+		if wd, ok := matchWeekday(m[1]); ok {
+			// Ближайший такой день после сегодняшнего
+			// ("в пятницу", сказанное в пятницу, — это через неделю)
+			days := (int(wd) - int(today.Weekday()) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+			return today.AddDate(0, 0, days), nil
+		}
+	}
+
+	return time.Time{}, ErrBadDate
+}
+
+// nextDate возвращает ближайшую дату с таким днём и месяцем, начиная
+// с сегодняшней. "29.02" ищется среди ближайших високосных лет
+func nextDate(today time.Time, month, day int) (time.Time, error) {
+	// Високосный год бывает не реже чем раз в 8 лет (2096 → 2104)
+	for year := today.Year(); year <= today.Year()+8; year++ {
+		date, err := makeDate(year, month, day, today.Location())
+		if err == nil && !date.Before(today) {
+			return date, nil
+		}
+	}
+	return time.Time{}, ErrBadDate
+}
+
+// makeDate собирает дату и проверяет, что она существует (нет "31.02")
+func makeDate(year, month, day int, loc *time.Location) (time.Time, error) {
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, ErrBadDate
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+	if date.Day() != day {
+		return time.Time{}, ErrBadDate
+	}
+	return date, nil
+}
+
+func matchWeekday(word string) (time.Weekday, bool) {
+	for _, w := range weekdayStems {
+		// Короткие формы ("пт") должны совпадать целиком
+		if word == w.stem || (len([]rune(w.stem)) > 2 && strings.HasPrefix(word, w.stem)) {
+			return w.day, true
+		}
+	}
+	return 0, false
+}
+
+func matchMonth(word string) (time.Month, bool) {
+	for _, m := range monthStems {
+		if strings.HasPrefix(word, m.stem) {
+			return m.month, true
+		}
+	}
+	return 0, false
+}
+
+// normalizeDateText: нижний регистр, ё → е, без лишних пробелов и знаков
+func normalizeDateText(text string) string {
+	s := strings.ToLower(strings.TrimSpace(text))
+	s = strings.ReplaceAll(s, "ё", "е")
+	s = punctuation.Replace(s)
+	s = strings.TrimPrefix(s, "до ")
+	return strings.TrimSpace(reSpaces.ReplaceAllString(s, " "))
+}
+
+func atoiDefault(s string, def int) int {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return def
+}
+
+// ============================================================
+// ОТОБРАЖЕНИЕ ДЕДЛАЙНОВ
+// ============================================================
+
+// formatDeadline — дедлайн для сообщений: "25.12.2026 18:00"
+func formatDeadline(deadline time.Time, loc *time.Location) string {
+	return deadline.In(loc).Format("02.01.2006 15:04")
+}
+
+// formatDeadlineShort — короткая форма для кнопок: "25.12 18:00"
+func formatDeadlineShort(deadline time.Time, loc *time.Location) string {
+	return deadline.In(loc).Format("02.01 15:04")
+}
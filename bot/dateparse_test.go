@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var msk = time.FixedZone("MSK", 3*60*60)
+
+// at — момент в часовом поясе MSK
+func at(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, msk)
+}
+
+func TestParseDeadline(t *testing.T) {
+	now := at(2027, time.January, 4, 20, 0) // Понедельник, 20:00
+
+	tests := []struct {
+		text string
+		want time.Time
+	}{
+		// Относительные даты
+		{"сегодня", at(2027, time.January, 4, 23, 59)},
+		{"сегодня в 21:30", at(2027, time.January, 4, 21, 30)},
+		{"Завтра в 18:00", at(2027, time.January, 5, 18, 0)},
+		{"послезавтра", at(2027, time.January, 6, 23, 59)},
+		{"через 3 дня", at(2027, time.January, 7, 23, 59)},
+		{"через неделю", at(2027, time.January, 11, 23, 59)},
+		{"через 2 месяца", at(2027, time.March, 4, 23, 59)},
+		{"через 2 часа", at(2027, time.January, 4, 22, 0)},
+		{"через полчаса", at(2027, time.January, 4, 20, 30)},
+		{"через 15 минут", at(2027, time.January, 4, 20, 15)},
+
+		// Дни недели: "в понедельник" в понедельник — через неделю
+		{"в пятницу", at(2027, time.January, 8, 23, 59)},
+		{"во вторник в 10", at(2027, time.January, 5, 10, 0)},
+		{"в субботу в 9 вечера", at(2027, time.January, 9, 21, 0)},
+		{"в понедельник", at(2027, time.January, 11, 23, 59)},
+		{"пт", at(2027, time.January, 8, 23, 59)},
+
+		// Только время: прошло — значит, завтра
+		{"21:00", at(2027, time.January, 4, 21, 0)},
+		{"в 10:00", at(2027, time.January, 5, 10, 0)},
+		{"20:00", at(2027, time.January, 5, 20, 0)},
+
+		// Числом и словами; без года — ближайшая такая дата
+		{"25.12", at(2027, time.December, 25, 23, 59)},
+		{"04.01", at(2027, time.January, 4, 23, 59)},
+		{"03.01", at(2028, time.January, 3, 23, 59)},
+		{"25.12.2027 18:00", at(2027, time.December, 25, 18, 0)},
+		{"25/12/27", at(2027, time.December, 25, 23, 59)},
+		{"25 декабря", at(2027, time.December, 25, 23, 59)},
+		{"1 мая 2028", at(2028, time.May, 1, 23, 59)},
+		{"2027-12-25", at(2027, time.December, 25, 23, 59)},
+		{"до 25.12, 18:00!", at(2027, time.December, 25, 18, 0)},
+
+		// 29 февраля — ближайший високосный год
+		{"29.02", at(2028, time.February, 29, 23, 59)},
+		{"29 февраля", at(2028, time.February, 29, 23, 59)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseDeadline(tt.text, now)
+			if err != nil {
+				t.Fatalf("ParseDeadline(%q): %v", tt.text, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("ParseDeadline(%q) = %v, ожидалось %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDeadlineErrors(t *testing.T) {
+	now := at(2027, time.January, 4, 20, 0)
+
+	tests := []struct {
+		text string
+		want error
+	}{
+		{"", ErrBadDate},
+		{"когда-нибудь", ErrBadDate},
+		{"31.02", ErrBadDate},
+		{"29.02.2027", ErrBadDate},
+		{"32.01", ErrBadDate},
+		{"25 чегототам", ErrBadDate},
+		{"завтра в 25:00", ErrBadDate},
+		{"в 10:75", ErrBadDate},
+
+		// Распознано, но уже прошло — не переносим молча
+		{"сегодня в 10:00", ErrPastDate},
+		{"сегодня 19:59", ErrPastDate},
+		{"04.01 18:00", ErrPastDate},
+		{"25.12.2026", ErrPastDate},
+		{"2026-12-31", ErrPastDate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseDeadline(tt.text, now)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ParseDeadline(%q) = %v, %v; ожидалась ошибка %v", tt.text, got, err, tt.want)
+			}
+		})
+	}
+
+	// Ошибка о прошедшем сроке говорит, какой срок распознан
+	_, err := ParseDeadline("сегодня в 10:00", now)
+	var past *PastDateError
+	if !errors.As(err, &past) || !past.At.Equal(at(2027, time.January, 4, 10, 0)) {
+		t.Fatalf("ParseDeadline(сегодня в 10:00): %v, ожидался *PastDateError на 04.01.2027 10:00", err)
+	}
+}
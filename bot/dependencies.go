@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ============================================================
+// ЗАВИСИМОСТИ МЕЖДУ ЗАДАЧАМИ ("заблокирована задачами ...")
+//
+// Task.BlockedBy — ID задач того же пользователя, которые нужно
+// выполнить раньше. Пока хоть одна из них не выполнена, задачу
+// нельзя перевести в статус "Выполнена".
+//
+// Зависимости не могут образовывать цикл (A ждёт B, B ждёт A) —
+// иначе ни одну из задач нельзя было бы завершить.
+//
+// Функции ниже работают со списком задач пользователя и
+// одинаково используются всеми хранилищами.
+// ============================================================
+
+var (
+	ErrDependencyCycle = errors.New("зависимость создаёт цикл: задачи ждали бы друг друга")
+	ErrSelfDependency  = errors.New("задача не может зависеть сама от себя")
+
+	// ErrTaskBlocked — задачу нельзя завершить, пока не выполнены блокирующие
+	// Конкретные задачи-блокеры передаёт *BlockedError
+	ErrTaskBlocked = errors.New("задача заблокирована невыполненными задачами")
+)
+
+// BlockedError — задачу нельзя завершить из-за невыполненных задач Blockers
+// errors.Is(err, ErrTaskBlocked) для неё возвращает true
+type BlockedError struct {
+	Blockers []Task
+}
+
+func (e *BlockedError) Error() string {
+	titles := make([]string, len(e.Blockers))
+	for i, t := range e.Blockers {
+		titles[i] = fmt.Sprintf("«%s»", t.Title)
+	}
+	return fmt.Sprintf("%v: %s", ErrTaskBlocked, strings.Join(titles, ", "))
+}
+
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrTaskBlocked
+}
+
+// findTask ищет задачу в списке по ID
+func findTask(tasks []Task, id int) (Task, bool) {
+	for _, t := range tasks {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Task{}, false
+}
+
+// OpenBlockers возвращает невыполненные задачи, блокирующие task
+func OpenBlockers(tasks []Task, task Task) []Task {
+	var open []Task
+	for _, id := range task.BlockedBy {
+		if blocker, ok := findTask(tasks, id); ok && !blocker.IsDone() {
+			open = append(open, blocker)
+		}
+	}
+	return open
+}
+
+// checkDependency проверяет, можно ли добавить зависимость
+// "taskID заблокирована blockerID"
+func checkDependency(tasks []Task, taskID, blockerID int) error {
+	if taskID == blockerID {
+		return ErrSelfDependency
+	}
+	if _, ok := findTask(tasks, taskID); !ok {
+		return ErrTaskNotFound
+	}
+	if _, ok := findTask(tasks, blockerID); !ok {
+		return ErrTaskNotFound
+	}
+
+	// Цикл появится, если blockerID уже (прямо или через другие
+	// задачи) ждёт taskID. Обходим зависимости в глубину от blockerID.
+	visited := make(map[int]bool)
+	stack := []int{blockerID}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == taskID {
+			return ErrDependencyCycle
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		if t, ok := findTask(tasks, id); ok {
+			stack = append(stack, t.BlockedBy...)
+		}
+	}
+	return nil
+}
+
+// unblockedBy возвращает задачи, которые ждали doneID и теперь
+// не ждут ни одной невыполненной задачи (tasks — уже после изменения)
+func unblockedBy(tasks []Task, doneID int) []Task {
+	var result []Task
+	for _, t := range tasks {
+		if !t.IsDone() && slices.Contains(t.BlockedBy, doneID) && len(OpenBlockers(tasks, t)) == 0 {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// blockedSet — ID невыполненных задач, которые сейчас чего-то ждут
+func blockedSet(tasks []Task) map[int]bool {
+	blocked := make(map[int]bool)
+	for _, t := range tasks {
+		if !t.IsDone() && len(OpenBlockers(tasks, t)) > 0 {
+			blocked[t.ID] = true
+		}
+	}
+	return blocked
+}
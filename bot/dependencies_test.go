@@ -0,0 +1,148 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+)
+
+// depTasks — задачи 1..4; blockedBy[id] — кого ждёт задача id
+func depTasks(blockedBy map[int][]int, done ...int) []Task {
+	tasks := make([]Task, 0, 4)
+	for id := 1; id <= 4; id++ {
+		task := Task{ID: id, Title: "Задача", Category: CategoryTodo, BlockedBy: blockedBy[id]}
+		for _, d := range done {
+			if d == id {
+				task.Category = CategoryDone
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// withoutTask — список задач без удалённой (в корзине)
+func withoutTask(tasks []Task, id int) []Task {
+	var result []Task
+	for _, t := range tasks {
+		if t.ID != id {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+func TestCheckDependency(t *testing.T) {
+	tests := []struct {
+		name              string
+		tasks             []Task
+		taskID, blockerID int
+		want              error
+	}{
+		{
+			name:      "на саму себя",
+			tasks:     depTasks(nil),
+			taskID:    1,
+			blockerID: 1,
+			want:      ErrSelfDependency,
+		},
+		{
+			name:      "без цикла",
+			tasks:     depTasks(map[int][]int{2: {3}}),
+			taskID:    1,
+			blockerID: 2,
+		},
+		{
+			name:      "цикл из двух задач",
+			tasks:     depTasks(map[int][]int{2: {1}}),
+			taskID:    1,
+			blockerID: 2,
+			want:      ErrDependencyCycle,
+		},
+		{
+			name:      "цикл из трёх задач",
+			tasks:     depTasks(map[int][]int{2: {3}, 3: {1}}),
+			taskID:    1,
+			blockerID: 2,
+			want:      ErrDependencyCycle,
+		},
+		{
+			name:      "ромб — не цикл",
+			tasks:     depTasks(map[int][]int{2: {3, 4}, 3: {4}}),
+			taskID:    1,
+			blockerID: 2,
+		},
+		{
+			name:      "удалённый блокер",
+			tasks:     withoutTask(depTasks(nil), 2),
+			taskID:    1,
+			blockerID: 2,
+			want:      ErrTaskNotFound,
+		},
+		{
+			name:      "удалённая задача",
+			tasks:     withoutTask(depTasks(nil), 1),
+			taskID:    1,
+			blockerID: 2,
+			want:      ErrTaskNotFound,
+		},
+		{
+			// Задача 3 ждёт удалённую 4 — обход не должен на ней спотыкаться
+			name:      "цепочка через удалённую задачу",
+			tasks:     withoutTask(depTasks(map[int][]int{2: {3}, 3: {4}}), 4),
+			taskID:    1,
+			blockerID: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDependency(tt.tasks, tt.taskID, tt.blockerID)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("checkDependency(%d, %d) = %v, ожидалось %v", tt.taskID, tt.blockerID, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenBlockers(t *testing.T) {
+	tests := []struct {
+		name  string
+		tasks []Task
+		want  []int
+	}{
+		{
+			name:  "все блокеры открыты",
+			tasks: depTasks(map[int][]int{1: {2, 3}}),
+			want:  []int{2, 3},
+		},
+		{
+			name:  "выполненный блокер не мешает",
+			tasks: depTasks(map[int][]int{1: {2, 3}}, 2),
+			want:  []int{3},
+		},
+		{
+			name:  "удалённый блокер не мешает",
+			tasks: withoutTask(depTasks(map[int][]int{1: {2, 3}}), 3),
+			want:  []int{2},
+		},
+		{
+			name:  "все выполнены или удалены",
+			tasks: withoutTask(depTasks(map[int][]int{1: {2, 3}}, 2), 3),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, _ := findTask(tt.tasks, 1)
+			got := OpenBlockers(tt.tasks, task)
+			if len(got) != len(tt.want) {
+				t.Fatalf("OpenBlockers = %v, ожидались задачи %v", got, tt.want)
+			}
+			for i, blocker := range got {
+				if blocker.ID != tt.want[i] {
+					t.Fatalf("OpenBlockers = %v, ожидались задачи %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,826 @@
+package bot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ============================================================
+// FileStore — хранилище задач в файлах на диске
+//
+// Данные держим в памяти (внутри обычного Storage), а каждое изменение
+// сразу дописываем в журнал journal.log и вызываем fsync.
+// При запуске загружаем снимок snapshot.json и "проигрываем" журнал.
+//
+// Чтобы журнал не рос бесконечно, каждые snapshotEvery записей
+// (и при закрытии) состояние сохраняется в новый снимок,
+// а журнал очищается (компактизация).
+//
+// Формат строки журнала: "<crc32 в hex> <JSON-запись>\n"
+// Если последняя строка оборвана (сбой посреди записи) —
+// она отбрасывается, а не ломает запуск.
+// ============================================================
+type FileStore struct {
+	mem *Storage // Данные в памяти (источник для чтения)
+	dir string   // Папка с файлами хранилища
+
+	mu            sync.Mutex // Сериализует изменения и запись в журнал
+	journal       *os.File   // Открытый на дозапись файл журнала
+	seq           uint64     // Номер последней записанной записи
+	records       int        // Сколько записей в журнале с момента последнего снимка
+	snapshotEvery int        // Через сколько записей делать снимок
+	pending       []change   // Изменения текущей операции, ещё не записанные на диск
+	err           error      // Первая ошибка записи: после неё хранилище только читает
+	closed        bool       // Close уже вызван
+}
+
+const (
+	journalFileName  = "journal.log"
+	snapshotFileName = "snapshot.json"
+
+	// DefaultSnapshotEvery — через сколько записей журнала делать снимок
+	DefaultSnapshotEvery = 500
+)
+
+// journalRecord — строка журнала: порядковый номер + изменение
+type journalRecord struct {
+	Seq uint64 `json:"seq"`
+	change
+}
+
+// snapshotFile — содержимое snapshot.json
+// Seq — номер последней записи журнала, уже учтённой в снимке
+type snapshotFile struct {
+	Seq   uint64       `json:"seq"`
+	State storageState `json:"state"`
+}
+
+// NewFileStore открывает (или создаёт) файловое хранилище в папке dir
+// snapshotEvery <= 0 означает значение по умолчанию
+func NewFileStore(dir string, snapshotEvery int) (*FileStore, error) {
+	if snapshotEvery <= 0 {
+		snapshotEvery = DefaultSnapshotEvery
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("создание папки хранилища: %w", err)
+	}
+
+	fs := &FileStore{
+		mem:           NewStorage(),
+		dir:           dir,
+		snapshotEvery: snapshotEvery,
+	}
+
+	if err := fs.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := fs.replayJournal(); err != nil {
+		return nil, err
+	}
+
+	journal, err := os.OpenFile(fs.path(journalFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("открытие журнала: %w", err)
+	}
+	fs.journal = journal
+
+	// Одноразовые миграции старых данных:
+	//   статусы-подписи → коды статусов
+	//   задачи без приоритета → обычный приоритет
+	//   задачи без категории статуса → категория из набора статусов
+	// Сразу сохраняем снимок, чтобы больше к этому не возвращаться
+	// (по порядку: категорию ищем уже по коду статуса)
+	fixed := fs.mem.migrateLegacyStatuses() + fs.mem.fillDefaultPriorities() + fs.mem.fillStatusCategories()
+	if fixed > 0 {
+		log.Printf("💾 Обновлены старые данные у %d задач", fixed)
+		if err := fs.compact(); err != nil {
+			journal.Close()
+			return nil, fmt.Errorf("сохранение снимка после миграции данных: %w", err)
+		}
+	}
+
+	// Все изменения внутреннего Storage собираем в pending,
+	// а на диск их записывает flush() в конце каждой операции
+	fs.mem.onChange = func(c change) {
+		fs.pending = append(fs.pending, c)
+	}
+
+	log.Printf("💾 Файловое хранилище %s: загружено, журнал — %d записей", dir, fs.records)
+	return fs, nil
+}
+
+// ============================================================
+// Методы TaskStore
+// Чтение идёт прямо из памяти, изменения — через flush()
+// ============================================================
+
+func (fs *FileStore) AddTask(userID, actor int64, draft TaskDraft) (Task, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Task{}, err
+	}
+	task, err := fs.mem.AddTask(userID, actor, draft)
+	if err != nil {
+		return Task{}, err
+	}
+	return task, fs.flush()
+}
+
+func (fs *FileStore) GetTasks(userID int64) ([]Task, error) {
+	return fs.mem.GetTasks(userID)
+}
+
+func (fs *FileStore) GetTask(userID int64, taskID int) (Task, error) {
+	return fs.mem.GetTask(userID, taskID)
+}
+
+func (fs *FileStore) UpdateTask(userID int64, taskID int, actor int64, patch TaskPatch) (Task, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Task{}, err
+	}
+	task, err := fs.mem.UpdateTask(userID, taskID, actor, patch)
+	if err != nil {
+		return Task{}, err
+	}
+	return task, fs.flush()
+}
+
+func (fs *FileStore) UpdateStatus(userID int64, taskID int, actor int64, newStatus string) (StatusChange, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return StatusChange{}, err
+	}
+	result, err := fs.mem.UpdateStatus(userID, taskID, actor, newStatus)
+	if err != nil {
+		return StatusChange{}, err
+	}
+	return result, fs.flush()
+}
+
+func (fs *FileStore) DeleteTask(userID int64, taskID int, actor int64) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return err
+	}
+	if err := fs.mem.DeleteTask(userID, taskID, actor); err != nil {
+		return err
+	}
+	return fs.flush()
+}
+
+func (fs *FileStore) GetTrash(userID int64) ([]Task, error) {
+	return fs.mem.GetTrash(userID)
+}
+
+func (fs *FileStore) RestoreTask(userID int64, taskID int, actor int64) (Task, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Task{}, err
+	}
+	task, err := fs.mem.RestoreTask(userID, taskID, actor)
+	if err != nil {
+		return Task{}, err
+	}
+	return task, fs.flush()
+}
+
+func (fs *FileStore) PurgeTrash(before time.Time) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return 0, err
+	}
+	purged, err := fs.mem.PurgeTrash(before)
+	if err != nil {
+		return 0, err
+	}
+	return purged, fs.flush()
+}
+
+func (fs *FileStore) AddDependency(userID int64, taskID, blockerID int, actor int64) (Task, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Task{}, err
+	}
+	task, err := fs.mem.AddDependency(userID, taskID, blockerID, actor)
+	if err != nil {
+		return Task{}, err
+	}
+	return task, fs.flush()
+}
+
+func (fs *FileStore) RemoveDependency(userID int64, taskID, blockerID int, actor int64) (Task, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Task{}, err
+	}
+	task, err := fs.mem.RemoveDependency(userID, taskID, blockerID, actor)
+	if err != nil {
+		return Task{}, err
+	}
+	return task, fs.flush()
+}
+
+func (fs *FileStore) AddChecklistItem(userID int64, taskID int, actor int64, text string) (ChecklistItem, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return ChecklistItem{}, err
+	}
+	item, err := fs.mem.AddChecklistItem(userID, taskID, actor, text)
+	if err != nil {
+		return ChecklistItem{}, err
+	}
+	return item, fs.flush()
+}
+
+func (fs *FileStore) ToggleChecklistItem(userID int64, taskID, itemID int, actor int64) (ChecklistItem, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return ChecklistItem{}, err
+	}
+	item, err := fs.mem.ToggleChecklistItem(userID, taskID, itemID, actor)
+	if err != nil {
+		return ChecklistItem{}, err
+	}
+	return item, fs.flush()
+}
+
+func (fs *FileStore) RemoveChecklistItem(userID int64, taskID, itemID int, actor int64) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return err
+	}
+	if err := fs.mem.RemoveChecklistItem(userID, taskID, itemID, actor); err != nil {
+		return err
+	}
+	return fs.flush()
+}
+
+func (fs *FileStore) GetHistory(userID int64, taskID int) ([]TaskEvent, error) {
+	return fs.mem.GetHistory(userID, taskID)
+}
+
+func (fs *FileStore) GetStatusEvents(userID int64) (map[int][]TaskEvent, error) {
+	return fs.mem.GetStatusEvents(userID)
+}
+
+func (fs *FileStore) AddComment(userID int64, taskID int, author int64, text string) (Comment, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Comment{}, err
+	}
+	comment, err := fs.mem.AddComment(userID, taskID, author, text)
+	if err != nil {
+		return Comment{}, err
+	}
+	return comment, fs.flush()
+}
+
+func (fs *FileStore) GetComments(userID int64, taskID int) ([]Comment, error) {
+	return fs.mem.GetComments(userID, taskID)
+}
+
+func (fs *FileStore) AddAttachment(userID int64, taskID int, att Attachment) (Attachment, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Attachment{}, err
+	}
+	att, err := fs.mem.AddAttachment(userID, taskID, att)
+	if err != nil {
+		return Attachment{}, err
+	}
+	return att, fs.flush()
+}
+
+func (fs *FileStore) GetAttachments(userID int64, taskID int) ([]Attachment, error) {
+	return fs.mem.GetAttachments(userID, taskID)
+}
+
+func (fs *FileStore) RemoveAttachment(userID int64, taskID, attachmentID int) (Attachment, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Attachment{}, err
+	}
+	att, err := fs.mem.RemoveAttachment(userID, taskID, attachmentID)
+	if err != nil {
+		return Attachment{}, err
+	}
+	return att, fs.flush()
+}
+
+func (fs *FileStore) StartTimer(userID int64, taskID int, member int64) (TimeEntry, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return TimeEntry{}, err
+	}
+	entry, err := fs.mem.StartTimer(userID, taskID, member)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	return entry, fs.flush()
+}
+
+func (fs *FileStore) StopTimer(userID int64, taskID int, member int64) (TimeEntry, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return TimeEntry{}, err
+	}
+	entry, err := fs.mem.StopTimer(userID, taskID, member)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	return entry, fs.flush()
+}
+
+func (fs *FileStore) AddTimeEntry(userID int64, taskID int, entry TimeEntry) (TimeEntry, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return TimeEntry{}, err
+	}
+	entry, err := fs.mem.AddTimeEntry(userID, taskID, entry)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	return entry, fs.flush()
+}
+
+func (fs *FileStore) GetTimeEntries(userID int64, taskID int) ([]TimeEntry, error) {
+	return fs.mem.GetTimeEntries(userID, taskID)
+}
+
+func (fs *FileStore) RemoveTimeEntry(userID int64, taskID, entryID int) (TimeEntry, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return TimeEntry{}, err
+	}
+	entry, err := fs.mem.RemoveTimeEntry(userID, taskID, entryID)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+	return entry, fs.flush()
+}
+
+func (fs *FileStore) GetWorkflow(userID int64) (Workflow, error) {
+	return fs.mem.GetWorkflow(userID)
+}
+
+func (fs *FileStore) SetWorkflow(userID int64, wf Workflow) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return err
+	}
+	if err := fs.mem.SetWorkflow(userID, wf); err != nil {
+		return err
+	}
+	return fs.flush()
+}
+
+func (fs *FileStore) GetProjects(userID int64) ([]Project, error) {
+	return fs.mem.GetProjects(userID)
+}
+
+func (fs *FileStore) AddProject(userID int64, draft ProjectDraft) (Project, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Project{}, err
+	}
+	project, err := fs.mem.AddProject(userID, draft)
+	if err != nil {
+		return Project{}, err
+	}
+	return project, fs.flush()
+}
+
+func (fs *FileStore) UpdateProject(userID int64, projectID int, patch ProjectPatch) (Project, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Project{}, err
+	}
+	project, err := fs.mem.UpdateProject(userID, projectID, patch)
+	if err != nil {
+		return Project{}, err
+	}
+	return project, fs.flush()
+}
+
+func (fs *FileStore) DeleteProject(userID int64, projectID int) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return err
+	}
+	if err := fs.mem.DeleteProject(userID, projectID); err != nil {
+		return err
+	}
+	return fs.flush()
+}
+
+func (fs *FileStore) GetWorkspaces(userID int64) ([]Workspace, error) {
+	return fs.mem.GetWorkspaces(userID)
+}
+
+func (fs *FileStore) GetWorkspace(workspaceID int64) (Workspace, error) {
+	return fs.mem.GetWorkspace(workspaceID)
+}
+
+func (fs *FileStore) CreateWorkspace(owner Member, name string) (Workspace, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Workspace{}, err
+	}
+	w, err := fs.mem.CreateWorkspace(owner, name)
+	if err != nil {
+		return Workspace{}, err
+	}
+	return w, fs.flush()
+}
+
+func (fs *FileStore) JoinWorkspace(member Member, code string) (Workspace, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Workspace{}, err
+	}
+	w, err := fs.mem.JoinWorkspace(member, code)
+	if err != nil {
+		return Workspace{}, err
+	}
+	return w, fs.flush()
+}
+
+func (fs *FileStore) LeaveWorkspace(userID, workspaceID int64) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return err
+	}
+	if err := fs.mem.LeaveWorkspace(userID, workspaceID); err != nil {
+		return err
+	}
+	return fs.flush()
+}
+
+func (fs *FileStore) SetMemberRole(actorID, workspaceID, userID int64, role Role) (Workspace, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return Workspace{}, err
+	}
+	w, err := fs.mem.SetMemberRole(actorID, workspaceID, userID, role)
+	if err != nil {
+		return Workspace{}, err
+	}
+	return w, fs.flush()
+}
+
+func (fs *FileStore) GetTags(userID int64) ([]TagInfo, error) {
+	return fs.mem.GetTags(userID)
+}
+
+func (fs *FileStore) DueTasks(until time.Time) ([]DueTask, error) {
+	return fs.mem.DueTasks(until)
+}
+
+func (fs *FileStore) MarkReminded(userID int64, taskID int, key string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return err
+	}
+	if err := fs.mem.MarkReminded(userID, taskID, key); err != nil {
+		return err
+	}
+	return fs.flush()
+}
+
+func (fs *FileStore) SnoozeReminder(userID int64, taskID int, until *time.Time) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.writable(); err != nil {
+		return err
+	}
+	if err := fs.mem.SnoozeReminder(userID, taskID, until); err != nil {
+		return err
+	}
+	return fs.flush()
+}
+
+// Close делает финальный снимок и закрывает журнал
+func (fs *FileStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.closed {
+		return nil
+	}
+	fs.closed = true
+
+	var snapErr error
+	if fs.err == nil && fs.records > 0 {
+		snapErr = fs.compact()
+	}
+	if err := fs.journal.Close(); err != nil && snapErr == nil {
+		snapErr = err
+	}
+	return snapErr
+}
+
+// ============================================================
+// ЗАПИСЬ
+// ============================================================
+
+// writable проверяет, можно ли сейчас менять данные (вызывать под fs.mu)
+func (fs *FileStore) writable() error {
+	if fs.closed {
+		return errors.New("хранилище закрыто")
+	}
+	if fs.err != nil {
+		return fmt.Errorf("хранилище доступно только для чтения после ошибки записи: %w", fs.err)
+	}
+	return nil
+}
+
+// flush записывает накопленные изменения в журнал и делает fsync
+// Вызывать под fs.mu. Если запись не удалась, данные в памяти уже
+// изменены, но на диске их нет — поэтому дальше хранилище
+// переходит в режим "только чтение", чтобы не разойтись ещё сильнее.
+func (fs *FileStore) flush() error {
+	if len(fs.pending) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	seq := fs.seq
+	for _, c := range fs.pending {
+		seq++
+		line, err := encodeRecord(journalRecord{Seq: seq, change: c})
+		if err != nil {
+			fs.pending = nil
+			fs.err = err
+			return err
+		}
+		buf.Write(line)
+	}
+	count := len(fs.pending)
+	fs.pending = nil
+
+	if _, err := fs.journal.Write(buf.Bytes()); err != nil {
+		fs.err = fmt.Errorf("запись журнала: %w", err)
+		return fs.err
+	}
+	if err := fs.journal.Sync(); err != nil {
+		fs.err = fmt.Errorf("fsync журнала: %w", err)
+		return fs.err
+	}
+	fs.seq = seq
+	fs.records += count
+
+	if fs.records >= fs.snapshotEvery {
+		if err := fs.compact(); err != nil {
+			// Журнал уже на диске, так что данные не потеряны —
+			// просто попробуем сделать снимок в следующий раз
+			log.Printf("⚠️  Не удалось сделать снимок хранилища: %v", err)
+		}
+	}
+	return nil
+}
+
+// compact сохраняет снимок состояния и очищает журнал (вызывать под fs.mu)
+//
+// Порядок важен для надёжности:
+// 1. пишем снимок во временный файл и делаем fsync
+// 2. атомарно переименовываем его в snapshot.json
+// 3. только после этого обрезаем журнал
+// Если упадём между шагами 2 и 3, записи журнала с seq <= seq снимка
+// будут пропущены при следующем запуске.
+func (fs *FileStore) compact() error {
+	data, err := json.Marshal(snapshotFile{Seq: fs.seq, State: fs.mem.exportState()})
+	if err != nil {
+		return err
+	}
+
+	tmp := fs.path(snapshotFileName + ".tmp")
+	if err := writeFileSync(tmp, data); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, fs.path(snapshotFileName)); err != nil {
+		return err
+	}
+	if err := syncDir(fs.dir); err != nil {
+		return err
+	}
+
+	if err := fs.journal.Truncate(0); err != nil {
+		return err
+	}
+	if err := fs.journal.Sync(); err != nil {
+		return err
+	}
+	fs.records = 0
+	return nil
+}
+
+// ============================================================
+// ЗАГРУЗКА
+// ============================================================
+
+// loadSnapshot загружает snapshot.json, если он есть
+func (fs *FileStore) loadSnapshot() error {
+	data, err := os.ReadFile(fs.path(snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("чтение снимка: %w", err)
+	}
+
+	var snap snapshotFile
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("снимок %s повреждён: %w", fs.path(snapshotFileName), err)
+	}
+	fs.mem.importState(snap.State)
+	fs.seq = snap.Seq
+	return nil
+}
+
+// replayJournal применяет записи журнала поверх снимка
+// Оборванная последняя запись отбрасывается (и обрезается в файле),
+// а повреждение в середине журнала считается ошибкой.
+func (fs *FileStore) replayJournal() error {
+	f, err := os.OpenFile(fs.path(journalFileName), os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("открытие журнала: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var offset int64 // Конец последней целой записи
+	lineNo := 0
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("чтение журнала: %w", readErr)
+		}
+		if len(line) == 0 {
+			break
+		}
+		lineNo++
+
+		rec, decodeErr := decodeRecord(line)
+		if decodeErr != nil || readErr == io.EOF {
+			// Запись без "\n" в конце или с неверной контрольной суммой
+			// допустима только последней — это след оборванной записи
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				log.Printf("⚠️  Журнал: отброшена оборванная запись в строке %d", lineNo)
+				if err := f.Truncate(offset); err != nil {
+					return fmt.Errorf("обрезка журнала: %w", err)
+				}
+				if err := f.Sync(); err != nil {
+					return fmt.Errorf("fsync журнала: %w", err)
+				}
+				break
+			}
+			return fmt.Errorf("журнал повреждён в строке %d: %v", lineNo, decodeErr)
+		}
+
+		offset += int64(len(line))
+		fs.records++
+		if rec.Seq <= fs.seq {
+			continue // Уже учтено в снимке
+		}
+		fs.mem.apply(rec.change)
+		fs.seq = rec.Seq
+	}
+	return nil
+}
+
+// ============================================================
+// ВСПОМОГАТЕЛЬНЫЕ ФУНКЦИИ
+// ============================================================
+
+func (fs *FileStore) path(name string) string {
+	return filepath.Join(fs.dir, name)
+}
+
+// encodeRecord превращает запись в строку журнала с контрольной суммой
+func encodeRecord(rec journalRecord) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	line := fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(payload), payload)
+	return []byte(line), nil
+}
+
+// decodeRecord разбирает строку журнала и проверяет контрольную сумму
+func decodeRecord(line []byte) (journalRecord, error) {
+	var rec journalRecord
+
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	sum, payload, ok := bytes.Cut(line, []byte(" "))
+	if !ok {
+		return rec, errors.New("нет контрольной суммы")
+	}
+	want, err := strconv.ParseUint(string(sum), 16, 32)
+	if err != nil {
+		return rec, fmt.Errorf("неверная контрольная сумма: %v", err)
+	}
+	if crc32.ChecksumIEEE(payload) != uint32(want) {
+		return rec, errors.New("контрольная сумма не совпадает")
+	}
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return rec, err
+	}
+	return rec, nil
+}
+
+// writeFileSync записывает файл целиком и делает fsync
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir делает fsync папки, чтобы переименование файла пережило сбой питания
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
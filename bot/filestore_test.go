@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const testUser int64 = 111
+
+// writeJournal открывает хранилище в dir и создаёт задачи
+// "Задача 1".."Задача n" — по записи журнала на задачу
+func writeJournal(t *testing.T, dir string, snapshotEvery, n int) *FileStore {
+	t.Helper()
+	fs, err := NewFileStore(dir, snapshotEvery)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	for i := 1; i <= n; i++ {
+		if _, err := fs.AddTask(testUser, testUser, TaskDraft{Title: fmt.Sprintf("Задача %d", i)}); err != nil {
+			t.Fatalf("AddTask %d: %v", i, err)
+		}
+	}
+	return fs
+}
+
+// crash закрывает журнал, не делая снимка (как при падении процесса)
+func crash(t *testing.T, fs *FileStore) {
+	t.Helper()
+	if err := fs.journal.Close(); err != nil {
+		t.Fatalf("закрытие журнала: %v", err)
+	}
+}
+
+// taskTitles — названия задач пользователя по порядку
+func taskTitles(t *testing.T, fs *FileStore) []string {
+	t.Helper()
+	tasks, err := fs.GetTasks(testUser)
+	if err != nil {
+		t.Fatalf("GetTasks: %v", err)
+	}
+	titles := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
+
+// lastLine — начало последней строки журнала
+func lastLine(data []byte) int {
+	return bytes.LastIndexByte(data[:len(data)-1], '\n') + 1
+}
+
+func TestFileStoreRecovery(t *testing.T) {
+	tests := []struct {
+		name          string
+		snapshotEvery int
+		tasks         int
+		clean         bool                // Закрыть штатно (со снимком), а потом вернуть старый журнал
+		damage        func([]byte) []byte // Что сделать с журналом перед повторным открытием
+		want          []string
+		wantErr       bool
+	}{
+		{
+			name:  "целый журнал",
+			tasks: 3,
+			want:  []string{"Задача 1", "Задача 2", "Задача 3"},
+		},
+		{
+			name:  "оборванная последняя запись",
+			tasks: 3,
+			damage: func(data []byte) []byte {
+				start := lastLine(data)
+				return data[:start+(len(data)-start)/2]
+			},
+			want: []string{"Задача 1", "Задача 2"},
+		},
+		{
+			name:  "последняя запись без перевода строки",
+			tasks: 3,
+			damage: func(data []byte) []byte {
+				return data[:len(data)-1]
+			},
+			want: []string{"Задача 1", "Задача 2"},
+		},
+		{
+			name:  "неверная контрольная сумма в последней записи",
+			tasks: 3,
+			damage: func(data []byte) []byte {
+				data = bytes.Clone(data)
+				data[lastLine(data)] ^= 0x01
+				return data
+			},
+			want: []string{"Задача 1", "Задача 2"},
+		},
+		{
+			name:  "повреждённая запись в середине",
+			tasks: 3,
+			damage: func(data []byte) []byte {
+				data = bytes.Clone(data)
+				data[0] ^= 0x01
+				return data
+			},
+			wantErr: true,
+		},
+		{
+			name:          "снимок и журнал после него",
+			snapshotEvery: 2,
+			tasks:         3,
+			want:          []string{"Задача 1", "Задача 2", "Задача 3"},
+		},
+		{
+			name:          "снимок и оборванный журнал после него",
+			snapshotEvery: 2,
+			tasks:         3,
+			damage: func(data []byte) []byte {
+				return data[:len(data)/2]
+			},
+			want: []string{"Задача 1", "Задача 2"},
+		},
+		{
+			name:  "журнал, уже учтённый в снимке",
+			tasks: 2,
+			clean: true,
+			want:  []string{"Задача 1", "Задача 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			journalPath := filepath.Join(dir, journalFileName)
+
+			fs := writeJournal(t, dir, tt.snapshotEvery, tt.tasks)
+			data, err := os.ReadFile(journalPath)
+			if err != nil {
+				t.Fatalf("чтение журнала: %v", err)
+			}
+			if tt.clean {
+				// Упали после снимка, но до обрезки журнала
+				if err := fs.Close(); err != nil {
+					t.Fatalf("Close: %v", err)
+				}
+			} else {
+				crash(t, fs)
+			}
+			if tt.damage != nil {
+				data = tt.damage(data)
+			}
+			if err := os.WriteFile(journalPath, data, 0o644); err != nil {
+				t.Fatalf("запись журнала: %v", err)
+			}
+
+			fs, err = NewFileStore(dir, tt.snapshotEvery)
+			if tt.wantErr {
+				if err == nil {
+					fs.Close()
+					t.Fatal("ожидалась ошибка открытия повреждённого журнала")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewFileStore: %v", err)
+			}
+			if got := taskTitles(t, fs); !slices.Equal(got, tt.want) {
+				t.Fatalf("задачи после восстановления: %q, ожидалось %q", got, tt.want)
+			}
+
+			// Оборванный хвост обрезан: новая запись ложится после целых
+			// и переживает ещё один перезапуск, а ID не повторяются
+			task, err := fs.AddTask(testUser, testUser, TaskDraft{Title: "Новая"})
+			if err != nil {
+				t.Fatalf("AddTask после восстановления: %v", err)
+			}
+			if task.ID != len(tt.want)+1 {
+				t.Errorf("ID новой задачи %d, ожидался %d", task.ID, len(tt.want)+1)
+			}
+			crash(t, fs)
+
+			fs, err = NewFileStore(dir, tt.snapshotEvery)
+			if err != nil {
+				t.Fatalf("повторное открытие: %v", err)
+			}
+			want := append(slices.Clone(tt.want), "Новая")
+			if got := taskTitles(t, fs); !slices.Equal(got, want) {
+				t.Fatalf("задачи после второго перезапуска: %q, ожидалось %q", got, want)
+			}
+			if err := fs.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+		})
+	}
+}
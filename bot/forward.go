@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ============================================================
+// ЗАДАЧИ ИЗ ПЕРЕСЛАННЫХ СООБЩЕНИЙ
+//
+// Работа часто приходит сообщениями в чатах групп. Такое сообщение
+// можно переслать боту — он предложит сделать из него задачу кнопкой
+// «✅ Создать задачу»: первая строка станет названием, весь текст —
+// описанием, а задача запомнит, кто и когда написал исходное
+// сообщение (Task.Source).
+//
+// Предложение бот отправляет ответом на пересланное сообщение,
+// поэтому по нажатию кнопки текст берётся из него же — ничего
+// не нужно держать в памяти между сообщениями
+// ============================================================
+
+// maxForwardTitle — длина названия задачи из первой строки сообщения
+const maxForwardTitle = 100
+
+// TaskSource — исходное сообщение, из которого сделана задача
+type TaskSource struct {
+	Sender   string    `json:"sender"`              // Имя автора или название канала
+	SenderID int64     `json:"sender_id,omitempty"` // Telegram ID автора (0 — скрыт настройками приватности или это канал)
+	Chat     string    `json:"chat,omitempty"`      // Канал или группа, если переслано оттуда
+	Date     time.Time `json:"date"`                // Когда было отправлено исходное сообщение
+}
+
+// Label — источник для карточки задачи: "Иван Петров (Группа БВТ) · 12.03.2026 14:20"
+func (s TaskSource) Label(loc *time.Location) string {
+	label := s.Sender
+	if s.Chat != "" && s.Chat != s.Sender {
+		label += " (" + s.Chat + ")"
+	}
+	return label + " · " + formatDeadline(s.Date, loc)
+}
+
+// isForwarded — сообщение переслано из другого чата
+func isForwarded(msg *tgbotapi.Message) bool {
+	return msg.ForwardDate != 0
+}
+
+// forwardText — текст пересланного сообщения (у фото и документов — подпись)
+func forwardText(msg *tgbotapi.Message) string {
+	if msg.Text != "" {
+		return strings.TrimSpace(msg.Text)
+	}
+	return strings.TrimSpace(msg.Caption)
+}
+
+// forwardSource — кто и когда написал исходное сообщение
+// Автор может скрыть ссылку на себя настройками приватности —
+// тогда Telegram присылает только имя (ForwardSenderName)
+func forwardSource(msg *tgbotapi.Message) *TaskSource {
+	source := &TaskSource{Date: time.Unix(int64(msg.ForwardDate), 0).UTC()}
+	switch {
+	case msg.ForwardFrom != nil:
+		member := memberOf(msg.ForwardFrom)
+		source.Sender = member.Name
+		source.SenderID = member.UserID
+	case msg.ForwardFromChat != nil:
+		source.Chat = msg.ForwardFromChat.Title
+		source.Sender = msg.ForwardFromChat.Title
+		if msg.ForwardSignature != "" {
+			source.Sender = msg.ForwardSignature
+		}
+	default:
+		source.Sender = msg.ForwardSenderName
+	}
+	if source.Sender == "" {
+		source.Sender = "неизвестный автор"
+	}
+	return source
+}
+
+// forwardDraft — черновик задачи из текста пересланного сообщения:
+// первая непустая строка — название, весь текст — описание
+// (если текст и есть название, описание не нужно)
+func forwardDraft(text string, source *TaskSource) TaskDraft {
+	text = strings.TrimSpace(text)
+	title, _, _ := strings.Cut(text, "\n")
+	title = truncate(strings.TrimSpace(title), maxForwardTitle)
+
+	draft := TaskDraft{Title: title, Source: source}
+	if text != title {
+		draft.Description = text
+	}
+	return draft
+}
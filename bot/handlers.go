@@ -1,10 +1,14 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -13,11 +17,42 @@ import (
 // Шаги диалога — определяют, чего бот ждёт от пользователя
 // ============================================================
 const (
-	StepNone        = ""                    // Обычное состояние (ничего не ждём)
-	StepWaitTitle   = "waiting_title"       // Ждём ввод названия задачи
-	StepWaitDesc    = "waiting_description" // Ждём ввод описания задачи
+	StepNone           = ""                    // Обычное состояние (ничего не ждём)
+	StepWaitTitle      = "waiting_title"       // Ждём ввод названия задачи
+	StepWaitDesc       = "waiting_description" // Ждём ввод описания задачи
+	StepWaitDeadline   = "waiting_deadline"    // Ждём срок выполнения задачи
+	StepWaitPriority   = "waiting_priority"    // Ждём выбор приоритета (кнопками)
+	StepEditTitle      = "editing_title"       // Ждём новое название задачи (TempTaskID)
+	StepEditDesc       = "editing_description" // Ждём новое описание задачи (TempTaskID)
+	StepEditDeadline   = "editing_deadline"    // Ждём новый срок задачи (TempTaskID)
+	StepEditTags       = "editing_tags"        // Ждём новые теги задачи (TempTaskID)
+	StepEditRecurrence = "editing_recurrence"  // Ждём правило повторения текстом (TempTaskID)
+	StepAddChecklist   = "adding_checklist"    // Ждём пункты чек-листа (TempTaskID)
+	StepWaitSearch     = "waiting_search"      // Ждём текст для поиска (/find без слов)
+	StepEditWorkflow   = "editing_workflow"    // Ждём новый набор статусов текстом (/statuses)
+	StepWaitProject    = "waiting_project"     // Ждём название нового проекта
+	StepWaitWorkspace  = "waiting_workspace"   // Ждём название новой команды (/team)
+	StepAddTime        = "adding_time"         // Ждём время, добавляемое вручную (TempTaskID)
 )
 
+// stepPermissions — какое право нужно, чтобы закончить шаг диалога
+// (роль могли понизить, пока пользователь набирал текст; см. permissions.go)
+var stepPermissions = map[string]Permission{
+	StepWaitTitle:      PermEdit,
+	StepWaitDesc:       PermEdit,
+	StepWaitDeadline:   PermEdit,
+	StepWaitPriority:   PermEdit,
+	StepEditTitle:      PermEdit,
+	StepEditDesc:       PermEdit,
+	StepEditDeadline:   PermEdit,
+	StepEditTags:       PermEdit,
+	StepEditRecurrence: PermEdit,
+	StepAddChecklist:   PermEdit,
+	StepAddTime:        PermEdit,
+	StepEditWorkflow:   PermManage,
+	StepWaitProject:    PermManage,
+}
+
 // ============================================================
 // handleMessage — обрабатывает все текстовые сообщения
 //
@@ -30,9 +65,29 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 	userID := msg.From.ID
 	chatID := msg.Chat.ID
 
+	// Пользователя могли убрать из команды, пока он в ней работал
+	b.checkSpace(chatID, userID)
+
+	// Пересланное сообщение — предлагаем сделать из него задачу
+	if isForwarded(msg) {
+		b.offerForwardedTask(msg)
+		return
+	}
+
+	// Фото или документ — вложение к задаче (или новая задача, если есть подпись)
+	if len(msg.Photo) > 0 || msg.Document != nil {
+		b.handleFile(msg)
+		return
+	}
+
 	// Получаем текущее состояние диалога пользователя
 	state := b.getUserState(userID)
 
+	if perm, ok := stepPermissions[state.Step]; ok && !b.allowed(chatID, userID, perm) {
+		b.resetUserState(userID)
+		return
+	}
+
 	// Если пользователь в процессе создания задачи
 	switch state.Step {
 	case StepWaitTitle:
@@ -41,6 +96,62 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 	case StepWaitDesc:
 		b.handleDescriptionInput(chatID, userID, msg.Text)
 		return
+	case StepWaitDeadline:
+		b.handleDeadlineInput(chatID, userID, msg.Text)
+		return
+	case StepWaitPriority:
+		b.sendWithInlineKeyboard(chatID, "🚩 Выбери приоритет кнопкой ниже:", newPriorityKeyboard())
+		return
+	case StepEditTitle, StepEditDesc, StepEditDeadline, StepEditTags, StepEditRecurrence:
+		b.handleEditInput(chatID, userID, state.Step, msg.Text)
+		return
+	case StepAddChecklist:
+		b.handleChecklistInput(chatID, userID, msg.Text)
+		return
+	case StepAddTime:
+		b.handleTimeInput(chatID, userID, msg.Text)
+		return
+	case StepWaitSearch:
+		b.handleSearch(chatID, userID, msg.Text)
+		return
+	case StepEditWorkflow:
+		b.handleWorkflowInput(chatID, userID, msg.Text)
+		return
+	case StepWaitProject:
+		b.handleProjectInput(chatID, userID, msg.Text)
+		return
+	case StepWaitWorkspace:
+		b.handleWorkspaceInput(chatID, memberOf(msg.From), msg.Text)
+		return
+	}
+
+	// Ответ текстом на сообщение с задачей — комментарий к ней.
+	// Шаг диалога важнее: пока бот ждёт ввода, ответ на любое его
+	// сообщение — это ввод, иначе начатый диалог бы потерялся.
+	// Команды и ответы без текста (стикер, голосовое) — не комментарии
+	if msg.Text != "" && !msg.IsCommand() {
+		if space, taskID, ok := b.replyTask(msg); ok {
+			b.handleCommentReply(chatID, userID, space, taskID, msg.Text)
+			return
+		}
+	}
+
+	// Команда или кнопка меню — пользователь ушёл от открытой задачи,
+	// следующие файлы к ней уже не относятся (см. handleFile)
+	b.forgetViewing(userID)
+
+	// Команда с аргументом: "/find лабораторная"
+	if msg.IsCommand() && msg.Command() == "find" {
+		b.handleFind(chatID, userID, msg.CommandArguments())
+		return
+	}
+
+	// Ссылка-приглашение в команду: "/start join_<код>"
+	if msg.IsCommand() && msg.Command() == "start" {
+		if code, ok := ParseJoinPayload(msg.CommandArguments()); ok {
+			b.handleJoin(chatID, memberOf(msg.From), code)
+			return
+		}
 	}
 
 	// Обработка команд и кнопок главного меню
@@ -48,11 +159,29 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 	case "/start":
 		b.handleStart(chatID)
 
+	case "/trash":
+		b.showTrash(chatID, userID)
+
+	case "/statuses":
+		b.showWorkflow(chatID, userID)
+
+	case "/team":
+		b.showWorkspaces(chatID, userID)
+
+	case "/mine":
+		b.showAssigned(chatID, userID, false)
+
+	case "/assigned":
+		b.showAssigned(chatID, userID, true)
+
+	case "/stats":
+		b.showStats(chatID, userID)
+
 	case "📋 Мои задачи":
-		b.handleTaskList(chatID, userID)
+		b.showProjects(chatID, userID)
 
 	case "➕ Новая задача":
-		b.handleNewTask(chatID, userID)
+		b.handleNewTask(chatID, userID, InboxProjectID)
 
 	case "ℹ️ О боте":
 		b.handleAbout(chatID)
@@ -83,11 +212,35 @@ func (b *Bot) handleStart(chatID int64) {
 	b.send(msg)
 }
 
+// allProjects — «проект» для sendTaskList: задачи из всех проектов
+const allProjects = -1
+
 // ============================================================
 // handleTaskList — показывает список задач пользователя
+// (все задачи сразу; первый экран «📋 Мои задачи» — проекты, см. showProjects)
 // ============================================================
 func (b *Bot) handleTaskList(chatID, userID int64) {
-	tasks := b.storage.GetTasks(userID)
+	b.sendTaskList(chatID, userID, allProjects, "")
+}
+
+// handleTagFilter — показывает только задачи с тегом
+func (b *Bot) handleTagFilter(chatID, userID int64, tag string) {
+	b.sendTaskList(chatID, userID, allProjects, NormalizeTag(tag))
+}
+
+// sendTaskList — отправляет список задач
+// projectID — проект или allProjects, tag — фильтр по тегу или ""
+func (b *Bot) sendTaskList(chatID, userID int64, projectID int, tag string) {
+	tasks, err := b.storage.GetTasks(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	if projectID != allProjects {
+		b.sendProjectTasks(chatID, userID, projectID, tasks)
+		return
+	}
 
 	// Если задач нет — показываем подсказку
 	if len(tasks) == 0 {
@@ -95,42 +248,339 @@ func (b *Bot) handleTaskList(chatID, userID int64) {
 		return
 	}
 
+	// Кнопка выбора тега нужна, только если теги вообще есть
+	hasTags := false
+	for _, task := range tasks {
+		if len(task.Tags) > 0 {
+			hasTags = true
+			break
+		}
+	}
+
+	// Блокировки считаем по всем задачам: блокер может быть без нужного тега
+	blocked := blockedSet(tasks)
+
 	// Формируем сообщение со списком
 	text := fmt.Sprintf(
 		"📋 *Твои задачи* \\(%d\\):\n\nНажми на задачу для подробностей 👇",
 		len(tasks),
 	)
+	if tag != "" {
+		tasks = FilterByTag(tasks, tag)
+		text = fmt.Sprintf(
+			"🏷 *Задачи с тегом %s* \\(%d\\):\n\nНажми на задачу для подробностей 👇",
+			escapeMarkdown(formatTags([]string{tag})), len(tasks),
+		)
+	}
 
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "MarkdownV2"
-	keyboard := taskListKeyboard(tasks)
+	keyboard := taskListKeyboard(tasks, blocked, b.workflow(b.space(userID)), b.now(), b.loc, tag, hasTags, nil)
 	msg.ReplyMarkup = keyboard
 
 	b.send(msg)
 }
 
+// sendProjectTasks — список задач одного проекта
+// tasks — все задачи пользователя (нужны, чтобы посчитать блокировки)
+func (b *Bot) sendProjectTasks(chatID, userID int64, projectID int, tasks []Task) {
+	projects, err := b.storage.GetProjects(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	project, ok := findProject(projects, projectID)
+	if !ok {
+		b.sendStorageError(chatID, ErrProjectNotFound)
+		return
+	}
+
+	blocked := blockedSet(tasks)
+	tasks = FilterByProject(tasks, projectID)
+
+	text := fmt.Sprintf(
+		"%s \\(%d\\):\n\nНажми на задачу для подробностей 👇",
+		escapeMarkdown(project.Label()), len(tasks),
+	)
+	switch {
+	case project.Archived:
+		text = fmt.Sprintf("%s \\(в архиве\\):\n\nЗадачи проекта видны в «Все задачи»\\. "+
+			"Чтобы добавлять новые, верни проект из архива 👇", escapeMarkdown(project.Label()))
+	case len(tasks) == 0:
+		text = fmt.Sprintf("%s:\n\n📭 Здесь пока нет задач\\.", escapeMarkdown(project.Label()))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "MarkdownV2"
+	msg.ReplyMarkup = taskListKeyboard(tasks, blocked, b.workflow(b.space(userID)), b.now(), b.loc, "", false, &project)
+	b.send(msg)
+}
+
+// ============================================================
+// ПРОЕКТЫ — первый экран «📋 Мои задачи»
+// Задачи сгруппированы по проектам (см. projects.go);
+// «📥 Входящие» есть всегда
+// ============================================================
+
+// showProjects — список проектов со счётчиками задач
+func (b *Bot) showProjects(chatID, userID int64) {
+	projects, tasks, ok := b.loadProjects(chatID, userID)
+	if !ok {
+		return
+	}
+
+	text := "📋 Твои проекты:\n\nВыбери проект или открой все задачи сразу 👇"
+	if len(tasks) == 0 {
+		text = "📭 У тебя пока нет задач.\nНажми «➕ Новая задача» или выбери проект, чтобы создать первую!"
+	}
+	if space := b.space(userID); IsShared(space) {
+		if w, err := b.storage.GetWorkspace(space); err == nil {
+			text = fmt.Sprintf("👥 Команда «%s» (сменить: /team)\n\n", w.Name) + text
+		}
+	}
+	b.sendWithInlineKeyboard(chatID, text, projectListKeyboard(projects, CountByProject(tasks), len(tasks)))
+}
+
+// showArchivedProjects — проекты в архиве
+func (b *Bot) showArchivedProjects(chatID, userID int64) {
+	projects, tasks, ok := b.loadProjects(chatID, userID)
+	if !ok {
+		return
+	}
+	b.sendWithInlineKeyboard(chatID, "🗄 Проекты в архиве:\n\nОткрой проект, чтобы вернуть его 👇",
+		archivedProjectsKeyboard(projects, CountByProject(tasks)))
+}
+
+// loadProjects — проекты и задачи пользователя (ошибку сразу показывает)
+func (b *Bot) loadProjects(chatID, userID int64) ([]Project, []Task, bool) {
+	projects, err := b.storage.GetProjects(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return nil, nil, false
+	}
+	tasks, err := b.storage.GetTasks(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return nil, nil, false
+	}
+	return projects, tasks, true
+}
+
+// startProjectCreation — спрашивает название нового проекта
+func (b *Bot) startProjectCreation(chatID, userID int64) {
+	state := b.getUserState(userID)
+	b.mu.Lock()
+	state.Step = StepWaitProject
+	b.mu.Unlock()
+
+	b.sendText(chatID, "📁 Как назовём проект? Можно начать со значка, например: «📚 Учёба»")
+}
+
+// handleProjectInput — создаёт проект из введённого названия
+func (b *Bot) handleProjectInput(chatID, userID int64, text string) {
+	draft := ParseProjectName(text)
+	if err := draft.Validate(); err != nil {
+		// Остаёмся на том же шаге — пользователь попробует ещё раз
+		b.sendText(chatID, "⚠️ "+err.Error()+"\n\n📁 Введи название ещё раз:")
+		return
+	}
+
+	b.resetUserState(userID)
+
+	project, err := b.storage.AddProject(b.space(userID), draft)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.sendText(chatID, fmt.Sprintf("✅ Проект «%s» создан.", project.Label()))
+	b.sendTaskList(chatID, userID, project.ID, "")
+}
+
+// handleArchiveProject — убирает проект в архив или возвращает из него
+func (b *Bot) handleArchiveProject(chatID, userID int64, projectID int, archived bool) {
+	project, err := b.storage.UpdateProject(b.space(userID), projectID, ProjectPatch{Archived: &archived})
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	if archived {
+		b.sendText(chatID, fmt.Sprintf("🗄 Проект «%s» в архиве. Его задачи остались в «Все задачи».", project.Label()))
+		b.showProjects(chatID, userID)
+		return
+	}
+	b.sendText(chatID, fmt.Sprintf("📤 Проект «%s» вернулся из архива.", project.Label()))
+	b.sendTaskList(chatID, userID, project.ID, "")
+}
+
+// showMoveSelection — предлагает проекты, куда перенести задачу
+func (b *Bot) showMoveSelection(chatID, userID int64, taskID int) {
+	task, err := b.storage.GetTask(b.space(userID), taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	projects, err := b.storage.GetProjects(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.sendWithInlineKeyboard(chatID, "📁 В какой проект перенести задачу?", moveTaskKeyboard(task, projects))
+}
+
+// handleMoveTask — переносит задачу в другой проект
+func (b *Bot) handleMoveTask(chatID, userID int64, data string) {
+	// Callback data имеет формат: "moveto_<taskID>_<projectID>"
+	taskID, projectID, ok := parseTwoIDs(data, "moveto_")
+	if !ok {
+		return
+	}
+
+	if _, err := b.storage.UpdateTask(b.space(userID), taskID, userID, TaskPatch{ProjectID: &projectID}); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	b.sendText(chatID, fmt.Sprintf("✅ Задача перенесена в «%s».", b.project(b.space(userID), projectID).Label()))
+	b.showTaskDetail(chatID, userID, taskID)
+}
+
+// showTagPicker — показывает теги пользователя для фильтрации списка
+func (b *Bot) showTagPicker(chatID, userID int64) {
+	tags, err := b.storage.GetTags(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	if len(tags) == 0 {
+		b.sendText(chatID, "🏷 Тегов пока нет. Добавь #тег в название задачи, например: «Решить ДЗ #матан»")
+		return
+	}
+	b.sendWithInlineKeyboard(chatID, "🏷 Выбери тег:", tagPickerKeyboard(tags))
+}
+
+// ============================================================
+// ПОИСК ЗАДАЧ — /find <текст>
+// Ищет по словам в названии и описании (см. search.go),
+// результаты показываются страницами по searchPageSize
+// ============================================================
+
+// handleFind — команда /find; без текста спрашивает, что искать
+func (b *Bot) handleFind(chatID, userID int64, query string) {
+	if strings.TrimSpace(query) == "" {
+		state := b.getUserState(userID)
+		b.mu.Lock()
+		state.Step = StepWaitSearch
+		b.mu.Unlock()
+		b.sendText(chatID, "🔎 Что ищем? Напиши слова из названия или описания задачи:")
+		return
+	}
+	b.handleSearch(chatID, userID, query)
+}
+
+// handleSearch — запоминает запрос и показывает первую страницу результатов
+func (b *Bot) handleSearch(chatID, userID int64, query string) {
+	b.resetUserState(userID)
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		b.sendText(chatID, "⚠️ Пустой запрос. Попробуй ещё раз: /find <текст>")
+		return
+	}
+
+	b.mu.Lock()
+	b.searches[userID] = query
+	b.mu.Unlock()
+
+	b.showSearchPage(chatID, 0, userID, 0)
+}
+
+// showSearchPage — страница результатов последнего поиска
+// messageID == 0 — отправить новое сообщение, иначе изменить это (листание)
+func (b *Bot) showSearchPage(chatID int64, messageID int, userID int64, page int) {
+	b.mu.Lock()
+	query := b.searches[userID]
+	b.mu.Unlock()
+	if query == "" {
+		// Например, бот перезапускался — запросы хранятся только в памяти
+		b.sendText(chatID, "🔎 Поиск устарел. Повтори его: /find <текст>")
+		return
+	}
+
+	found, err := SearchTasks(b.storage, b.space(userID), query)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	if len(found) == 0 {
+		b.sendText(chatID, fmt.Sprintf("🔎 По запросу «%s» ничего не нашлось.", query))
+		return
+	}
+	// Блокировки считаем по всем задачам, а не только по найденным
+	tasks, err := b.storage.GetTasks(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	// Задачи могли удалить, пока пользователь листал — не выходим за конец
+	pages := (len(found) + searchPageSize - 1) / searchPageSize
+	page = max(0, min(page, pages-1))
+
+	text := fmt.Sprintf("🔎 Найдено по запросу «%s»: %d", query, len(found))
+	if pages > 1 {
+		text += fmt.Sprintf("\nСтраница %d из %d", page+1, pages)
+	}
+	keyboard := searchResultsKeyboard(found, blockedSet(tasks), b.workflow(b.space(userID)), page, b.now(), b.loc)
+
+	if messageID == 0 {
+		b.sendWithInlineKeyboard(chatID, text, keyboard)
+		return
+	}
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, keyboard)
+	if _, err := b.api.Send(edit); err != nil {
+		log.Printf("❌ Ошибка обновления сообщения: %v", err)
+	}
+}
+
 // ============================================================
 // СОЗДАНИЕ ЗАДАЧИ — пошаговый диалог
 // ============================================================
 
 // handleNewTask — начинает процесс создания новой задачи
-func (b *Bot) handleNewTask(chatID, userID int64) {
+// projectID — проект, куда попадёт задача («Входящие» из главного меню)
+func (b *Bot) handleNewTask(chatID, userID int64, projectID int) {
+	// Наблюдатель задачи команды только смотрит
+	if !b.allowed(chatID, userID, PermEdit) {
+		return
+	}
+
 	// Устанавливаем шаг "ждём название"
 	state := b.getUserState(userID)
 	b.mu.Lock()
 	state.Step = StepWaitTitle
 	state.TempTitle = ""
+	state.TempDesc = ""
+	state.TempProject = projectID
 	b.mu.Unlock()
 
 	b.sendText(chatID, "✏️ Введи название задачи:")
 }
 
 // handleTitleInput — пользователь ввёл название задачи
+// #хештеги из названия становятся тегами задачи
 func (b *Bot) handleTitleInput(chatID, userID int64, title string) {
+	title, tags := ExtractTags(title)
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		b.sendText(chatID, "⚠️ "+err.Error()+"\n\n✏️ Введи название ещё раз:")
+		return
+	}
+
 	// Сохраняем название и переходим к следующему шагу
 	state := b.getUserState(userID)
 	b.mu.Lock()
 	state.TempTitle = title
+	state.TempTags = tags
 	state.Step = StepWaitDesc
 	b.mu.Unlock()
 
@@ -140,18 +590,74 @@ func (b *Bot) handleTitleInput(chatID, userID int64, title string) {
 }
 
 // handleDescriptionInput — пользователь ввёл описание задачи
+// (или нажал «Пропустить» — тогда description пустое)
 func (b *Bot) handleDescriptionInput(chatID, userID int64, description string) {
-	b.finishTaskCreation(chatID, userID, description)
+	state := b.getUserState(userID)
+	b.mu.Lock()
+	state.TempDesc = description
+	state.Step = StepWaitDeadline
+	b.mu.Unlock()
+
+	b.sendWithInlineKeyboard(chatID,
+		"⏰ Когда срок? Например: «завтра в 18:00», «в пятницу», «через 3 дня», «25.12»",
+		noDeadlineKeyboard())
+}
+
+// handleDeadlineInput — пользователь ввёл срок задачи
+func (b *Bot) handleDeadlineInput(chatID, userID int64, text string) {
+	deadline, ok := b.parseDeadlineInput(chatID, text)
+	if !ok {
+		return // Остаёмся на том же шаге — пользователь попробует ещё раз
+	}
+	b.askPriority(chatID, userID, &deadline)
+}
+
+// parseDeadlineInput — разбирает срок и объясняет пользователю ошибку
+func (b *Bot) parseDeadlineInput(chatID int64, text string) (time.Time, bool) {
+	now := b.now()
+	deadline, err := ParseDeadline(text, now)
+	var past *PastDateError
+	if errors.As(err, &past) {
+		b.sendText(chatID, fmt.Sprintf("⚠️ %s уже прошло. Введи срок в будущем:", formatDeadline(past.At, b.loc)))
+		return time.Time{}, false
+	}
+	if err != nil {
+		b.sendText(chatID, "🤔 Не понял дату. Попробуй так: «завтра в 18:00», «в пятницу», «через 3 дня» или «25.12»")
+		return time.Time{}, false
+	}
+	return deadline, true
+}
+
+// askPriority — запоминает срок и предлагает выбрать приоритет
+// (deadline == nil — задача без срока)
+func (b *Bot) askPriority(chatID, userID int64, deadline *time.Time) {
+	state := b.getUserState(userID)
+	b.mu.Lock()
+	state.TempDeadline = deadline
+	state.Step = StepWaitPriority
+	b.mu.Unlock()
+
+	b.sendWithInlineKeyboard(chatID, "🚩 Насколько это важно?", newPriorityKeyboard())
 }
 
 // finishTaskCreation — завершает создание задачи и сохраняет её
-func (b *Bot) finishTaskCreation(chatID, userID int64, description string) {
+func (b *Bot) finishTaskCreation(chatID, userID int64, priority string) {
 	state := b.getUserState(userID)
 
 	b.mu.Lock()
+	step := state.Step
 	title := state.TempTitle
+	description := state.TempDesc
+	deadline := state.TempDeadline
+	tags := state.TempTags
+	projectID := state.TempProject
 	b.mu.Unlock()
 
+	// Кнопка от старого сообщения, когда задача уже создана
+	if step != StepWaitPriority {
+		return
+	}
+
 	// Проверяем, что название есть (на случай ошибки)
 	if title == "" {
 		b.sendText(chatID, "⚠️ Что-то пошло не так. Попробуй создать задачу заново.")
@@ -160,17 +666,39 @@ func (b *Bot) finishTaskCreation(chatID, userID int64, description string) {
 	}
 
 	// Сохраняем задачу в хранилище
-	task := b.storage.AddTask(userID, title, description)
+	task, err := b.storage.AddTask(b.space(userID), userID, TaskDraft{
+		Title:       title,
+		Description: description,
+		Deadline:    deadline,
+		Priority:    priority,
+		Tags:        tags,
+		ProjectID:   projectID,
+	})
 
 	// Сбрасываем состояние диалога
 	b.resetUserState(userID)
 
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
 	// Формируем сообщение-подтверждение
-	text := fmt.Sprintf("✅ Задача создана!\n\n📌 %s\n📊 %s", task.Title, task.Status)
+	text := fmt.Sprintf("✅ Задача создана!\n\n📌 %s\n", task.Title)
 	if task.Description != "" {
-		text = fmt.Sprintf("✅ Задача создана!\n\n📌 %s\n📝 %s\n📊 %s",
-			task.Title, task.Description, task.Status)
+		text += fmt.Sprintf("📝 %s\n", task.Description)
+	}
+	if task.Deadline != nil {
+		text += fmt.Sprintf("⏰ %s\n", formatDeadline(*task.Deadline, b.loc))
 	}
+	text += fmt.Sprintf("🚩 %s\n", PriorityLabel(task.Priority))
+	if len(task.Tags) > 0 {
+		text += fmt.Sprintf("🏷 %s\n", formatTags(task.Tags))
+	}
+	if task.ProjectID != InboxProjectID {
+		text += fmt.Sprintf("📁 %s\n", b.project(b.space(userID), task.ProjectID).Label())
+	}
+	text += fmt.Sprintf("📊 %s", b.workflow(b.space(userID)).Label(task.Status))
 
 	b.sendText(chatID, text)
 }
@@ -186,18 +714,93 @@ func (b *Bot) handleAbout(chatID int64) {
 		"📌 Возможности:\n" +
 		"• Создание задач\n" +
 		"• Просмотр списка задач\n" +
-		"• Смена статуса\n" +
-		"• Удаление задач\n\n" +
-		"🚧 В разработке:\n" +
-		"• Сохранение в PostgreSQL\n" +
-		"• Дедлайны и напоминания\n" +
-		"• Приоритеты задач"
+		"• Проекты и «Входящие» для задач без проекта\n" +
+		"• Общие задачи команды: /team, роли участников — там же\n" +
+		"• Исполнители: /mine — назначено мне, /assigned — назначено мной\n" +
+		"• Смена статуса и свои статусы: /statuses\n" +
+		"• Редактирование задач\n" +
+		"• Дедлайны \\(«завтра в 18:00», «в пятницу»\\)\n" +
+		"• Удаление задач в корзину с отменой: /trash\n" +
+		"• Напоминания о дедлайнах\n" +
+		"• Приоритеты задач\n" +
+		"• Теги \\(\\#матан\\) и фильтр по тегам\n" +
+		"• Чек\\-листы внутри задачи\n" +
+		"• Зависимости между задачами\n" +
+		"• Повторяющиеся задачи\n" +
+		"• История изменений задачи\n" +
+		"• Комментарии: ответь на сообщение с задачей\n" +
+		"• Вложения: открой задачу и пришли фото или документ, фото с подписью — новая задача\n" +
+		"• Перешли боту сообщение из любого чата — он предложит сделать из него задачу\n" +
+		"• Учёт времени: ⏱ таймер в карточке задачи и время, добавленное вручную\n" +
+		"• Статистика за последние недели: /stats\n" +
+		"• Поиск по задачам: /find \\<текст\\>\n" +
+		"• Сохранение в PostgreSQL / SQLite"
 
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "MarkdownV2"
 	b.send(msg)
 }
 
+// callbackPermissions — какое право нужно кнопке (по началу callback data)
+// Кнопкам, которых здесь нет, хватает права смотреть задачи: они только
+// показывают данные или работают с самим пользователем (выход из команды)
+// Новая задача ("newtask_") проверяется в handleNewTask — как и кнопка меню
+var callbackPermissions = []struct {
+	prefix string
+	perm   Permission
+}{
+	{"skip", PermEdit},
+	{"nodeadline", PermEdit},
+	{"newprio_", PermEdit},
+	{"setstatus_", PermEdit},
+	{"edit", PermEdit}, // edit_, edittitle_, editdesc_, editdeadline_, editprio_, edittags_
+	{"setprio_", PermEdit},
+	{"snooze_", PermEdit},
+	{"chk", PermEdit}, // chk_, chkadd_, chkremove_, chkdel_
+	{"recur_", PermEdit},
+	{"setrecur_", PermEdit},
+	{"dep_", PermEdit},
+	{"move_", PermEdit},
+	{"moveto_", PermEdit},
+	{"assign_", PermEdit},
+	{"assignto_", PermEdit},
+	{"fwdtask", PermEdit},
+	{"timer_", PermEdit},
+	{"timeadd_", PermEdit},
+	{"delete_", PermDelete},
+	{"confirm_delete_", PermDelete},
+	{"restore_", PermDelete},
+	{"wfedit", PermManage},
+	{"wfreset", PermManage},
+	{"newproj", PermManage},
+	{"archproj_", PermManage},
+	{"unarchproj_", PermManage},
+	{"wsinvite", PermManage},
+	{"wsrole_", PermManage},
+	{"setrole_", PermManage},
+}
+
+// callbackPermission — право, которое нужно кнопке с данными data
+func callbackPermission(data string) Permission {
+	for _, c := range callbackPermissions {
+		if strings.HasPrefix(data, c.prefix) {
+			return c.perm
+		}
+	}
+	return PermView
+}
+
+// allowed проверяет право пользователя в текущем пространстве
+// Если права нет — объясняет это пользователю и возвращает false
+func (b *Bot) allowed(chatID, userID int64, perm Permission) bool {
+	err := Authorize(b.storage, userID, b.space(userID), perm)
+	if err == nil {
+		return true
+	}
+	b.sendStorageError(chatID, err)
+	return false
+}
+
 // ============================================================
 // handleCallback — обрабатывает нажатия inline-кнопок
 //
@@ -213,12 +816,39 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 	answer := tgbotapi.NewCallback(cb.ID, "")
 	b.api.Request(answer)
 
+	b.checkSpace(chatID, userID)
+
+	// "in_<пространство>_<callback>" — кнопка из уведомления или общего
+	// списка: переходим в пространство задачи и выполняем действие как обычно
+	if rest, ok := strings.CutPrefix(data, "in_"); ok {
+		inner, ok := b.enterSpace(chatID, userID, rest)
+		if !ok {
+			return
+		}
+		data = inner
+	}
+
+	// Роль в команде может не позволять действие (см. permissions.go)
+	if perm := callbackPermission(data); perm != PermView && !b.allowed(chatID, userID, perm) {
+		return
+	}
+
 	// Определяем действие по callback data
 	switch {
 
 	// "Пропустить" — при создании задачи пропускаем описание
 	case data == "skip":
-		b.finishTaskCreation(chatID, userID, "")
+		b.handleDescriptionInput(chatID, userID, "")
+
+	// "Без срока" — задача без дедлайна, переходим к приоритету
+	case data == "nodeadline":
+		b.askPriority(chatID, userID, nil)
+
+	// "newprio_<код>" — приоритет новой задачи, создаём её
+	case strings.HasPrefix(data, "newprio_"):
+		if priority := strings.TrimPrefix(data, "newprio_"); IsValidPriority(priority) {
+			b.finishTaskCreation(chatID, userID, priority)
+		}
 
 	// "task_<ID>" — показать подробности задачи
 	case strings.HasPrefix(data, "task_"):
@@ -228,12 +858,39 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 	// "status_<ID>" — показать меню выбора статуса
 	case strings.HasPrefix(data, "status_"):
 		taskID := b.parseID(data, "status_")
-		b.showStatusSelection(chatID, taskID)
+		b.showStatusSelection(chatID, userID, taskID)
 
-	// "setstatus_<ID>_<status>" — установить новый статус
+	// "setstatus_<ID>_<код статуса>" — установить новый статус
 	case strings.HasPrefix(data, "setstatus_"):
 		b.handleSetStatus(chatID, userID, data)
 
+	// "edit_<ID>" — выбрать, что редактировать
+	case strings.HasPrefix(data, "edit_"):
+		taskID := b.parseID(data, "edit_")
+		b.showEditSelection(chatID, taskID)
+
+	// "edittitle_<ID>" / "editdesc_<ID>" — начать редактирование поля
+	case strings.HasPrefix(data, "edittitle_"):
+		taskID := b.parseID(data, "edittitle_")
+		b.startEdit(chatID, userID, taskID, StepEditTitle)
+
+	case strings.HasPrefix(data, "editdesc_"):
+		taskID := b.parseID(data, "editdesc_")
+		b.startEdit(chatID, userID, taskID, StepEditDesc)
+
+	case strings.HasPrefix(data, "editdeadline_"):
+		taskID := b.parseID(data, "editdeadline_")
+		b.startEdit(chatID, userID, taskID, StepEditDeadline)
+
+	// "editprio_<ID>" — показать меню выбора приоритета
+	case strings.HasPrefix(data, "editprio_"):
+		taskID := b.parseID(data, "editprio_")
+		b.showPrioritySelection(chatID, taskID)
+
+	// "setprio_<ID>_<код приоритета>" — установить новый приоритет
+	case strings.HasPrefix(data, "setprio_"):
+		b.handleSetPriority(chatID, userID, data)
+
 	// "delete_<ID>" — запросить подтверждение удаления
 	case strings.HasPrefix(data, "delete_"):
 		taskID := b.parseID(data, "delete_")
@@ -244,6 +901,203 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 		taskID := b.parseID(data, "confirm_delete_")
 		b.handleDelete(chatID, userID, taskID)
 
+	// "restore_<ID>" — вернуть задачу из корзины
+	case strings.HasPrefix(data, "restore_"):
+		taskID := b.parseID(data, "restore_")
+		b.handleRestore(chatID, userID, taskID)
+
+	// "trash" — показать корзину
+	case data == "trash":
+		b.showTrash(chatID, userID)
+
+	// "snooze_<ID>" — отложить напоминание о сроке на час
+	case strings.HasPrefix(data, "snooze_"):
+		taskID := b.parseID(data, "snooze_")
+		b.handleSnooze(chatID, userID, taskID)
+
+	// "tags" — выбрать тег для фильтра списка
+	case data == "tags":
+		b.showTagPicker(chatID, userID)
+
+	// "tag_<тег>" — список задач с тегом
+	case strings.HasPrefix(data, "tag_"):
+		b.handleTagFilter(chatID, userID, strings.TrimPrefix(data, "tag_"))
+
+	// "edittags_<ID>" — начать редактирование тегов
+	case strings.HasPrefix(data, "edittags_"):
+		taskID := b.parseID(data, "edittags_")
+		b.startEdit(chatID, userID, taskID, StepEditTags)
+
+	// "chk_<ID>_<пункт>" — отметить пункт чек-листа (сообщение меняется на месте)
+	case strings.HasPrefix(data, "chk_"):
+		b.handleToggleChecklistItem(chatID, cb.Message.MessageID, userID, data)
+
+	// "chkadd_<ID>" — добавить пункты в чек-лист
+	case strings.HasPrefix(data, "chkadd_"):
+		taskID := b.parseID(data, "chkadd_")
+		b.startChecklistInput(chatID, userID, taskID)
+
+	// "chkremove_<ID>" — выбрать пункт для удаления
+	case strings.HasPrefix(data, "chkremove_"):
+		taskID := b.parseID(data, "chkremove_")
+		b.showChecklistRemoval(chatID, userID, taskID)
+
+	// "chkdel_<ID>_<пункт>" — удалить пункт чек-листа
+	case strings.HasPrefix(data, "chkdel_"):
+		b.handleRemoveChecklistItem(chatID, cb.Message.MessageID, userID, data)
+
+	// "recur_<ID>" — выбрать правило повторения
+	case strings.HasPrefix(data, "recur_"):
+		taskID := b.parseID(data, "recur_")
+		b.showRecurrenceSelection(chatID, userID, taskID)
+
+	// "setrecur_<ID>_<вариант>" — установить повторение из готовых вариантов
+	case strings.HasPrefix(data, "setrecur_"):
+		b.handleSetRecurrence(chatID, userID, data)
+
+	// "deps_<ID>" — выбрать задачи, которые блокируют эту
+	case strings.HasPrefix(data, "deps_"):
+		taskID := b.parseID(data, "deps_")
+		b.showDependencies(chatID, userID, taskID)
+
+	// "dep_<ID>_<блокер>" — добавить или убрать блокер (сообщение меняется на месте)
+	case strings.HasPrefix(data, "dep_"):
+		b.handleToggleDependency(chatID, cb.Message.MessageID, userID, data)
+
+	// "find_<страница>" — листание результатов поиска (сообщение меняется на месте)
+	case strings.HasPrefix(data, "find_"):
+		if page, err := strconv.Atoi(strings.TrimPrefix(data, "find_")); err == nil {
+			b.showSearchPage(chatID, cb.Message.MessageID, userID, page)
+		}
+
+	// "history_<ID>" — история изменений задачи
+	case strings.HasPrefix(data, "history_"):
+		taskID := b.parseID(data, "history_")
+		b.showHistory(chatID, userID, taskID)
+
+	// "comments_<ID>" — обсуждение задачи (приходит через "in_")
+	case strings.HasPrefix(data, "comments_"):
+		taskID := b.parseID(data, "comments_")
+		b.showComments(chatID, userID, taskID)
+
+	// "files_<ID>" — прислать вложения задачи
+	case strings.HasPrefix(data, "files_"):
+		taskID := b.parseID(data, "files_")
+		b.showAttachments(chatID, userID, taskID)
+
+	// "timer_<ID>" — запустить или остановить таймер, "time_<ID>" — итоги
+	// учёта времени, "timeadd_<ID>" — добавить время вручную
+	case strings.HasPrefix(data, "timer_"):
+		taskID := b.parseID(data, "timer_")
+		b.toggleTimer(chatID, cb.Message.MessageID, userID, taskID)
+
+	case strings.HasPrefix(data, "time_"):
+		taskID := b.parseID(data, "time_")
+		b.showTimeReport(chatID, userID, taskID)
+
+	case strings.HasPrefix(data, "timeadd_"):
+		taskID := b.parseID(data, "timeadd_")
+		b.startTimeInput(chatID, userID, taskID)
+
+	// "fwdtask" — задача из пересланного сообщения (приходит через "in_")
+	case data == "fwdtask":
+		b.createForwardedTask(chatID, userID, cb.Message)
+
+	// "wfedit" — ввести новый набор статусов, "wfreset" — вернуть статусы по умолчанию
+	case data == "wfedit":
+		b.startWorkflowEdit(chatID, userID)
+
+	case data == "wfreset":
+		b.handleWorkflowReset(chatID, userID)
+
+	// "projects" — список проектов, "projarchive" — проекты в архиве
+	case data == "projects":
+		b.showProjects(chatID, userID)
+
+	case data == "projarchive":
+		b.showArchivedProjects(chatID, userID)
+
+	// "proj_<ID>" — задачи проекта
+	case strings.HasPrefix(data, "proj_"):
+		projectID := b.parseID(data, "proj_")
+		b.sendTaskList(chatID, userID, projectID, "")
+
+	// "newproj" — создать проект
+	case data == "newproj":
+		b.startProjectCreation(chatID, userID)
+
+	// "newtask_<проект>" — новая задача сразу в проект
+	case strings.HasPrefix(data, "newtask_"):
+		projectID := b.parseID(data, "newtask_")
+		b.handleNewTask(chatID, userID, projectID)
+
+	// "archproj_<ID>" / "unarchproj_<ID>" — убрать проект в архив / вернуть
+	case strings.HasPrefix(data, "archproj_"):
+		projectID := b.parseID(data, "archproj_")
+		b.handleArchiveProject(chatID, userID, projectID, true)
+
+	case strings.HasPrefix(data, "unarchproj_"):
+		projectID := b.parseID(data, "unarchproj_")
+		b.handleArchiveProject(chatID, userID, projectID, false)
+
+	// "move_<ID>" — выбрать проект для задачи
+	case strings.HasPrefix(data, "move_"):
+		taskID := b.parseID(data, "move_")
+		b.showMoveSelection(chatID, userID, taskID)
+
+	// "moveto_<ID>_<проект>" — перенести задачу в проект
+	case strings.HasPrefix(data, "moveto_"):
+		b.handleMoveTask(chatID, userID, data)
+
+	// "ws_<ID>" — перейти в пространство (личное или команду)
+	case strings.HasPrefix(data, "ws_"):
+		if space, err := strconv.ParseInt(strings.TrimPrefix(data, "ws_"), 10, 64); err == nil {
+			b.handleSwitchSpace(chatID, userID, space)
+		}
+
+	// "newws" — создать команду, "wsinvite" — ссылка-приглашение
+	case data == "newws":
+		b.startWorkspaceCreation(chatID, userID)
+
+	case data == "wsinvite":
+		b.showInvite(chatID, userID)
+
+	// "wsmembers" — участники команды и их роли
+	case data == "wsmembers":
+		b.showMembers(chatID, userID)
+
+	// "wsrole_<пользователь>" — выбрать роль участника
+	case strings.HasPrefix(data, "wsrole_"):
+		if member, err := strconv.ParseInt(strings.TrimPrefix(data, "wsrole_"), 10, 64); err == nil {
+			b.showRoleSelection(chatID, userID, member)
+		}
+
+	// "setrole_<пользователь>_<роль>" — назначить роль
+	case strings.HasPrefix(data, "setrole_"):
+		b.handleSetRole(chatID, userID, data)
+
+	// "wsleave_<ID>" — выйти из команды
+	case strings.HasPrefix(data, "wsleave_"):
+		if space, err := strconv.ParseInt(strings.TrimPrefix(data, "wsleave_"), 10, 64); err == nil {
+			b.handleLeaveWorkspace(chatID, userID, space)
+		}
+
+	// "assign_<ID>" — выбрать исполнителя задачи команды
+	case strings.HasPrefix(data, "assign_"):
+		taskID := b.parseID(data, "assign_")
+		b.showAssigneeSelection(chatID, userID, taskID)
+
+	// "assignto_<ID задачи>_<ID пользователя>" — назначить (0 — снять)
+	case strings.HasPrefix(data, "assignto_"):
+		b.handleAssign(chatID, userID, data)
+
+	// "assigned_me" / "assigned_byme" — задачи, назначенные мне и мной
+	case data == "assigned_me":
+		b.showAssigned(chatID, userID, false)
+
+	case data == "assigned_byme":
+		b.showAssigned(chatID, userID, true)
+
 	// "back_to_list" — вернуться к списку задач
 	case data == "back_to_list":
 		b.handleTaskList(chatID, userID)
@@ -256,76 +1110,1668 @@ func (b *Bot) handleCallback(cb *tgbotapi.CallbackQuery) {
 
 // showTaskDetail — показывает подробную информацию о задаче
 func (b *Bot) showTaskDetail(chatID, userID int64, taskID int) {
-	task, found := b.storage.GetTask(userID, taskID)
-	if !found {
-		b.sendText(chatID, "⚠️ Задача не найдена.")
+	task, blockers, err := b.loadTaskDetail(userID, taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
 		return
 	}
 
-	// Формируем текст с деталями
-	text := fmt.Sprintf("📌 *%s*\n\n", escapeMarkdown(task.Title))
-
-	if task.Description != "" {
-		text += fmt.Sprintf("📝 %s\n\n", escapeMarkdown(task.Description))
-	}
-
-	text += fmt.Sprintf("📊 Статус: %s\n", escapeMarkdown(task.Status))
-	text += fmt.Sprintf("📅 Создана: %s", escapeMarkdown(task.CreatedAt.Format("02.01.2006 15:04")))
-
-	msg := tgbotapi.NewMessage(chatID, text)
+	space := b.space(userID)
+	msg := tgbotapi.NewMessage(chatID, b.taskDetailText(space, task, blockers))
 	msg.ParseMode = "MarkdownV2"
-	keyboard := taskActionsKeyboard(taskID)
+	keyboard := taskActionsKeyboard(task, space, b.commentCount(space, taskID), b.attachmentCount(space, taskID),
+		b.timerRunning(space, taskID, userID))
 	msg.ReplyMarkup = keyboard
 
+	b.setViewing(userID, space, taskID)
 	b.send(msg)
 }
 
-// ============================================================
-// СМЕНА СТАТУСА
-// ============================================================
+// refreshTaskDetail — обновляет уже отправленное сообщение с задачей
+// (например, после отметки пункта чек-листа), не присылая новое
+func (b *Bot) refreshTaskDetail(chatID int64, messageID int, userID int64, taskID int) {
+	task, blockers, err := b.loadTaskDetail(userID, taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
 
-// showStatusSelection — показывает кнопки выбора нового статуса
-func (b *Bot) showStatusSelection(chatID int64, taskID int) {
-	keyboard := statusKeyboard(taskID)
-	b.sendWithInlineKeyboard(chatID, "Выбери новый статус:", keyboard)
+	space := b.space(userID)
+	text := b.taskDetailText(space, task, blockers)
+	keyboard := taskActionsKeyboard(task, space, b.commentCount(space, taskID), b.attachmentCount(space, taskID),
+		b.timerRunning(space, taskID, userID))
+	b.setViewing(userID, space, taskID)
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, keyboard)
+	edit.ParseMode = "MarkdownV2"
+	if _, err := b.api.Send(edit); err != nil {
+		log.Printf("❌ Ошибка обновления сообщения: %v", err)
+	}
 }
 
-// handleSetStatus — устанавливает выбранный статус
-func (b *Bot) handleSetStatus(chatID, userID int64, data string) {
-	// Callback data имеет формат: "setstatus_<taskID>_<statusKey>"
+// loadTaskDetail — задача и задачи, которые её блокируют
+// (список всех задач читаем, только если у задачи есть блокеры)
+func (b *Bot) loadTaskDetail(userID int64, taskID int) (Task, []Task, error) {
+	task, err := b.storage.GetTask(b.space(userID), taskID)
+	if err != nil || len(task.BlockedBy) == 0 {
+		return task, nil, err
+	}
+	tasks, err := b.storage.GetTasks(b.space(userID))
+	if err != nil {
+		return Task{}, nil, err
+	}
+	var blockers []Task
+	for _, id := range task.BlockedBy {
+		if blocker, ok := findTask(tasks, id); ok {
+			blockers = append(blockers, blocker)
+		}
+	}
+	return task, blockers, nil
+}
+
+// taskDetailText — текст карточки задачи (MarkdownV2)
+// space — пространство задачи: из него берутся статусы, проект и имя исполнителя
+func (b *Bot) taskDetailText(space int64, task Task, blockers []Task) string {
+	wf := b.workflow(space)
+	project := b.project(space, task.ProjectID)
+
+	// Формируем текст с деталями
+	text := fmt.Sprintf("📌 *%s*\n\n", escapeMarkdown(task.Title))
+
+	if task.Description != "" {
+		text += fmt.Sprintf("📝 %s\n\n", escapeMarkdown(task.Description))
+	}
+
+	text += fmt.Sprintf("📊 Статус: %s\n", escapeMarkdown(wf.Label(task.Status)))
+	text += fmt.Sprintf("🚩 Приоритет: %s\n", escapeMarkdown(PriorityLabel(task.Priority)))
+	text += fmt.Sprintf("📁 Проект: %s\n", escapeMarkdown(project.Label()))
+	if task.Assignee != NoAssignee {
+		text += fmt.Sprintf("👤 Исполнитель: %s\n", escapeMarkdown(MemberLabel(b.members(space), task.Assignee)))
+	}
+	if len(task.Tags) > 0 {
+		text += fmt.Sprintf("🏷 Теги: %s\n", escapeMarkdown(formatTags(task.Tags)))
+	}
+	if task.Recurrence != nil {
+		text += fmt.Sprintf("🔁 Повтор: %s\n", escapeMarkdown(task.Recurrence.Describe()))
+	}
+	if task.Deadline != nil {
+		deadline := formatDeadline(*task.Deadline, b.loc)
+		if task.IsOverdue(b.now()) {
+			text += fmt.Sprintf("🔥 Срок: %s \\(просрочено\\)\n", escapeMarkdown(deadline))
+		} else {
+			text += fmt.Sprintf("⏰ Срок: %s\n", escapeMarkdown(deadline))
+		}
+	}
+	if entries := b.timeEntries(space, task.ID); len(entries) > 0 {
+		summary := SummarizeTime(entries, b.now(), b.loc)
+		text += fmt.Sprintf("🕒 Учтено: %s", escapeMarkdown(FormatTracked(summary.Total)))
+		if summary.Running > 0 {
+			text += " \\(идёт таймер\\)"
+		}
+		text += "\n"
+	}
+	if task.Source != nil {
+		text += fmt.Sprintf("📨 Из сообщения: %s\n", escapeMarkdown(task.Source.Label(b.loc)))
+	}
+	text += fmt.Sprintf("📅 Создана: %s", escapeMarkdown(task.CreatedAt.Format("02.01.2006 15:04")))
+
+	// Зависимости: сначала список блокеров, затем — можно ли уже завершать
+	if len(blockers) > 0 {
+		text += "\n\n🔗 Зависит от:"
+		for _, blocker := range blockers {
+			mark := "⏳"
+			if blocker.IsDone() {
+				mark = "✅"
+			}
+			text += fmt.Sprintf("\n%s %s", mark, escapeMarkdown(blocker.Title))
+		}
+		if !task.IsDone() && len(OpenBlockers(blockers, task)) > 0 {
+			text += "\n⛔ Задачу можно будет завершить, когда выполнятся все блокирующие"
+		}
+	}
+
+	// Сами пункты чек-листа — кнопки под сообщением
+	if progress := task.Progress(); progress.Total > 0 {
+		text += fmt.Sprintf("\n\n☑️ Чек\\-лист: %s \\(нажми на пункт, чтобы отметить\\)", escapeMarkdown(progress.String()))
+	}
+	return text
+}
+
+// ============================================================
+// ЧЕК-ЛИСТ
+// ============================================================
+
+// startChecklistInput — ждём от пользователя текст новых пунктов
+func (b *Bot) startChecklistInput(chatID, userID int64, taskID int) {
+	if _, err := b.storage.GetTask(b.space(userID), taskID); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	state := b.getUserState(userID)
+	b.mu.Lock()
+	state.Step = StepAddChecklist
+	state.TempTaskID = taskID
+	b.mu.Unlock()
+
+	b.sendText(chatID, "☑️ Введи пункт чек-листа.\nМожно сразу несколько — каждый с новой строки.")
+}
+
+// handleChecklistInput — добавляет пункты (по одному на строку)
+func (b *Bot) handleChecklistInput(chatID, userID int64, text string) {
+	state := b.getUserState(userID)
+	b.mu.Lock()
+	taskID := state.TempTaskID
+	b.mu.Unlock()
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		b.sendText(chatID, "⚠️ Пункт не может быть пустым. Введи текст пункта:")
+		return
+	}
+
+	b.resetUserState(userID)
+
+	added := 0
+	for _, line := range lines {
+		if _, err := b.storage.AddChecklistItem(b.space(userID), taskID, userID, line); err != nil {
+			b.sendStorageError(chatID, err)
+			break
+		}
+		added++
+	}
+	if added > 0 {
+		b.sendText(chatID, fmt.Sprintf("✅ Добавлено пунктов: %d", added))
+	}
+	b.showTaskDetail(chatID, userID, taskID)
+}
+
+// handleToggleChecklistItem — отмечает пункт и обновляет сообщение на месте
+func (b *Bot) handleToggleChecklistItem(chatID int64, messageID int, userID int64, data string) {
+	// Callback data: "chk_<taskID>_<itemID>"
+	taskID, itemID, ok := parseTwoIDs(data, "chk_")
+	if !ok {
+		return
+	}
+	if _, err := b.storage.ToggleChecklistItem(b.space(userID), taskID, itemID, userID); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.refreshTaskDetail(chatID, messageID, userID, taskID)
+}
+
+// showChecklistRemoval — показывает пункты, которые можно удалить
+func (b *Bot) showChecklistRemoval(chatID, userID int64, taskID int) {
+	task, err := b.storage.GetTask(b.space(userID), taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	if len(task.Checklist) == 0 {
+		b.sendText(chatID, "☑️ В чек-листе пока нет пунктов.")
+		return
+	}
+	b.sendWithInlineKeyboard(chatID, "🗑 Какой пункт удалить?", checklistRemoveKeyboard(task))
+}
+
+// handleRemoveChecklistItem — удаляет пункт; сообщение превращается в карточку задачи
+func (b *Bot) handleRemoveChecklistItem(chatID int64, messageID int, userID int64, data string) {
+	// Callback data: "chkdel_<taskID>_<itemID>"
+	taskID, itemID, ok := parseTwoIDs(data, "chkdel_")
+	if !ok {
+		return
+	}
+	if err := b.storage.RemoveChecklistItem(b.space(userID), taskID, itemID, userID); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.refreshTaskDetail(chatID, messageID, userID, taskID)
+}
+
+// ============================================================
+// СМЕНА СТАТУСА
+// ============================================================
+
+// showStatusSelection — показывает кнопки выбора нового статуса
+// (только те статусы, в которые можно перейти из текущего)
+func (b *Bot) showStatusSelection(chatID, userID int64, taskID int) {
+	task, err := b.storage.GetTask(b.space(userID), taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	wf := b.workflow(b.space(userID))
+	if len(wf.Targets(task.Status)) == 0 {
+		b.sendText(chatID, fmt.Sprintf("📊 Из статуса «%s» перейти некуда. Переходы настраиваются командой /statuses.",
+			wf.Label(task.Status)))
+		return
+	}
+	text := fmt.Sprintf("Сейчас: %s\n\nВыбери новый статус:", wf.Label(task.Status))
+	b.sendWithInlineKeyboard(chatID, text, statusKeyboard(task, wf))
+}
+
+// handleSetStatus — устанавливает выбранный статус
+func (b *Bot) handleSetStatus(chatID, userID int64, data string) {
+	// Callback data имеет формат: "setstatus_<taskID>_<код статуса>"
 	// Разбиваем строку на 3 части по символу "_"
 	parts := strings.SplitN(data, "_", 3)
 	if len(parts) < 3 {
 		return
 	}
-
-	// Парсим ID задачи из строки в число
+
+	// Парсим ID задачи из строки в число
+	taskID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return
+	}
+
+	// Обновляем статус в хранилище: оно же проверит, что такой статус
+	// есть в наборе пользователя и переход в него разрешён (см. status.go)
+	status := parts[2]
+	result, err := b.storage.UpdateStatus(b.space(userID), taskID, userID, status)
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		b.sendText(chatID, "⛔ Задачу пока нельзя завершить — сначала нужно выполнить:\n"+
+			formatTaskTitles(blocked.Blockers))
+		return
+	}
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	b.sendText(chatID, fmt.Sprintf("✅ Статус изменён на: %s", b.workflow(b.space(userID)).Label(status)))
+	if len(result.StoppedTimers) > 0 {
+		b.sendText(chatID, "⏹ Задача выполнена — таймер остановлен.")
+	}
+	// Показываем обновлённые подробности задачи
+	b.showTaskDetail(chatID, userID, taskID)
+	b.NotifyUnblocked(b.space(userID), result.Unblocked)
+	if result.Next != nil {
+		b.sendWithInlineKeyboard(chatID,
+			fmt.Sprintf("🔁 Следующий повтор «%s» — до %s", result.Next.Title, formatDeadline(*result.Next.Deadline, b.loc)),
+			tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("📌 Открыть", fmt.Sprintf("task_%d", result.Next.ID)),
+			)))
+	}
+}
+
+// ============================================================
+// НАБОР СТАТУСОВ — /statuses
+// Пользователь может завести свои статусы («👀 На проверке»,
+// «⏸ Отложено») и ограничить переходы между ними (см. status.go)
+// ============================================================
+
+// showWorkflow — текущие статусы пользователя и кнопки настройки
+func (b *Bot) showWorkflow(chatID, userID int64) {
+	wf, err := b.storage.GetWorkflow(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	text := "📊 Твои статусы (по порядку):\n"
+	for i, status := range wf.Statuses {
+		text += fmt.Sprintf("\n%d. %s — %s", i+1, status.Label(), categoryLabels[status.Category])
+		if len(status.Next) > 0 {
+			labels := make([]string, len(status.Next))
+			for j, code := range status.Next {
+				labels[j] = wf.Label(code)
+			}
+			text += "\n    ➡️ " + strings.Join(labels, ", ")
+		}
+	}
+	text += "\n\nНовые задачи получают первый статус. Если переходы (➡️) не указаны, " +
+		"из статуса можно перейти в любой."
+	b.sendWithInlineKeyboard(chatID, text, workflowKeyboard())
+}
+
+// startWorkflowEdit — присылает текущий набор текстом и ждёт исправленный
+func (b *Bot) startWorkflowEdit(chatID, userID int64) {
+	wf, err := b.storage.GetWorkflow(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	state := b.getUserState(userID)
+	b.mu.Lock()
+	state.Step = StepEditWorkflow
+	b.mu.Unlock()
+
+	b.sendText(chatID, "✏️ Пришли набор статусов — по одному в строке:\n"+
+		"код: эмодзи Название (категория) -> куда можно перейти\n\n"+
+		"Категории: todo — не начата, doing — в работе, done — завершена.\n"+
+		"Часть «-> ...» можно не писать. Например:\n"+
+		"review: 👀 На проверке (doing) -> done, progress\n\n"+
+		"Сейчас так (скопируй и измени):")
+	b.sendText(chatID, wf.Format())
+}
+
+// handleWorkflowInput — разбирает и сохраняет новый набор статусов
+func (b *Bot) handleWorkflowInput(chatID, userID int64, text string) {
+	wf, err := ParseWorkflow(text)
+	if err != nil {
+		// Остаёмся на том же шаге — пользователь исправит и пришлёт снова
+		b.sendText(chatID, "⚠️ "+err.Error()+"\n\n✏️ Исправь и пришли набор ещё раз:")
+		return
+	}
+
+	b.resetUserState(userID)
+
+	if err := b.storage.SetWorkflow(b.space(userID), wf); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.sendText(chatID, "✅ Статусы сохранены.")
+	b.showWorkflow(chatID, userID)
+}
+
+// handleWorkflowReset — возвращает статусы по умолчанию
+func (b *Bot) handleWorkflowReset(chatID, userID int64) {
+	if err := b.storage.SetWorkflow(b.space(userID), DefaultWorkflow()); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.sendText(chatID, "✅ Вернул статусы по умолчанию.")
+	b.showWorkflow(chatID, userID)
+}
+
+// ============================================================
+// КОМАНДЫ — /team
+// Общие пространства, где задачи, проекты и статусы видны всем
+// участникам (см. workspaces.go). Пользователь работает с одним
+// пространством за раз; переключается здесь же
+// ============================================================
+
+// showWorkspaces — личные задачи и команды пользователя
+func (b *Bot) showWorkspaces(chatID, userID int64) {
+	workspaces, err := b.storage.GetWorkspaces(userID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	text := "👥 Твои пространства:\n\nЗадачи, проекты и статусы команды видны всем её участникам. " +
+		"Выбери, с чем работать 👇"
+	b.sendWithInlineKeyboard(chatID, text, workspacesKeyboard(userID, workspaces, b.space(userID)))
+}
+
+// handleSwitchSpace — переключает пользователя на личные задачи или команду
+func (b *Bot) handleSwitchSpace(chatID, userID, space int64) {
+	if err := CheckAccess(b.storage, userID, space); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.setSpace(userID, space)
+
+	if !IsShared(space) {
+		b.sendText(chatID, "👤 Теперь перед тобой личные задачи.")
+	} else if w, err := b.storage.GetWorkspace(space); err == nil {
+		b.sendText(chatID, fmt.Sprintf("👥 Теперь перед тобой задачи команды «%s».", w.Name))
+	}
+	b.showProjects(chatID, userID)
+}
+
+// enterSpace разбирает "<пространство>_<callback>" (см. inSpace),
+// переключает пользователя в пространство и возвращает callback
+func (b *Bot) enterSpace(chatID, userID int64, rest string) (string, bool) {
+	first, inner, found := strings.Cut(rest, "_")
+	space, err := strconv.ParseInt(first, 10, 64)
+	if !found || err != nil {
+		return "", false
+	}
+	if err := CheckAccess(b.storage, userID, space); err != nil {
+		b.sendStorageError(chatID, err)
+		return "", false
+	}
+	if b.space(userID) != space {
+		b.setSpace(userID, space)
+	}
+	return inner, true
+}
+
+// startWorkspaceCreation — спрашивает название новой команды
+func (b *Bot) startWorkspaceCreation(chatID, userID int64) {
+	state := b.getUserState(userID)
+	b.mu.Lock()
+	state.Step = StepWaitWorkspace
+	b.mu.Unlock()
+
+	b.sendText(chatID, "👥 Как назовём команду? Например: «Группа БВТ2301» или «Курсовой проект»")
+}
+
+// handleWorkspaceInput — создаёт команду, переключает в неё и даёт ссылку
+func (b *Bot) handleWorkspaceInput(chatID int64, member Member, name string) {
+	userID := member.UserID
+
+	if err := validateWorkspaceName(name); err != nil {
+		// Остаёмся на том же шаге — пользователь попробует ещё раз
+		b.sendText(chatID, "⚠️ "+err.Error()+"\n\n👥 Введи название ещё раз:")
+		return
+	}
+
+	b.resetUserState(userID)
+
+	w, err := b.storage.CreateWorkspace(member, name)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.setSpace(userID, w.ID)
+	b.sendText(chatID, fmt.Sprintf("✅ Команда «%s» создана, ты уже в ней.", w.Name))
+	b.showInvite(chatID, userID)
+}
+
+// showInvite — ссылка-приглашение в текущую команду
+func (b *Bot) showInvite(chatID, userID int64) {
+	space := b.space(userID)
+	if !IsShared(space) {
+		b.sendText(chatID, "👤 Сейчас открыты личные задачи — в них никого не пригласить. Выбери команду: /team")
+		return
+	}
+	w, err := b.storage.GetWorkspace(space)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.sendText(chatID, fmt.Sprintf("🔗 Отправь эту ссылку тем, кого зовёшь в «%s»:\n%s",
+		w.Name, InviteLink(b.Username(), w.InviteCode)))
+}
+
+// handleJoin — вступление в команду по ссылке-приглашению
+func (b *Bot) handleJoin(chatID int64, member Member, code string) {
+	userID := member.UserID
+	w, err := b.storage.JoinWorkspace(member, code)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.setSpace(userID, w.ID)
+
+	// По ссылке может прийти и новый пользователь — ему нужно меню
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"👋 Ты в команде «%s» (участников: %d).\nЗадачи команды — в «📋 Мои задачи», "+
+			"вернуться к личным — /team", w.Name, len(w.Members)))
+	msg.ReplyMarkup = mainMenuKeyboard(b.webAppURL)
+	b.send(msg)
+}
+
+// handleLeaveWorkspace — выход из команды (задачи остаются у остальных)
+func (b *Bot) handleLeaveWorkspace(chatID, userID, space int64) {
+	if err := b.storage.LeaveWorkspace(userID, space); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	if b.space(userID) == space {
+		b.setSpace(userID, userID)
+	}
+	b.sendText(chatID, "🚪 Готово: ты больше не в этой команде. Перед тобой личные задачи.")
+}
+
+// showMembers — участники текущей команды с ролями
+func (b *Bot) showMembers(chatID, userID int64) {
+	space := b.space(userID)
+	if !IsShared(space) {
+		b.sendText(chatID, "👤 Сейчас открыты личные задачи — в них только ты. Выбери команду: /team")
+		return
+	}
+	w, err := b.storage.GetWorkspace(space)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "👑 Участники «%s»:\n\n", w.Name)
+	for _, m := range w.Members {
+		fmt.Fprintf(&sb, "• %s — %s", m.Label(), m.Role.Label())
+		if m.UserID == userID {
+			sb.WriteString(" (ты)")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\nАдминистратор и владелец удаляют задачи и настраивают команду, " +
+		"участник создаёт и меняет задачи, наблюдатель только смотрит.")
+	b.sendWithInlineKeyboard(chatID, sb.String(), membersKeyboard(w, userID))
+}
+
+// showRoleSelection — роли, которые можно назначить участнику
+func (b *Bot) showRoleSelection(chatID, userID, memberID int64) {
+	w, err := b.storage.GetWorkspace(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	member, ok := w.Member(memberID)
+	if !ok {
+		b.sendStorageError(chatID, ErrMemberNotFound)
+		return
+	}
+	b.sendWithInlineKeyboard(chatID,
+		fmt.Sprintf("👑 %s — %s. Какую роль дать?", member.Label(), member.Role.Label()),
+		roleKeyboard(w, userID, member))
+}
+
+// handleSetRole — назначает роль (callback data "setrole_<пользователь>_<роль>")
+// и сообщает об этом самому участнику
+func (b *Bot) handleSetRole(chatID, userID int64, data string) {
+	first, code, found := strings.Cut(strings.TrimPrefix(data, "setrole_"), "_")
+	memberID, err := strconv.ParseInt(first, 10, 64)
+	if !found || err != nil {
+		return
+	}
+	role, err := ParseRole(code)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	w, err := b.storage.SetMemberRole(userID, b.space(userID), memberID, role)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	member, _ := w.Member(memberID)
+	b.sendText(chatID, fmt.Sprintf("✅ %s — теперь %s.", member.Label(), strings.ToLower(role.Label())))
+	b.sendText(memberID, fmt.Sprintf("👑 Твоя роль в команде «%s» теперь: %s.", w.Name, strings.ToLower(role.Label())))
+	b.showMembers(chatID, userID)
+}
+
+// checkSpace возвращает пользователя к личным задачам, если его
+// текущей команды больше нет или он в ней не состоит (например,
+// вышел через Mini App)
+func (b *Bot) checkSpace(chatID, userID int64) {
+	space := b.space(userID)
+	if !IsShared(space) {
+		return
+	}
+	err := CheckAccess(b.storage, userID, space)
+	if errors.Is(err, ErrWorkspaceNotFound) {
+		b.setSpace(userID, userID)
+		b.sendText(chatID, "⚠️ Ты больше не в команде — перед тобой личные задачи.")
+	} else if err != nil {
+		log.Printf("❌ Ошибка проверки пространства пользователя %d: %v", userID, err)
+	}
+}
+
+// ============================================================
+// ИСПОЛНИТЕЛИ
+// Задачу команды поручают её участнику (см. assignees.go);
+// исполнитель получает сообщение с задачей и кнопками статусов
+// ============================================================
+
+// showAssigneeSelection — участники команды для выбора исполнителя
+func (b *Bot) showAssigneeSelection(chatID, userID int64, taskID int) {
+	space := b.space(userID)
+	task, err := b.storage.GetTask(space, taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	w, err := b.storage.GetWorkspace(space)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.sendWithInlineKeyboard(chatID, fmt.Sprintf("👤 Кому поручить «%s»?", task.Title), assigneeKeyboard(task, w.Members))
+}
+
+// handleAssign — назначает исполнителя ("assignto_<ID задачи>_<ID пользователя>")
+func (b *Bot) handleAssign(chatID, userID int64, data string) {
+	first, second, _ := strings.Cut(strings.TrimPrefix(data, "assignto_"), "_")
+	taskID, err1 := strconv.Atoi(first)
+	assignee, err2 := strconv.ParseInt(second, 10, 64)
+	if err1 != nil || err2 != nil {
+		return
+	}
+
+	space := b.space(userID)
+	task, err := b.storage.UpdateTask(space, taskID, userID, TaskPatch{Assignee: &assignee, AssignedBy: userID})
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	if assignee == NoAssignee {
+		b.sendText(chatID, "🚫 Исполнитель снят.")
+	} else {
+		b.sendText(chatID, fmt.Sprintf("👤 Исполнитель: %s", MemberLabel(b.members(space), assignee)))
+	}
+	b.showTaskDetail(chatID, userID, taskID)
+	b.NotifyAssigned(space, task)
+}
+
+// NotifyAssigned присылает исполнителю задачу, которую ему поручили
+// (себе самому не пишем). Вызывается и ботом, и HTTP API
+func (b *Bot) NotifyAssigned(space int64, task Task) {
+	if task.Assignee == NoAssignee || task.Assignee == task.AssignedBy {
+		return
+	}
+
+	members := b.members(space)
+	header := "📬 Тебе поручили задачу"
+	if w, err := b.storage.GetWorkspace(space); err == nil {
+		header += fmt.Sprintf(" в команде «%s»", w.Name)
+	}
+	header += fmt.Sprintf(" (от: %s)", MemberLabel(members, task.AssignedBy))
+
+	msg := tgbotapi.NewMessage(task.Assignee, escapeMarkdown(header)+"\n\n"+b.taskDetailText(space, task, nil))
+	msg.ParseMode = "MarkdownV2"
+	msg.ReplyMarkup = assignedTaskKeyboard(task, b.workflow(space), space)
+	b.send(msg)
+}
+
+// showAssigned — «Назначено мне» (byMe == false) или «Назначено мной»:
+// задачи из всех пространств пользователя
+func (b *Bot) showAssigned(chatID, userID int64, byMe bool) {
+	find, title, empty := AssignedTo, "🙋 Назначено мне", "🙋 Тебе пока ничего не поручили."
+	if byMe {
+		find, title, empty = AssignedBy, "📤 Назначено мной", "📤 Пока нет задач, порученных тобой другим."
+	}
+
+	tasks, err := find(b.storage, userID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	// Названия команд и наборы статусов — для подписей кнопок
+	names := make(map[int64]string)
+	workflows := make(map[int64]Workflow)
+	for _, st := range tasks {
+		if _, ok := workflows[st.Space]; ok {
+			continue
+		}
+		workflows[st.Space] = b.workflow(st.Space)
+		if w, err := b.storage.GetWorkspace(st.Space); err == nil {
+			names[st.Space] = w.Name
+		}
+	}
+
+	// Сначала невыполненные, внутри — по приоритету и сроку
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Task.IsDone() != tasks[j].Task.IsDone() {
+			return !tasks[i].Task.IsDone()
+		}
+		return priorityLess(tasks[i].Task, tasks[j].Task)
+	})
+
+	text := fmt.Sprintf("%s — задач: %d", title, len(tasks))
+	if len(tasks) == 0 {
+		text = empty
+	}
+	b.sendWithInlineKeyboard(chatID, text, assignedListKeyboard(tasks, names, workflows, b.now(), b.loc))
+}
+
+// ============================================================
+// ПОВТОРЕНИЕ ЗАДАЧИ
+// ============================================================
+
+// showRecurrenceSelection — показывает текущее правило и готовые варианты
+func (b *Bot) showRecurrenceSelection(chatID, userID int64, taskID int) {
+	task, err := b.storage.GetTask(b.space(userID), taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	current := "не повторяется"
+	if task.Recurrence != nil {
+		current = task.Recurrence.Describe()
+	}
+	b.sendWithInlineKeyboard(chatID,
+		fmt.Sprintf("🔁 Сейчас: %s\n\nКак повторять задачу? Когда она будет выполнена, появится следующая.", current),
+		recurrenceKeyboard(taskID))
+}
+
+// handleSetRecurrence — применяет вариант повторения с кнопки
+func (b *Bot) handleSetRecurrence(chatID, userID int64, data string) {
+	// Callback data имеет формат: "setrecur_<taskID>_<вариант>"
+	parts := strings.SplitN(data, "_", 3)
+	if len(parts) < 3 {
+		return
+	}
+	taskID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return
+	}
+
+	// Дни считаем в часовом поясе бота
+	rec := &Recurrence{Interval: 1, Location: b.loc}
+	var patch TaskPatch
+	switch parts[2] {
+	case "daily":
+		rec.Freq = FreqDaily
+	case "weekly":
+		rec.Freq = FreqWeekly
+	case "weekdays":
+		rec.Freq = FreqWeekly
+		rec.Weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	case "monthly":
+		rec.Freq = FreqMonthly
+	case "custom":
+		b.startEdit(chatID, userID, taskID, StepEditRecurrence)
+		return
+	case "none":
+		patch.ClearRecurrence = true
+	default:
+		return
+	}
+	if !patch.ClearRecurrence {
+		patch.Recurrence = rec
+	}
+
+	task, err := b.storage.UpdateTask(b.space(userID), taskID, userID, patch)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	if task.Recurrence == nil {
+		b.sendText(chatID, "✅ Повторение убрано.")
+	} else {
+		msg := fmt.Sprintf("✅ Повтор: %s", task.Recurrence.Describe())
+		if task.Deadline == nil {
+			msg += "\nСрок следующего повтора отсчитается от момента выполнения."
+		}
+		b.sendText(chatID, msg)
+	}
+	b.showTaskDetail(chatID, userID, taskID)
+}
+
+// ============================================================
+// ЗАВИСИМОСТИ
+// ============================================================
+
+// showDependencies — показывает задачи, которые можно отметить блокерами
+func (b *Bot) showDependencies(chatID, userID int64, taskID int) {
+	task, err := b.storage.GetTask(b.space(userID), taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	tasks, err := b.storage.GetTasks(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	if len(tasks) < 2 {
+		b.sendText(chatID, "🔗 Других задач пока нет — зависеть не от чего.")
+		return
+	}
+	b.sendWithInlineKeyboard(chatID,
+		"🔗 Какие задачи нужно выполнить раньше этой?\nНажми, чтобы отметить или снять.",
+		dependencyKeyboard(task, tasks))
+}
+
+// handleToggleDependency — добавляет или убирает блокер и обновляет клавиатуру на месте
+func (b *Bot) handleToggleDependency(chatID int64, messageID int, userID int64, data string) {
+	// Callback data: "dep_<taskID>_<blockerID>"
+	taskID, blockerID, ok := parseTwoIDs(data, "dep_")
+	if !ok {
+		return
+	}
+	task, err := b.storage.GetTask(b.space(userID), taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	if slices.Contains(task.BlockedBy, blockerID) {
+		task, err = b.storage.RemoveDependency(b.space(userID), taskID, blockerID, userID)
+	} else {
+		task, err = b.storage.AddDependency(b.space(userID), taskID, blockerID, userID)
+	}
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	tasks, err := b.storage.GetTasks(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, dependencyKeyboard(task, tasks))
+	if _, err := b.api.Send(edit); err != nil {
+		log.Printf("❌ Ошибка обновления сообщения: %v", err)
+	}
+}
+
+// ============================================================
+// ИСТОРИЯ ИЗМЕНЕНИЙ
+// ============================================================
+
+// maxHistoryLines — сколько последних событий показывать в чате
+// (у сообщения Telegram есть предел длины)
+const maxHistoryLines = 20
+
+// showHistory — показывает, когда и что менялось в задаче
+func (b *Bot) showHistory(chatID, userID int64, taskID int) {
+	events, err := b.storage.GetHistory(b.space(userID), taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	if len(events) == 0 {
+		b.sendText(chatID, "🕓 История пуста: задача создана до того, как бот начал её вести.")
+		return
+	}
+
+	text := "🕓 История задачи:\n"
+	if len(events) > maxHistoryLines {
+		text = fmt.Sprintf("🕓 История задачи (последние %d из %d):\n", maxHistoryLines, len(events))
+		events = events[len(events)-maxHistoryLines:]
+	}
+	space := b.space(userID)
+	wf, members := b.workflow(space), b.members(space)
+	for _, e := range events {
+		// В команде важно, кто внёс изменение; очистку корзины (Actor = 0) делает бот
+		who := ""
+		if IsShared(space) && e.Actor != 0 {
+			who = MemberLabel(members, e.Actor) + ": "
+		}
+		text += fmt.Sprintf("\n%s — %s%s", formatDeadline(e.At, b.loc), who, e.Describe(b.loc, wf, members))
+	}
+
+	b.sendWithInlineKeyboard(chatID, text, tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ К задаче", fmt.Sprintf("task_%d", taskID)),
+		),
+	))
+}
+
+// NotifyUnblocked сообщает, что задачи больше ничего не ждут: владельцу
+// личных задач или всем участникам команды (space — пространство задач)
+// Вызывается и ботом, и HTTP API (статус можно сменить в Mini App)
+func (b *Bot) NotifyUnblocked(space int64, tasks []Task) {
+	recipients := b.recipients(space)
+	for _, task := range tasks {
+		for _, chatID := range recipients {
+			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+				"🔓 Задача «%s» разблокирована — всё, что она ждала, выполнено!", task.Title))
+			msg.ReplyMarkup = openTaskKeyboard(task.ID, space)
+			b.send(msg)
+		}
+	}
+}
+
+// ============================================================
+// ОБСУЖДЕНИЕ ЗАДАЧИ
+// Комментарий — ответ (reply) на сообщение с задачей: карточку,
+// ленту комментариев или уведомление (см. comments.go)
+// ============================================================
+
+// maxCommentLines — сколько последних комментариев показывать в чате,
+// maxCommentPreview — до скольких символов обрезать каждый
+// (у сообщения Telegram есть предел длины)
+const (
+	maxCommentLines   = 10
+	maxCommentPreview = 300
+)
+
+// commentHint — подсказка, как написать комментарий
+const commentHint = "↩️ Ответь на это сообщение, чтобы написать комментарий."
+
+// showComments — последние комментарии к задаче
+func (b *Bot) showComments(chatID, userID int64, taskID int) {
+	space := b.space(userID)
+	task, err := b.storage.GetTask(space, taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	comments, err := b.storage.GetComments(space, taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "💬 Обсуждение «%s»", task.Title)
+	if len(comments) > maxCommentLines {
+		fmt.Fprintf(&sb, " (последние %d из %d)", maxCommentLines, len(comments))
+		comments = comments[len(comments)-maxCommentLines:]
+	}
+	sb.WriteString(":")
+	if len(comments) == 0 {
+		sb.WriteString("\n\nПока ни одного комментария.")
+	}
+	members := b.members(space)
+	for _, c := range comments {
+		fmt.Fprintf(&sb, "\n\n%s · %s\n%s", b.authorLabel(space, members, c.Author),
+			formatDeadline(c.CreatedAt, b.loc), truncate(c.Text, maxCommentPreview))
+	}
+	sb.WriteString("\n\n" + commentHint)
+	b.sendWithInlineKeyboard(chatID, sb.String(), commentsKeyboard(space, taskID))
+}
+
+// replyTask находит задачу, на сообщение о которой ответил пользователь:
+// у таких сообщений бота есть кнопка обсуждения с пространством
+// и ID задачи (см. commentsButton).
+// Пространство берём из кнопки и текущее не переключаем: отвечать
+// можно и на уведомление из другой команды
+func (b *Bot) replyTask(msg *tgbotapi.Message) (int64, int, bool) {
+	reply := msg.ReplyToMessage
+	if reply == nil || reply.From == nil || reply.From.ID != b.api.Self.ID || reply.ReplyMarkup == nil {
+		return 0, 0, false
+	}
+	for _, row := range reply.ReplyMarkup.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData == nil {
+				continue
+			}
+			rest, ok := strings.CutPrefix(*button.CallbackData, "in_")
+			if !ok {
+				continue
+			}
+			first, inner, _ := strings.Cut(rest, "_")
+			space, err := strconv.ParseInt(first, 10, 64)
+			if err != nil {
+				continue
+			}
+			if id, ok := strings.CutPrefix(inner, "comments_"); ok {
+				if taskID, err := strconv.Atoi(id); err == nil {
+					return space, taskID, true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// handleCommentReply — ответ на сообщение с задачей становится комментарием
+// (space — из replyTask)
+func (b *Bot) handleCommentReply(chatID, userID, space int64, taskID int, text string) {
+	if err := Authorize(b.storage, userID, space, PermEdit); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	task, err := b.storage.GetTask(space, taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	comment, err := b.storage.AddComment(space, taskID, userID, text)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.sendText(chatID, fmt.Sprintf("💬 Комментарий к «%s» добавлен.", task.Title))
+	b.NotifyComment(space, task, comment)
+}
+
+// NotifyComment присылает новый комментарий остальным участникам команды
+// (в личных задачах сообщать некому). Вызывается и ботом, и HTTP API
+func (b *Bot) NotifyComment(space int64, task Task, comment Comment) {
+	if !IsShared(space) {
+		return
+	}
+	w, err := b.storage.GetWorkspace(space)
+	if err != nil {
+		log.Printf("❌ Ошибка чтения участников пространства %d: %v", space, err)
+		return
+	}
+	text := fmt.Sprintf("💬 %s — к задаче «%s» (команда «%s»):\n\n%s\n\n%s",
+		MemberLabel(w.Members, comment.Author), task.Title, w.Name, comment.Text, commentHint)
+	for _, m := range w.Members {
+		if m.UserID == comment.Author {
+			continue
+		}
+		b.sendWithInlineKeyboard(m.UserID, text, commentsKeyboard(space, task.ID))
+	}
+}
+
+// commentCount — сколько комментариев у задачи (для кнопки обсуждения)
+// Ошибку только логируем: из-за неё не стоит прятать карточку задачи
+func (b *Bot) commentCount(space int64, taskID int) int {
+	comments, err := b.storage.GetComments(space, taskID)
+	if err != nil {
+		log.Printf("❌ Ошибка чтения комментариев задачи %d: %v", taskID, err)
+	}
+	return len(comments)
+}
+
+// authorLabel — подпись автора комментария: в личных задачах автор
+// всегда сам пользователь, в команде — имя участника
+func (b *Bot) authorLabel(space int64, members []Member, author int64) string {
+	if !IsShared(space) {
+		return "Ты"
+	}
+	return MemberLabel(members, author)
+}
+
+// ============================================================
+// ВЛОЖЕНИЯ (см. attachments.go)
+// ============================================================
+
+// attachmentHint — подсказка, как приложить файл
+const attachmentHint = "📎 Чтобы приложить фото или документ, открой задачу и пришли файл — или ответь им на сообщение с задачей."
+
+// fileAttachment — вложение из фото или документа в сообщении
+// (из размеров фото, которые прислал Telegram, берём самый большой — он последний)
+func fileAttachment(msg *tgbotapi.Message) Attachment {
+	att := Attachment{Author: msg.From.ID, Caption: msg.Caption}
+	if len(msg.Photo) > 0 {
+		photo := msg.Photo[len(msg.Photo)-1]
+		att.Kind = AttachmentPhoto
+		att.FileID = photo.FileID
+		att.MimeType = "image/jpeg"
+		att.Size = int64(photo.FileSize)
+		return att
+	}
+	att.Kind = AttachmentDocument
+	att.FileID = msg.Document.FileID
+	att.Name = msg.Document.FileName
+	att.MimeType = msg.Document.MimeType
+	att.Size = int64(msg.Document.FileSize)
+	return att
+}
+
+// handleFile — пользователь прислал фото или документ. Куда его деть:
+// 1. Ответ на сообщение с задачей — приложить к этой задаче
+// 2. Недавно открытая задача (viewedTask) — приложить к ней
+// 3. Есть подпись — создать задачу с этим названием и файлом
+// 4. Иначе — подсказать, как приложить файл
+func (b *Bot) handleFile(msg *tgbotapi.Message) {
+	userID := msg.From.ID
+	chatID := msg.Chat.ID
+	att := fileAttachment(msg)
+
+	if space, taskID, ok := b.replyTask(msg); ok {
+		b.attachFile(chatID, userID, space, taskID, att)
+		return
+	}
+	if space, taskID, ok := b.viewedTask(userID); ok {
+		b.attachFile(chatID, userID, space, taskID, att)
+		return
+	}
+	if strings.TrimSpace(att.Caption) != "" {
+		b.createTaskFromFile(chatID, userID, att)
+		return
+	}
+	b.sendText(chatID, "🤔 К какой задаче это приложить?\n\n"+attachmentHint+
+		"\nА фото с подписью станет новой задачей.")
+}
+
+// attachFile прикладывает файл к задаче пространства space
+// (из replyTask или viewedTask — текущее не переключаем, см. replyTask)
+func (b *Bot) attachFile(chatID, userID, space int64, taskID int, att Attachment) {
+	if err := Authorize(b.storage, userID, space, PermEdit); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	task, err := b.storage.GetTask(space, taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	att, err = b.storage.AddAttachment(space, taskID, att)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.sendWithInlineKeyboard(chatID,
+		fmt.Sprintf("📎 %s — приложено к задаче «%s».", attachmentLabel(att), task.Title),
+		attachmentsKeyboard(space, taskID))
+}
+
+// createTaskFromFile — файл с подписью становится новой задачей во
+// «Входящих» текущего пространства: первая строка подписи — название
+// (#хештеги — теги), остальное — описание, файл — первое вложение
+func (b *Bot) createTaskFromFile(chatID, userID int64, att Attachment) {
+	if !b.allowed(chatID, userID, PermEdit) {
+		return
+	}
+	first, rest, _ := strings.Cut(strings.TrimSpace(att.Caption), "\n")
+	title, tags := ExtractTags(first)
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		b.sendText(chatID, "⚠️ "+err.Error())
+		return
+	}
+
+	space := b.space(userID)
+	task, err := b.storage.AddTask(space, userID, TaskDraft{
+		Title:       title,
+		Description: strings.TrimSpace(rest),
+		Tags:        tags,
+	})
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.attachSource(chatID, space, task.ID, att)
+
+	b.sendText(chatID, "✅ Задача создана из файла! Следующие файлы, пока она открыта, приложатся к ней.")
+	b.showTaskDetail(chatID, userID, task.ID)
+}
+
+// attachSource прикладывает к только что созданной задаче файл, из которого
+// она сделана. Подпись файла уже стала названием и описанием — второй раз
+// её не храним
+func (b *Bot) attachSource(chatID, space int64, taskID int, att Attachment) {
+	att.Caption = ""
+	if _, err := b.storage.AddAttachment(space, taskID, att); err != nil {
+		b.sendStorageError(chatID, err)
+	}
+}
+
+// showAttachments присылает файлы задачи и подсказку, как добавить ещё
+func (b *Bot) showAttachments(chatID, userID int64, taskID int) {
+	space := b.space(userID)
+	task, err := b.storage.GetTask(space, taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	attachments, err := b.storage.GetAttachments(space, taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	members := b.members(space)
+	for _, a := range attachments {
+		caption := a.Caption
+		if IsShared(space) {
+			caption = strings.TrimSpace(caption + "\n👤 " + MemberLabel(members, a.Author))
+		}
+		b.sendAttachment(chatID, a, caption)
+	}
+
+	text := fmt.Sprintf("📎 У задачи «%s» пока нет вложений.", task.Title)
+	if len(attachments) > 0 {
+		text = fmt.Sprintf("📎 Вложения задачи «%s»: %d", task.Title, len(attachments))
+	}
+	b.setViewing(userID, space, taskID)
+	b.sendWithInlineKeyboard(chatID, text+"\n\n"+attachmentHint, attachmentsKeyboard(space, taskID))
+}
+
+// sendAttachment присылает файл вложения: присланный боту — по file_id,
+// загруженный через Mini App — с диска (документом, чтобы не сжимать оригинал)
+func (b *Bot) sendAttachment(chatID int64, a Attachment, caption string) {
+	var file tgbotapi.RequestFileData = tgbotapi.FileID(a.FileID)
+	kind := a.Kind
+	if a.FileID == "" {
+		if b.files == nil {
+			b.sendText(chatID, fmt.Sprintf("⚠️ Файл «%s» недоступен: хранилище файлов не настроено.", a.Name))
+			return
+		}
+		f, err := b.files.Open(a.Blob)
+		if err != nil {
+			log.Printf("❌ Ошибка чтения вложения %q: %v", a.Blob, err)
+			b.sendText(chatID, fmt.Sprintf("⚠️ Файл «%s» не найден.", a.Name))
+			return
+		}
+		defer f.Close()
+		file = tgbotapi.FileReader{Name: a.Name, Reader: f}
+		kind = AttachmentDocument
+	}
+
+	var msg tgbotapi.Chattable
+	if kind == AttachmentPhoto {
+		photo := tgbotapi.NewPhoto(chatID, file)
+		photo.Caption = caption
+		msg = photo
+	} else {
+		doc := tgbotapi.NewDocument(chatID, file)
+		doc.Caption = caption
+		msg = doc
+	}
+	if _, err := b.api.Send(msg); err != nil {
+		log.Printf("❌ Ошибка отправки вложения: %v", err)
+	}
+}
+
+// attachmentCount — сколько вложений у задачи (для кнопки «📎 Файлы»)
+// Ошибку только логируем, как в commentCount
+func (b *Bot) attachmentCount(space int64, taskID int) int {
+	attachments, err := b.storage.GetAttachments(space, taskID)
+	if err != nil {
+		log.Printf("❌ Ошибка чтения вложений задачи %d: %v", taskID, err)
+	}
+	return len(attachments)
+}
+
+// attachmentLabel — вложение одной строкой: "🖼 Фото" или "📄 lab1.pdf (340 КБ)"
+func attachmentLabel(a Attachment) string {
+	if a.Kind == AttachmentPhoto {
+		return "🖼 Фото"
+	}
+	if a.Size > 0 {
+		return fmt.Sprintf("📄 %s (%s)", a.Name, FormatSize(a.Size))
+	}
+	return "📄 " + a.Name
+}
+
+// ============================================================
+// УЧЁТ ВРЕМЕНИ (см. timetracking.go)
+// ============================================================
+
+// toggleTimer — кнопка таймера в карточке задачи: запускает таймер
+// пользователя или останавливает идущий, затем обновляет карточку
+func (b *Bot) toggleTimer(chatID int64, messageID int, userID int64, taskID int) {
+	space := b.space(userID)
+	if b.timerRunning(space, taskID, userID) {
+		entry, err := b.storage.StopTimer(space, taskID, userID)
+		if err != nil {
+			b.sendStorageError(chatID, err)
+			return
+		}
+		total := SummarizeTime(b.timeEntries(space, taskID), b.now(), b.loc).Total
+		b.sendText(chatID, fmt.Sprintf("⏹ Таймер остановлен: %s. Всего по задаче: %s",
+			FormatTracked(entry.Duration(b.now())), FormatTracked(total)))
+	} else {
+		if _, err := b.storage.StartTimer(space, taskID, userID); err != nil {
+			b.sendStorageError(chatID, err)
+			return
+		}
+		b.sendText(chatID, "⏱ Таймер запущен. Останови его кнопкой в карточке задачи — "+
+			"или он остановится сам, когда задача будет выполнена.")
+	}
+	b.refreshTaskDetail(chatID, messageID, userID, taskID)
+}
+
+// showTimeReport — итоги учёта времени задачи: всего, по дням и последние записи
+func (b *Bot) showTimeReport(chatID, userID int64, taskID int) {
+	space := b.space(userID)
+	task, err := b.storage.GetTask(space, taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	entries, err := b.storage.GetTimeEntries(space, taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	text := fmt.Sprintf("🕒 Учёт времени «%s»\n", task.Title)
+	if len(entries) == 0 {
+		b.sendWithInlineKeyboard(chatID, text+"\nВремя ещё не учтено. Запусти таймер в карточке задачи "+
+			"или добавь время вручную.", timeKeyboard(taskID))
+		return
+	}
+
+	now := b.now()
+	summary := SummarizeTime(entries, now, b.loc)
+	text += "\nВсего: " + FormatTracked(summary.Total)
+	if summary.Running > 0 {
+		text += fmt.Sprintf("\n⏱ Идёт таймеров: %d", summary.Running)
+	}
+
+	text += "\n\nПо дням:"
+	for _, day := range summary.Days {
+		date, _ := time.ParseInLocation("2006-01-02", day.Date, b.loc)
+		text += fmt.Sprintf("\n• %s — %s", date.Format("02.01"), FormatTracked(day.Duration))
+	}
+
+	// Последние записи — не больше timeReportEntries, новые снизу
+	text += "\n\nЗаписи:"
+	members := b.members(space)
+	for _, e := range entries[max(0, len(entries)-timeReportEntries):] {
+		line := fmt.Sprintf("\n• %s · %s", e.Start.In(b.loc).Format("02.01 15:04"), FormatTracked(e.Duration(now)))
+		switch {
+		case e.Running():
+			line += " (идёт)"
+		case e.Manual:
+			line += " (вручную)"
+		}
+		if IsShared(space) {
+			line += " · " + b.authorLabel(space, members, e.UserID)
+		}
+		if e.Note != "" {
+			line += " — " + e.Note
+		}
+		text += line
+	}
+	b.sendWithInlineKeyboard(chatID, text, timeKeyboard(taskID))
+}
+
+// timeReportEntries — сколько последних записей показывать в итогах
+const timeReportEntries = 10
+
+// startTimeInput — ждём от пользователя, сколько времени добавить
+func (b *Bot) startTimeInput(chatID, userID int64, taskID int) {
+	if _, err := b.storage.GetTask(b.space(userID), taskID); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	state := b.getUserState(userID)
+	b.mu.Lock()
+	state.Step = StepAddTime
+	state.TempTaskID = taskID
+	b.mu.Unlock()
+
+	b.sendText(chatID, "🕒 Сколько времени добавить? Например: 45м, 1ч 30м или 1:30.\n"+
+		"Время запишется как закончившееся только что.")
+}
+
+// handleTimeInput — добавляет запись времени вручную
+func (b *Bot) handleTimeInput(chatID, userID int64, text string) {
+	d, err := ParseTrackedDuration(text)
+	if err != nil {
+		b.sendText(chatID, "⚠️ "+err.Error())
+		return
+	}
+
+	state := b.getUserState(userID)
+	b.mu.Lock()
+	taskID := state.TempTaskID
+	b.mu.Unlock()
+	b.resetUserState(userID)
+
+	space := b.space(userID)
+	if _, err := b.storage.AddTimeEntry(space, taskID, ManualEntry(userID, b.now(), d, "")); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	total := SummarizeTime(b.timeEntries(space, taskID), b.now(), b.loc).Total
+	b.sendWithInlineKeyboard(chatID, fmt.Sprintf("✅ Добавлено: %s. Всего по задаче: %s",
+		FormatTracked(d), FormatTracked(total)), timeKeyboard(taskID))
+}
+
+// timeEntries — записи времени задачи (для карточки; ошибку только логируем)
+func (b *Bot) timeEntries(space int64, taskID int) []TimeEntry {
+	entries, err := b.storage.GetTimeEntries(space, taskID)
+	if err != nil {
+		log.Printf("❌ Ошибка чтения учёта времени задачи %d: %v", taskID, err)
+	}
+	return entries
+}
+
+// timerRunning — идёт ли таймер пользователя userID у задачи
+func (b *Bot) timerRunning(space int64, taskID int, userID int64) bool {
+	_, running := RunningTimer(b.timeEntries(space, taskID), userID)
+	return running
+}
+
+// ============================================================
+// СТАТИСТИКА — /stats (см. stats.go)
+// Показатели текущего пространства за последние недели
+// ============================================================
+func (b *Bot) showStats(chatID, userID int64) {
+	now := b.now()
+	from, to := DefaultStatsPeriod(now, b.loc)
+	space := b.space(userID)
+	stats, err := LoadStats(b.storage, space, from, to, now, b.loc)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	text := fmt.Sprintf("📊 Статистика за %s–%s\n\n", from.Format("02.01"), to.AddDate(0, 0, -1).Format("02.01.2006"))
+	text += fmt.Sprintf("➕ Создано: %d\n✅ Выполнено: %d\n", stats.Created, stats.Completed)
+	if stats.Completed > 0 {
+		text += "⏱ Среднее время выполнения: " + FormatLeadTime(stats.LeadTime) + "\n"
+	}
+	text += fmt.Sprintf("🔥 Просрочено сейчас: %d\n", stats.Overdue)
+
+	text += "\nПо неделям (создано / выполнено):"
+	for _, w := range stats.Weeks {
+		text += fmt.Sprintf("\n• %s–%s: %d / %d", w.Start.Format("02.01"), w.Start.AddDate(0, 0, 6).Format("02.01"),
+			w.Created, w.Completed)
+	}
+
+	text += "\n\nСейчас по статусам:"
+	for _, s := range stats.ByStatus {
+		text += fmt.Sprintf("\n• %s: %d", s.Status.Label(), s.Count)
+	}
+
+	if len(stats.ByMember) > 0 {
+		members := b.members(space)
+		text += "\n\n👥 По участникам (выполнено / открыто):"
+		for _, m := range stats.ByMember {
+			text += fmt.Sprintf("\n• %s: %d / %d", MemberLabel(members, m.UserID), m.Completed, m.Open)
+		}
+	}
+	b.sendText(chatID, text)
+}
+
+// ============================================================
+// ПЕРЕСЛАННЫЕ СООБЩЕНИЯ (см. forward.go)
+// ============================================================
+
+// offerForwardedTask — отвечает на пересланное сообщение предложением
+// сделать из него задачу. Пересланный файл без текста — обычное
+// вложение (см. handleFile)
+func (b *Bot) offerForwardedTask(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	text := forwardText(msg)
+	if text == "" {
+		if len(msg.Photo) > 0 || msg.Document != nil {
+			b.handleFile(msg)
+			return
+		}
+		b.sendText(chatID, "🤔 В пересланном сообщении нет текста — из него не получится задача.")
+		return
+	}
+
+	source := forwardSource(msg)
+	draft := forwardDraft(text, source)
+	reply := tgbotapi.NewMessage(chatID, fmt.Sprintf("📨 Сделать задачу из сообщения?\n\n📌 %s\n👤 %s",
+		draft.Title, source.Label(b.loc)))
+	reply.ReplyToMessageID = msg.MessageID
+	reply.ReplyMarkup = forwardTaskKeyboard(b.space(msg.From.ID))
+	b.send(reply)
+}
+
+// createForwardedTask — кнопка «✅ Создать задачу» под предложением:
+// исходное сообщение — то, на которое предложение отвечает.
+// Файл из сообщения становится первым вложением задачи
+func (b *Bot) createForwardedTask(chatID, userID int64, offer *tgbotapi.Message) {
+	original := offer.ReplyToMessage
+	if original == nil || !isForwarded(original) || forwardText(original) == "" {
+		b.sendText(chatID, "⚠️ Не нашёл исходное сообщение — перешли его ещё раз.")
+		return
+	}
+
+	space := b.space(userID)
+	task, err := b.storage.AddTask(space, userID, forwardDraft(forwardText(original), forwardSource(original)))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	if len(original.Photo) > 0 || original.Document != nil {
+		b.attachSource(chatID, space, task.ID, fileAttachment(original))
+	}
+
+	// Убираем кнопку, чтобы повторное нажатие не создало копию задачи
+	edit := tgbotapi.NewEditMessageText(chatID, offer.MessageID, "✅ Задача создана из сообщения: "+task.Title)
+	if _, err := b.api.Send(edit); err != nil {
+		log.Printf("❌ Ошибка обновления сообщения: %v", err)
+	}
+	b.showTaskDetail(chatID, userID, task.ID)
+}
+
+// formatTaskTitles — названия задач списком, по одной на строку
+func formatTaskTitles(tasks []Task) string {
+	lines := make([]string, len(tasks))
+	for i, task := range tasks {
+		lines[i] = "• " + task.Title
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ============================================================
+// СМЕНА ПРИОРИТЕТА
+// ============================================================
+
+// showPrioritySelection — показывает кнопки выбора приоритета
+func (b *Bot) showPrioritySelection(chatID int64, taskID int) {
+	keyboard := priorityKeyboard(taskID)
+	b.sendWithInlineKeyboard(chatID, "Выбери приоритет:", keyboard)
+}
+
+// handleSetPriority — устанавливает выбранный приоритет
+func (b *Bot) handleSetPriority(chatID, userID int64, data string) {
+	// Callback data имеет формат: "setprio_<taskID>_<код приоритета>"
+	parts := strings.SplitN(data, "_", 3)
+	if len(parts) < 3 {
+		return
+	}
 	taskID, err := strconv.Atoi(parts[1])
 	if err != nil {
 		return
 	}
+	priority := parts[2]
+	if !IsValidPriority(priority) {
+		return
+	}
 
-	// Определяем полный статус по короткому ключу
-	var status string
-	switch parts[2] {
-	case "new":
-		status = StatusNew
-	case "progress":
-		status = StatusInProgress
-	case "done":
-		status = StatusDone
-	default:
+	if _, err := b.storage.UpdateTask(b.space(userID), taskID, userID, TaskPatch{Priority: &priority}); err != nil {
+		b.sendStorageError(chatID, err)
 		return
 	}
 
-	// Обновляем статус в хранилище
-	if b.storage.UpdateStatus(userID, taskID, status) {
-		b.sendText(chatID, fmt.Sprintf("✅ Статус изменён на: %s", status))
-		// Показываем обновлённые подробности задачи
-		b.showTaskDetail(chatID, userID, taskID)
-	} else {
-		b.sendText(chatID, "⚠️ Задача не найдена.")
+	b.sendText(chatID, fmt.Sprintf("✅ Приоритет изменён на: %s", PriorityLabel(priority)))
+	b.showTaskDetail(chatID, userID, taskID)
+}
+
+// ============================================================
+// РЕДАКТИРОВАНИЕ ЗАДАЧИ — пошаговый диалог
+// ============================================================
+
+// showEditSelection — спрашивает, что именно редактировать
+func (b *Bot) showEditSelection(chatID int64, taskID int) {
+	keyboard := editKeyboard(taskID)
+	b.sendWithInlineKeyboard(chatID, "✏️ Что изменить?", keyboard)
+}
+
+// startEdit — запоминает задачу и ждёт новое значение поля
+func (b *Bot) startEdit(chatID, userID int64, taskID int, step string) {
+	// Убеждаемся, что задача существует, и показываем текущее значение
+	task, err := b.storage.GetTask(b.space(userID), taskID)
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+
+	state := b.getUserState(userID)
+	b.mu.Lock()
+	state.Step = step
+	state.TempTaskID = taskID
+	b.mu.Unlock()
+
+	switch step {
+	case StepEditTitle:
+		b.sendText(chatID, fmt.Sprintf("Сейчас: %s\n\n✏️ Введи новое название:", task.Title))
+		return
+	case StepEditDeadline:
+		current := "без срока"
+		if task.Deadline != nil {
+			current = formatDeadline(*task.Deadline, b.loc)
+		}
+		b.sendText(chatID, fmt.Sprintf("Сейчас: %s\n\n⏰ Введи новый срок (или «-», чтобы убрать):", current))
+		return
+	case StepEditTags:
+		current := "без тегов"
+		if len(task.Tags) > 0 {
+			current = formatTags(task.Tags)
+		}
+		b.sendText(chatID, fmt.Sprintf("Сейчас: %s\n\n🏷 Введи теги через пробел, например «#матан #работа» (или «-», чтобы убрать все):", current))
+		return
+	case StepEditRecurrence:
+		b.sendText(chatID, "🔁 Напиши, как повторять задачу, например:\n"+
+			"• каждые 3 дня\n"+
+			"• по понедельникам и средам\n"+
+			"• каждые 2 недели по пт\n"+
+			"• каждый месяц до 31.12\n"+
+			"(или «-», чтобы убрать повторение)")
+		return
+	}
+
+	current := task.Description
+	if current == "" {
+		current = "(пусто)"
+	}
+	b.sendText(chatID, fmt.Sprintf("Сейчас: %s\n\n📝 Введи новое описание (или «-», чтобы очистить):", current))
+}
+
+// handleEditInput — пользователь ввёл новое значение поля
+func (b *Bot) handleEditInput(chatID, userID int64, step, text string) {
+	state := b.getUserState(userID)
+	b.mu.Lock()
+	taskID := state.TempTaskID
+	b.mu.Unlock()
+
+	var patch TaskPatch
+	switch step {
+	case StepEditTitle:
+		if strings.TrimSpace(text) == "" {
+			b.sendText(chatID, "⚠️ Название не может быть пустым. Введи новое название:")
+			return
+		}
+		// #хештеги из нового названия добавляются к тегам задачи
+		title, tags := ExtractTags(text)
+		if len(tags) > 0 {
+			task, err := b.storage.GetTask(b.space(userID), taskID)
+			if err != nil {
+				b.resetUserState(userID)
+				b.sendStorageError(chatID, err)
+				return
+			}
+			tags = append(slices.Clone(task.Tags), tags...)
+			if _, err := normalizeTags(tags); err != nil {
+				b.sendText(chatID, "⚠️ "+err.Error()+"\n\n✏️ Введи новое название:")
+				return
+			}
+			patch.Tags = &tags
+		}
+		patch.Title = &title
+	case StepEditDesc:
+		if text == "-" {
+			text = ""
+		}
+		patch.Description = &text
+	case StepEditDeadline:
+		if text == "-" {
+			patch.ClearDeadline = true
+			break
+		}
+		deadline, ok := b.parseDeadlineInput(chatID, text)
+		if !ok {
+			return
+		}
+		patch.Deadline = &deadline
+	case StepEditTags:
+		var tags []string
+		if text != "-" {
+			tags = strings.Fields(strings.ReplaceAll(text, ",", " "))
+		}
+		if _, err := normalizeTags(tags); err != nil {
+			b.sendText(chatID, "⚠️ "+err.Error()+"\n\n🏷 Введи теги ещё раз:")
+			return
+		}
+		patch.Tags = &tags
+	case StepEditRecurrence:
+		if text == "-" {
+			patch.ClearRecurrence = true
+			break
+		}
+		rec, err := ParseRecurrenceText(text, b.now())
+		if err != nil {
+			b.sendText(chatID, "⚠️ Не понял правило. Попробуй, например, «каждую неделю» или «по пн и чт»:")
+			return
+		}
+		patch.Recurrence = rec
+	}
+
+	b.resetUserState(userID)
+
+	if _, err := b.storage.UpdateTask(b.space(userID), taskID, userID, patch); err != nil {
+		b.sendStorageError(chatID, err)
+		return
 	}
+
+	b.sendText(chatID, "✅ Задача обновлена.")
+	b.showTaskDetail(chatID, userID, taskID)
 }
 
 // ============================================================
@@ -338,13 +2784,56 @@ func (b *Bot) showDeleteConfirmation(chatID int64, taskID int) {
 	b.sendWithInlineKeyboard(chatID, "⚠️ Ты уверен, что хочешь удалить эту задачу?", keyboard)
 }
 
-// handleDelete — удаляет задачу из хранилища
+// handleDelete — убирает задачу в корзину (см. trash.go)
+// Сразу предлагаем отменить: удалить могли по ошибке
 func (b *Bot) handleDelete(chatID, userID int64, taskID int) {
-	if b.storage.DeleteTask(userID, taskID) {
-		b.sendText(chatID, "🗑 Задача удалена.")
-	} else {
-		b.sendText(chatID, "⚠️ Задача не найдена.")
+	if err := b.storage.DeleteTask(b.space(userID), taskID, userID); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	text := fmt.Sprintf("🗑 Задача перемещена в корзину. Через %s она удалится навсегда.",
+		formatOffset(b.trashRetention))
+	b.sendWithInlineKeyboard(chatID, text, deletedTaskKeyboard(taskID))
+}
+
+// ============================================================
+// КОРЗИНА — /trash
+// ============================================================
+
+// showTrash — задачи в корзине; нажатие на задачу восстанавливает её
+func (b *Bot) showTrash(chatID, userID int64) {
+	tasks, err := b.storage.GetTrash(b.space(userID))
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
 	}
+	if len(tasks) == 0 {
+		b.sendText(chatID, "🗑 Корзина пуста.")
+		return
+	}
+
+	text := fmt.Sprintf("🗑 Корзина (%d)", len(tasks))
+	if len(tasks) > trashListLimit {
+		text = fmt.Sprintf("🗑 Корзина (последние %d из %d)", trashListLimit, len(tasks))
+	}
+	text += fmt.Sprintf(":\n\nЗадачи удаляются навсегда через %s после удаления.\n"+
+		"Нажми на задачу, чтобы восстановить её 👇", formatOffset(b.trashRetention))
+	b.sendWithInlineKeyboard(chatID, text, trashKeyboard(tasks, b.loc))
+}
+
+// handleRestore — возвращает задачу из корзины и показывает её
+func (b *Bot) handleRestore(chatID, userID int64, taskID int) {
+	task, err := b.storage.RestoreTask(b.space(userID), taskID, userID)
+	if errors.Is(err, ErrTaskNotFound) {
+		b.sendText(chatID, "⚠️ Этой задачи нет в корзине: её уже восстановили или удалили навсегда.")
+		return
+	}
+	if err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.sendText(chatID, fmt.Sprintf("♻️ Задача «%s» восстановлена.", task.Title))
+	b.showTaskDetail(chatID, userID, taskID)
 }
 
 // ============================================================
@@ -364,6 +2853,51 @@ func (b *Bot) sendText(chatID int64, text string) {
 	b.send(msg)
 }
 
+// sendStorageError — сообщает пользователю об ошибке хранилища
+// "Задача не найдена" и ошибки проверки показываем как есть,
+// остальные ошибки логируем
+func (b *Bot) sendStorageError(chatID int64, err error) {
+	if errors.Is(err, ErrTaskNotFound) {
+		b.sendText(chatID, "⚠️ Задача не найдена.")
+		return
+	}
+	// Ошибки проверки данных понятны пользователю как есть
+	if errors.Is(err, ErrItemNotFound) {
+		b.sendText(chatID, "⚠️ Пункт чек-листа не найден.")
+		return
+	}
+	if errors.Is(err, ErrProjectNotFound) {
+		b.sendText(chatID, "⚠️ Проект не найден.")
+		return
+	}
+	if errors.Is(err, ErrWorkspaceNotFound) {
+		b.sendText(chatID, "⚠️ Команда не найдена или ты в ней больше не состоишь.")
+		return
+	}
+	if errors.Is(err, ErrForbidden) {
+		b.sendText(chatID, "🔒 Не получится: "+strings.TrimPrefix(err.Error(), ErrForbidden.Error()+": ")+
+			".\nРоли в команде — /team → «👑 Участники»")
+		return
+	}
+	if errors.Is(err, ErrEmptyTitle) || errors.Is(err, ErrBadPriority) || errors.Is(err, ErrBadTag) ||
+		errors.Is(err, ErrEmptyItem) || errors.Is(err, ErrChecklistFull) ||
+		errors.Is(err, ErrSelfDependency) || errors.Is(err, ErrDependencyCycle) ||
+		errors.Is(err, ErrBadRecurrence) || errors.Is(err, ErrUnknownStatus) ||
+		errors.Is(err, ErrBadTransition) || errors.Is(err, ErrBadWorkflow) || errors.Is(err, ErrStatusInUse) ||
+		errors.Is(err, ErrProjectArchived) || errors.Is(err, ErrBadProjectName) ||
+		errors.Is(err, ErrBadInvite) || errors.Is(err, ErrBadWorkspaceName) || errors.Is(err, ErrBadAssignee) ||
+		errors.Is(err, ErrBadRole) || errors.Is(err, ErrMemberNotFound) || errors.Is(err, ErrOwnerLeave) ||
+		errors.Is(err, ErrEmptyComment) || errors.Is(err, ErrCommentTooLong) ||
+		errors.Is(err, ErrAttachmentNotFound) || errors.Is(err, ErrTooManyAttachments) ||
+		errors.Is(err, ErrTimeEntryNotFound) || errors.Is(err, ErrTimerRunning) || errors.Is(err, ErrTimerNotRunning) ||
+		errors.Is(err, ErrTimerTaskDone) || errors.Is(err, ErrTooManyTimeEntries) || errors.Is(err, ErrBadTimeEntry) {
+		b.sendText(chatID, "⚠️ "+err.Error())
+		return
+	}
+	log.Printf("❌ Ошибка хранилища: %v", err)
+	b.sendText(chatID, "⚠️ Не удалось обратиться к хранилищу. Попробуй позже.")
+}
+
 // sendWithInlineKeyboard — отправляет текст с inline-клавиатурой
 func (b *Bot) sendWithInlineKeyboard(chatID int64, text string, keyboard tgbotapi.InlineKeyboardMarkup) {
 	msg := tgbotapi.NewMessage(chatID, text)
@@ -382,6 +2916,21 @@ func (b *Bot) parseID(data, prefix string) int {
 	return id
 }
 
+// parseTwoIDs — извлекает два числовых ID из callback data
+// Например: parseTwoIDs("chk_42_3", "chk_") вернёт 42, 3
+func parseTwoIDs(data, prefix string) (int, int, bool) {
+	first, second, found := strings.Cut(strings.TrimPrefix(data, prefix), "_")
+	if !found {
+		return 0, 0, false
+	}
+	a, err1 := strconv.Atoi(first)
+	b, err2 := strconv.Atoi(second)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return a, b, true
+}
+
 // escapeMarkdown — экранирует спецсимволы для формата MarkdownV2
 // Telegram требует экранировать эти символы обратным слешем
 func escapeMarkdown(text string) string {
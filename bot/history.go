@@ -0,0 +1,228 @@
+package bot
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ============================================================
+// ИСТОРИЯ ЗАДАЧИ (журнал событий)
+//
+// Каждое изменение задачи записывается неизменяемым событием:
+// когда, кто и что поменял. Так можно узнать, когда задачу
+// взяли в работу и когда закончили, хотя в самой задаче
+// хранится только текущий статус.
+//
+// События не удаляются вместе с задачей: удалённая задача сначала
+// попадает в корзину ("deleted"), оттуда её можно вернуть ("restored"),
+// а при очистке корзины последним в истории будет событие "purged".
+//
+// Служебные поля напоминаний (RemindersSent, SnoozedUntil)
+// в историю не попадают — это не правки пользователя.
+// ============================================================
+
+// Типы событий
+const (
+	EventCreated       = "created"        // Задача создана
+	EventStatusChanged = "status_changed" // Изменён статус (From/To — коды статусов)
+	EventEdited        = "edited"         // Изменено поле Field
+	EventDeleted       = "deleted"        // Задача убрана в корзину
+	EventRestored      = "restored"       // Задача возвращена из корзины
+	EventPurged        = "purged"         // Задача удалена навсегда при очистке корзины (Actor = 0)
+)
+
+// Поля задачи в событиях EventEdited
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldDeadline    = "deadline"   // From/To — время в RFC 3339 (UTC)
+	FieldPriority    = "priority"   // From/To — коды приоритетов
+	FieldTags        = "tags"       // From/To — "#матан #работа"
+	FieldRecurrence  = "recurrence" // From/To — правило RRULE
+	FieldChecklist   = "checklist"  // Один пункт: добавлен (To), удалён (From) или отмечен
+	FieldBlockedBy   = "blocked_by" // Один блокер "#3": добавлен (To) или убран (From)
+	FieldAssignee    = "assignee"   // From/To — ID исполнителя ("" — не назначен)
+)
+
+// fieldLabels — названия полей для сообщений бота
+var fieldLabels = map[string]string{
+	FieldTitle:       "название",
+	FieldDescription: "описание",
+	FieldDeadline:    "срок",
+	FieldPriority:    "приоритет",
+	FieldTags:        "теги",
+	FieldRecurrence:  "повтор",
+	FieldChecklist:   "чек-лист",
+	FieldBlockedBy:   "зависимости",
+	FieldAssignee:    "исполнитель",
+}
+
+// TaskEvent — одно событие в истории задачи
+type TaskEvent struct {
+	ID     int       `json:"id"`              // Номер события в истории задачи (1, 2, 3...)
+	TaskID int       `json:"task_id"`         // Задача, с которой произошло событие
+	Type   string    `json:"type"`            // Тип события (EventCreated...)
+	At     time.Time `json:"at"`              // Когда
+	Actor  int64     `json:"actor"`           // Кто (ID пользователя Telegram; в задачах команды — участник, а не пространство)
+	Field  string    `json:"field,omitempty"` // Что изменено (для EventEdited)
+	From   string    `json:"from,omitempty"`  // Значение до изменения
+	To     string    `json:"to,omitempty"`    // Значение после изменения
+}
+
+// taskEvents сравнивает задачу до и после изменения и возвращает события
+// before == nil — задача создана, after == nil — удалена.
+// ID событиям выдаёт хранилище.
+func taskEvents(before, after *Task, actor int64, at time.Time) []TaskEvent {
+	switch {
+	case before == nil && after == nil:
+		return nil
+	case before == nil:
+		return []TaskEvent{{TaskID: after.ID, Type: EventCreated, At: at, Actor: actor}}
+	case after == nil:
+		return []TaskEvent{{TaskID: before.ID, Type: EventDeleted, At: at, Actor: actor}}
+	}
+
+	var events []TaskEvent
+	add := func(typ, field, from, to string) {
+		events = append(events, TaskEvent{
+			TaskID: after.ID, Type: typ, At: at, Actor: actor, Field: field, From: from, To: to,
+		})
+	}
+	edit := func(field, from, to string) {
+		if from != to {
+			add(EventEdited, field, from, to)
+		}
+	}
+
+	if before.Status != after.Status {
+		add(EventStatusChanged, "", before.Status, after.Status)
+	}
+	edit(FieldTitle, before.Title, after.Title)
+	edit(FieldDescription, before.Description, after.Description)
+	edit(FieldDeadline, eventTime(before.Deadline), eventTime(after.Deadline))
+	edit(FieldPriority, before.Priority, after.Priority)
+	edit(FieldTags, formatTags(before.Tags), formatTags(after.Tags))
+	edit(FieldRecurrence, recurrenceText(before.Recurrence), recurrenceText(after.Recurrence))
+	edit(FieldAssignee, assigneeText(before.Assignee), assigneeText(after.Assignee))
+
+	// Чек-лист и блокеры — по событию на каждый изменившийся пункт
+	for _, old := range before.Checklist {
+		i := slices.IndexFunc(after.Checklist, func(item ChecklistItem) bool { return item.ID == old.ID })
+		if i < 0 {
+			events = append(events, checklistEvent(actor, after.ID, &old, nil, at))
+		} else if after.Checklist[i] != old {
+			events = append(events, checklistEvent(actor, after.ID, &old, &after.Checklist[i], at))
+		}
+	}
+	for _, item := range after.Checklist {
+		if !slices.ContainsFunc(before.Checklist, func(old ChecklistItem) bool { return old.ID == item.ID }) {
+			events = append(events, checklistEvent(actor, after.ID, nil, &item, at))
+		}
+	}
+	for _, id := range before.BlockedBy {
+		if !slices.Contains(after.BlockedBy, id) {
+			add(EventEdited, FieldBlockedBy, fmt.Sprintf("#%d", id), "")
+		}
+	}
+	for _, id := range after.BlockedBy {
+		if !slices.Contains(before.BlockedBy, id) {
+			add(EventEdited, FieldBlockedBy, "", fmt.Sprintf("#%d", id))
+		}
+	}
+	return events
+}
+
+// restoredEvent — задачу вернули из корзины
+func restoredEvent(taskID int, actor int64, at time.Time) TaskEvent {
+	return TaskEvent{TaskID: taskID, Type: EventRestored, At: at, Actor: actor}
+}
+
+// purgedEvent — задачу удалила очистка корзины, а не пользователь
+func purgedEvent(taskID int, at time.Time) TaskEvent {
+	return TaskEvent{TaskID: taskID, Type: EventPurged, At: at}
+}
+
+// eventTime — время для From/To ("" — не задано)
+func eventTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// checklistEvent — событие об одном пункте чек-листа
+// before == nil — пункт добавлен, after == nil — удалён
+func checklistEvent(actor int64, taskID int, before, after *ChecklistItem, at time.Time) TaskEvent {
+	e := TaskEvent{TaskID: taskID, Type: EventEdited, At: at, Actor: actor, Field: FieldChecklist}
+	if before != nil {
+		e.From = checklistEventText(*before)
+	}
+	if after != nil {
+		e.To = checklistEventText(*after)
+	}
+	return e
+}
+
+// checklistEventText — пункт чек-листа для From/To: "✅ Найти литературу"
+func checklistEventText(item ChecklistItem) string {
+	if item.Done {
+		return "✅ " + item.Text
+	}
+	return "⬜ " + item.Text
+}
+
+// Describe — событие по-русски для сообщений: "статус: 🆕 Новая → 🔄 В работе"
+// loc — часовой пояс для сроков, wf — набор статусов пользователя (подписи),
+// members — участники пространства (имена исполнителей, может быть nil)
+func (e TaskEvent) Describe(loc *time.Location, wf Workflow, members []Member) string {
+	switch e.Type {
+	case EventCreated:
+		return "задача создана"
+	case EventDeleted:
+		return "задача удалена в корзину"
+	case EventRestored:
+		return "задача восстановлена из корзины"
+	case EventPurged:
+		return "задача удалена навсегда (корзина очищена)"
+	case EventStatusChanged:
+		return fmt.Sprintf("статус: %s → %s", wf.Label(e.From), wf.Label(e.To))
+	}
+
+	label, ok := fieldLabels[e.Field]
+	if !ok {
+		label = e.Field
+	}
+	from, to := e.eventValue(e.From, loc, members), e.eventValue(e.To, loc, members)
+	switch {
+	case e.From == "":
+		return fmt.Sprintf("%s: добавлено «%s»", label, to)
+	case e.To == "":
+		return fmt.Sprintf("%s: убрано «%s»", label, from)
+	}
+	return fmt.Sprintf("%s: «%s» → «%s»", label, from, to)
+}
+
+// eventValue — значение From/To в читаемом виде
+func (e TaskEvent) eventValue(value string, loc *time.Location, members []Member) string {
+	switch e.Field {
+	case FieldDeadline:
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return formatDeadline(t, loc)
+		}
+	case FieldPriority:
+		return PriorityLabel(value)
+	case FieldRecurrence:
+		if r, err := ParseRecurrence(value, nil); err == nil {
+			return r.Describe()
+		}
+	case FieldDescription:
+		return truncate(value, 50)
+	case FieldAssignee:
+		if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return MemberLabel(members, id)
+		}
+	}
+	return value
+}
@@ -2,6 +2,9 @@ package bot
 
 import (
 	"fmt"
+	"slices"
+	"sort"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -66,17 +69,29 @@ func mainMenuKeyboard(webAppURL string) interface{} {
 
 // ============================================================
 // СПИСОК ЗАДАЧ — Inline-клавиатура
-// Каждая задача отображается как кнопка с её статусом и названием
+// Каждая задача отображается как кнопка со значком приоритета,
+// статусом и названием
+//
+// Порядок: сначала просроченные (🔥), затем остальные по приоритету
+// (при равном приоритете — ближайший срок выше), выполненные — в конце
 //
 // При нажатии отправляется callback с данными "task_<ID>"
+// Задачи из blocked (ждут невыполненные задачи) помечены ⛔
+//
+// Внизу — кнопка фильтра по тегу (если у задач есть теги),
+// а в отфильтрованном списке (tag != "") — кнопка сброса фильтра
+// В списке проекта (project != nil) — новая задача в этот проект
+// и архив; в конце всегда — возврат к проектам
+// Подписи статусов берутся из набора пользователя wf
 // ============================================================
-func taskListKeyboard(tasks []Task) tgbotapi.InlineKeyboardMarkup {
+func taskListKeyboard(tasks []Task, blocked map[int]bool, wf Workflow, now time.Time, loc *time.Location, tag string, hasTags bool, project *Project) tgbotapi.InlineKeyboardMarkup {
 	// Создаём срез рядов кнопок
 	var rows [][]tgbotapi.InlineKeyboardButton
 
+	sortTasksForList(tasks, now)
+
 	for _, task := range tasks {
-		// Текст кнопки: "статус | название"
-		buttonText := fmt.Sprintf("%s | %s", task.Status, task.Title)
+		buttonText := taskButtonText(task, blocked[task.ID], wf, now, loc)
 
 		// callback data — строка, которая придёт боту при нажатии
 		callbackData := fmt.Sprintf("task_%d", task.ID)
@@ -88,69 +103,535 @@ func taskListKeyboard(tasks []Task) tgbotapi.InlineKeyboardMarkup {
 		rows = append(rows, row)
 	}
 
+	switch {
+	case tag != "":
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🏷 Другой тег", "tags"),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Сбросить фильтр", "back_to_list"),
+		))
+	case hasTags:
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🏷 Фильтр по тегу", "tags"),
+		))
+	}
+
+	if project != nil {
+		newTask := tgbotapi.NewInlineKeyboardButtonData("➕ Задача сюда", fmt.Sprintf("newtask_%d", project.ID))
+		// «Входящие» в архив не убираются, а в архивный проект задачи не добавляются
+		switch {
+		case project.ID == InboxProjectID:
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(newTask))
+		case project.Archived:
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("📤 Из архива", fmt.Sprintf("unarchproj_%d", project.ID)),
+			))
+		default:
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(newTask,
+				tgbotapi.NewInlineKeyboardButtonData("🗄 В архив", fmt.Sprintf("archproj_%d", project.ID)),
+			))
+		}
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Проекты", "projects"),
+	))
+
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
+// ============================================================
+// ПРОЕКТЫ — Inline-клавиатура (первый экран «📋 Мои задачи»)
+// «📥 Входящие», проекты не из архива, все задачи сразу,
+// создание проекта и архив (если в нём что-то есть)
+// Рядом с проектом — сколько в нём задач
+// Callback data: "proj_<ID>", "back_to_list", "newproj", "projarchive"
+// ============================================================
+func projectListKeyboard(projects []Project, counts map[int]int, total int) tgbotapi.InlineKeyboardMarkup {
+	projectButton := func(p Project) []tgbotapi.InlineKeyboardButton {
+		return tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%s (%d)", p.Label(), counts[p.ID]),
+			fmt.Sprintf("proj_%d", p.ID),
+		))
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{projectButton(InboxProject())}
+	archived := 0
+	for _, p := range projects {
+		if p.Archived {
+			archived++
+			continue
+		}
+		rows = append(rows, projectButton(p))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("📋 Все задачи (%d)", total), "back_to_list"),
+	))
+
+	last := tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("➕ Новый проект", "newproj"))
+	if archived > 0 {
+		last = append(last, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗄 Архив (%d)", archived), "projarchive"))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(append(rows, last)...)
+}
+
+// archivedProjectsKeyboard — проекты в архиве (нажатие открывает проект)
+func archivedProjectsKeyboard(projects []Project, counts map[int]int) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, p := range projects {
+		if !p.Archived {
+			continue
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%s (%d)", p.Label(), counts[p.ID]),
+			fmt.Sprintf("proj_%d", p.ID),
+		)))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Проекты", "projects"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// ============================================================
+// ПЕРЕНОС В ПРОЕКТ — Inline-клавиатура
+// Проекты не из архива; текущий отмечен ✅
+// Callback data: "moveto_<задача>_<проект>"
+// ============================================================
+func moveTaskKeyboard(task Task, projects []Project) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, p := range append([]Project{InboxProject()}, projects...) {
+		if p.Archived {
+			continue
+		}
+		text := p.Label()
+		if p.ID == task.ProjectID {
+			text = "✅ " + text
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			text, fmt.Sprintf("moveto_%d_%d", task.ID, p.ID),
+		)))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("task_%d", task.ID)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// taskButtonText — текст кнопки задачи в списке:
+// "приоритет статус | название [чек-лист] (до срока)"
+func taskButtonText(task Task, blocked bool, wf Workflow, now time.Time, loc *time.Location) string {
+	text := fmt.Sprintf("%s %s | %s", PriorityIcon(task.Priority), wf.Label(task.Status), task.Title)
+	if progress := task.Progress(); progress.Total > 0 {
+		text += fmt.Sprintf(" [%s]", progress)
+	}
+	if task.Deadline != nil && !task.IsDone() {
+		text += fmt.Sprintf(" (до %s)", formatDeadlineShort(*task.Deadline, loc))
+	}
+	if task.IsOverdue(now) {
+		text = "🔥 " + text
+	}
+	if blocked {
+		text = "⛔ " + text
+	}
+	return text
+}
+
+// ============================================================
+// РЕЗУЛЬТАТЫ ПОИСКА — Inline-клавиатура
+// По searchPageSize задач на страницу (в порядке релевантности),
+// внизу — листание страниц
+// Callback data: "task_<ID>", "find_<номер страницы>"
+// ============================================================
+const searchPageSize = 8
+
+func searchResultsKeyboard(tasks []Task, blocked map[int]bool, wf Workflow, page int, now time.Time, loc *time.Location) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	start := page * searchPageSize
+	end := min(start+searchPageSize, len(tasks))
+	for _, task := range tasks[start:end] {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				taskButtonText(task, blocked[task.ID], wf, now, loc),
+				fmt.Sprintf("task_%d", task.ID),
+			),
+		))
+	}
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", fmt.Sprintf("find_%d", page-1)))
+	}
+	if end < len(tasks) {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("Дальше ▶️", fmt.Sprintf("find_%d", page+1)))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// ============================================================
+// ВЫБОР ТЕГА — Inline-клавиатура
+// Кнопки "#тег (количество задач)", по две в ряд
+// Callback data: "tag_<тег>"
+// ============================================================
+func tagPickerKeyboard(tags []TagInfo) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, tag := range tags {
+		text := fmt.Sprintf("#%s (%d)", tag.Name, tag.Count)
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(text, "tag_"+tag.Name))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Все задачи", "back_to_list"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// sortTasksForList упорядочивает задачи для списка в боте (см. taskListKeyboard)
+func sortTasksForList(tasks []Task, now time.Time) {
+	// group — номер группы задачи: чем меньше, тем выше в списке
+	group := func(t Task) int {
+		switch {
+		case t.IsDone():
+			return 2
+		case t.IsOverdue(now):
+			return 0
+		default:
+			return 1
+		}
+	}
+
+	// SliceStable сохраняет порядок создания внутри группы
+	sort.SliceStable(tasks, func(i, j int) bool {
+		gi, gj := group(tasks[i]), group(tasks[j])
+		if gi != gj {
+			return gi < gj
+		}
+		if gi < 2 {
+			return priorityLess(tasks[i], tasks[j]) // см. priority.go
+		}
+		return false
+	})
+}
+
 // ============================================================
 // ДЕЙСТВИЯ С ЗАДАЧЕЙ — Inline-клавиатура
 // Показывается при просмотре конкретной задачи
 //
+// Сверху — пункты чек-листа: нажатие отмечает пункт ("chk_<ID>_<пункт>")
+//
+// В задаче команды — ещё выбор исполнителя ("assign_<ID>")
+//
+// Кнопка обсуждения (comments — сколько в нём комментариев) идёт через
+// inSpace: по ней бот узнаёт задачу, когда пользователь отвечает на
+// сообщение с карточкой (см. replyTask в handlers.go)
+//
+// Кнопка вложений ("files_<ID>", files — сколько их) присылает файлы задачи
+//
+// Таймер ("timer_<ID>") запускает или останавливает учёт времени
+// пользователя (timer — его таймер уже идёт), "time_<ID>" — итоги
+//
 // Можешь добавить свои кнопки, например:
-// "📎 Прикрепить файл", "👤 Назначить исполнителя" и т.д.
+// "🔗 Поделиться" и т.д.
 // ============================================================
-func taskActionsKeyboard(taskID int) tgbotapi.InlineKeyboardMarkup {
-	return tgbotapi.NewInlineKeyboardMarkup(
+func taskActionsKeyboard(task Task, space int64, comments, files int, timer bool) tgbotapi.InlineKeyboardMarkup {
+	taskID := task.ID
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, item := range task.Checklist {
+		mark := "⬜"
+		if item.Done {
+			mark = "✅"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				mark+" "+truncate(item.Text, 40),
+				fmt.Sprintf("chk_%d_%d", taskID, item.ID),
+			),
+		))
+	}
+
+	// Ряд управления чек-листом
+	checklistRow := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➕ Пункт", fmt.Sprintf("chkadd_%d", taskID)),
+	)
+	if len(task.Checklist) > 0 {
+		checklistRow = append(checklistRow,
+			tgbotapi.NewInlineKeyboardButtonData("➖ Убрать пункт", fmt.Sprintf("chkremove_%d", taskID)))
+	}
+	rows = append(rows, checklistRow)
+
+	// Исполнителя можно выбрать только среди участников команды
+	statusRow := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔄 Сменить статус", fmt.Sprintf("status_%d", taskID)),
+	)
+	if IsShared(space) {
+		statusRow = append(statusRow,
+			tgbotapi.NewInlineKeyboardButtonData("👤 Исполнитель", fmt.Sprintf("assign_%d", taskID)))
+	}
+
+	// Выполненной задаче таймер не нужен (хранилище его и не запустит)
+	timeRow := tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🕒 Учёт времени", fmt.Sprintf("time_%d", taskID)),
+	)
+	if timer || !task.IsDone() {
+		timeRow = append([]tgbotapi.InlineKeyboardButton{timerButton(taskID, timer)}, timeRow...)
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(append(rows,
 		// Ряд 1: смена статуса
+		statusRow,
+		// Ряд 2: редактирование и история изменений
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
-				"🔄 Сменить статус",
-				fmt.Sprintf("status_%d", taskID),
+				"✏️ Редактировать",
+				fmt.Sprintf("edit_%d", taskID),
+			),
+			tgbotapi.NewInlineKeyboardButtonData(
+				"🕓 История",
+				fmt.Sprintf("history_%d", taskID),
 			),
 		),
-		// Ряд 2: удаление
+		// Ряд 3: обсуждение и вложения
+		tgbotapi.NewInlineKeyboardRow(
+			commentsButton(space, taskID, comments),
+			filesButton(taskID, files),
+		),
+		// Ряд 4: таймер и учёт времени
+		timeRow,
+		// Ряд 5: удаление и назад к списку задач проекта
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
 				"🗑 Удалить",
 				fmt.Sprintf("delete_%d", taskID),
 			),
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ К списку задач", fmt.Sprintf("proj_%d", task.ProjectID)),
 		),
-		// Ряд 3: назад к списку
+	)...)
+}
+
+// ============================================================
+// recurrenceKeyboard — готовые варианты повторения задачи
+// Callback data: "setrecur_<ID>_<вариант>"; "custom" — ввести правило текстом
+// ============================================================
+func recurrenceKeyboard(taskID int) tgbotapi.InlineKeyboardMarkup {
+	button := func(text, option string) tgbotapi.InlineKeyboardButton {
+		return tgbotapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("setrecur_%d_%s", taskID, option))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(button("Каждый день", "daily"), button("Каждую неделю", "weekly")),
+		tgbotapi.NewInlineKeyboardRow(button("По будням", "weekdays"), button("Каждый месяц", "monthly")),
+		tgbotapi.NewInlineKeyboardRow(button("✍️ Своё правило", "custom")),
+		tgbotapi.NewInlineKeyboardRow(button("🚫 Без повтора", "none")),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⬅️ К списку задач", "back_to_list"),
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("task_%d", taskID)),
 		),
 	)
 }
 
+// ============================================================
+// dependencyKeyboard — выбор задач, которые блокируют task
+// ✅ — задача уже среди блокеров (нажатие убирает её),
+// ⬜ — можно добавить. Выполненные задачи предлагаем,
+// только если они уже отмечены (ждать их нечего).
+// Callback data: "dep_<задача>_<блокер>"
+// ============================================================
+func dependencyKeyboard(task Task, tasks []Task) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, other := range tasks {
+		selected := slices.Contains(task.BlockedBy, other.ID)
+		if other.ID == task.ID || (other.IsDone() && !selected) {
+			continue
+		}
+		mark := "⬜"
+		if selected {
+			mark = "✅"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				mark+" "+truncate(other.Title, 40),
+				fmt.Sprintf("dep_%d_%d", task.ID, other.ID),
+			),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("task_%d", task.ID)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// ============================================================
+// УДАЛЕНИЕ ПУНКТА ЧЕК-ЛИСТА — Inline-клавиатура
+// Callback data: "chkdel_<ID>_<пункт>"
+// ============================================================
+func checklistRemoveKeyboard(task Task) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, item := range task.Checklist {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				"🗑 "+truncate(item.Text, 40),
+				fmt.Sprintf("chkdel_%d_%d", task.ID, item.ID),
+			),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("task_%d", task.ID)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// truncate обрезает текст кнопки до max символов
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
+
 // ============================================================
 // ВЫБОР СТАТУСА — Inline-клавиатура
-// Показывает все доступные статусы для задачи
+// Показывает статусы, в которые задачу можно перевести
+// из текущего
 //
-// Чтобы добавить новый статус:
-// 1. Добавь константу в storage.go (например, StatusOnHold = "⏸ На паузе")
-// 2. Добавь новый ряд кнопок ниже
-// 3. Добавь обработку в handlers.go (функция handleSetStatus)
+// Кнопки строятся из набора статусов пользователя (Workflow,
+// status.go): новый статус добавляется командой /statuses,
+// код бота менять не нужно
+// ============================================================
+func statusKeyboard(task Task, wf Workflow) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	// Callback data: "setstatus_<ID>_<код статуса>"
+	for _, status := range wf.Targets(task.Status) {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				status.Label(),
+				fmt.Sprintf("setstatus_%d_%s", task.ID, status.Code),
+			),
+		))
+	}
+
+	// Кнопка "Назад" к деталям задачи
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(
+			"⬅️ Назад",
+			fmt.Sprintf("task_%d", task.ID),
+		),
+	))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
 // ============================================================
-func statusKeyboard(taskID int) tgbotapi.InlineKeyboardMarkup {
+// workflowKeyboard — кнопки под списком статусов (/statuses)
+// ============================================================
+func workflowKeyboard() tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Изменить", "wfedit"),
+			tgbotapi.NewInlineKeyboardButtonData("↩️ По умолчанию", "wfreset"),
+		),
+	)
+}
+
+// ============================================================
+// ВЫБОР ПРИОРИТЕТА — Inline-клавиатуры
+// Кнопки строятся по общей таблице приоритетов (priority.go)
+// ============================================================
+
+// priorityKeyboard — смена приоритета задачи: "setprio_<ID>_<код>"
+func priorityKeyboard(taskID int) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, p := range Priorities {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
-				StatusNew,
-				fmt.Sprintf("setstatus_%d_new", taskID),
+				p.Label,
+				fmt.Sprintf("setprio_%d_%s", taskID, p.Code),
 			),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(
+			"⬅️ Назад",
+			fmt.Sprintf("task_%d", taskID),
 		),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// newPriorityKeyboard — приоритет при создании задачи: "newprio_<код>"
+// Две кнопки в ряд, чтобы клавиатура была компактнее
+func newPriorityKeyboard() tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, p := range Priorities {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(p.Label, "newprio_"+p.Code))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// ============================================================
+// РЕДАКТИРОВАНИЕ — Inline-клавиатура
+// Выбор поля задачи, которое нужно изменить
+// ============================================================
+func editKeyboard(taskID int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
-				StatusInProgress,
-				fmt.Sprintf("setstatus_%d_progress", taskID),
+				"📌 Название",
+				fmt.Sprintf("edittitle_%d", taskID),
+			),
+			tgbotapi.NewInlineKeyboardButtonData(
+				"📝 Описание",
+				fmt.Sprintf("editdesc_%d", taskID),
 			),
 		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
-				StatusDone,
-				fmt.Sprintf("setstatus_%d_done", taskID),
+				"⏰ Срок",
+				fmt.Sprintf("editdeadline_%d", taskID),
+			),
+			tgbotapi.NewInlineKeyboardButtonData(
+				"🚩 Приоритет",
+				fmt.Sprintf("editprio_%d", taskID),
+			),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				"🏷 Теги",
+				fmt.Sprintf("edittags_%d", taskID),
+			),
+			tgbotapi.NewInlineKeyboardButtonData(
+				"🔗 Зависимости",
+				fmt.Sprintf("deps_%d", taskID),
+			),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				"🔁 Повтор",
+				fmt.Sprintf("recur_%d", taskID),
+			),
+			tgbotapi.NewInlineKeyboardButtonData(
+				"📁 Проект",
+				fmt.Sprintf("move_%d", taskID),
 			),
 		),
-		// Кнопка "Назад" к деталям задачи
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
 				"⬅️ Назад",
@@ -172,6 +653,18 @@ func skipKeyboard() tgbotapi.InlineKeyboardMarkup {
 	)
 }
 
+// ============================================================
+// БЕЗ СРОКА — Inline-клавиатура
+// Показывается на шаге ввода дедлайна при создании задачи
+// ============================================================
+func noDeadlineKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⏭ Без срока", "nodeadline"),
+		),
+	)
+}
+
 // ============================================================
 // ПОДТВЕРЖДЕНИЕ УДАЛЕНИЯ — Inline-клавиатура
 // Защита от случайного удаления задачи
@@ -190,3 +683,319 @@ func confirmDeleteKeyboard(taskID int) tgbotapi.InlineKeyboardMarkup {
 		),
 	)
 }
+
+// ============================================================
+// deletedTaskKeyboard — кнопки под сообщением «Задача в корзине»
+// Callback data: "restore_<ID>", "trash"
+// ============================================================
+func deletedTaskKeyboard(taskID int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("↩️ Отменить", fmt.Sprintf("restore_%d", taskID)),
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Корзина", "trash"),
+		),
+	)
+}
+
+// ============================================================
+// trashKeyboard — задачи в корзине, по нажатию задача восстанавливается
+// Показываются первые trashListLimit (недавно удалённые)
+// Callback data: "restore_<ID>"
+// ============================================================
+const trashListLimit = 20
+
+func trashKeyboard(tasks []Task, loc *time.Location) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, task := range tasks[:min(len(tasks), trashListLimit)] {
+		text := fmt.Sprintf("♻️ %s (удалена %s)", task.Title, formatDeadlineShort(trashTime(task), loc))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("restore_%d", task.ID)),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// ============================================================
+// reminderKeyboard — кнопки под напоминанием о сроке
+// Кнопка «Выполнено» переводит задачу в первый завершающий статус,
+// доступный из текущего (если такого перехода нет — кнопки нет)
+//
+// Напоминание живёт дольше текущего выбора пространства, поэтому
+// все кнопки идут через inSpace — и для личных задач, и для задач
+// команды (space — пространство задачи)
+// ============================================================
+func reminderKeyboard(task Task, wf Workflow, space int64) tgbotapi.InlineKeyboardMarkup {
+	var first []tgbotapi.InlineKeyboardButton
+	if done, ok := wf.DoneTarget(task.Status); ok {
+		first = append(first, tgbotapi.NewInlineKeyboardButtonData(
+			done.Label(),
+			inSpace(space, fmt.Sprintf("setstatus_%d_%s", task.ID, done.Code)),
+		))
+	}
+	first = append(first, tgbotapi.NewInlineKeyboardButtonData(
+		"⏰ Отложить на 1 час",
+		inSpace(space, fmt.Sprintf("snooze_%d", task.ID)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(
+		first,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				"📌 Открыть задачу",
+				inSpace(space, fmt.Sprintf("task_%d", task.ID)),
+			),
+		),
+	)
+}
+
+// ============================================================
+// openTaskKeyboard — кнопка «Открыть» под уведомлением о задаче
+// (через inSpace: уведомление может прийти из другого пространства)
+// ============================================================
+func openTaskKeyboard(taskID int, space int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("📌 Открыть задачу", inSpace(space, fmt.Sprintf("task_%d", taskID))),
+	))
+}
+
+// ============================================================
+// inSpace — callback data, которая сначала переключает пользователя
+// в пространство space, а затем выполняет обычное действие data:
+// "in_<пространство>_<callback>" (например, "in_-3_setstatus_5_done").
+// Нужна кнопкам, которые живут дольше текущего выбора пространства:
+// уведомлениям и спискам задач из разных пространств
+// ============================================================
+func inSpace(space int64, data string) string {
+	return fmt.Sprintf("in_%d_%s", space, data)
+}
+
+// ============================================================
+// ИСПОЛНИТЕЛЬ — Inline-клавиатура выбора участника команды
+// Текущий исполнитель отмечен ✅
+// Callback data: "assignto_<ID задачи>_<ID пользователя>" (0 — снять)
+// ============================================================
+func assigneeKeyboard(task Task, members []Member) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, m := range members {
+		text := "👤 " + m.Label()
+		if m.UserID == task.Assignee {
+			text = "✅ " + m.Label()
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("assignto_%d_%d", task.ID, m.UserID)),
+		))
+	}
+	if task.Assignee != NoAssignee {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🚫 Снять исполнителя", fmt.Sprintf("assignto_%d_%d", task.ID, NoAssignee)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("task_%d", task.ID)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// ============================================================
+// УЧАСТНИКИ КОМАНДЫ — Inline-клавиатура со списком участников
+// Кнопки только у тех, кому userID может поменять роль
+// ============================================================
+func membersKeyboard(w Workspace, userID int64) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, m := range w.Members {
+		if !slices.ContainsFunc(Roles, func(r Role) bool { return r != m.Role && w.CanSetRole(userID, m.UserID, r) }) {
+			continue
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ "+m.Label(), fmt.Sprintf("wsrole_%d", m.UserID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", fmt.Sprintf("ws_%d", w.ID)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// ============================================================
+// РОЛЬ УЧАСТНИКА — Inline-клавиатура выбора роли
+// Только роли, которые userID может назначить; «владелец» — передача команды
+// ============================================================
+func roleKeyboard(w Workspace, userID int64, member Member) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, role := range Roles {
+		if role == member.Role || !w.CanSetRole(userID, member.UserID, role) {
+			continue
+		}
+		text := role.Label()
+		if role == RoleOwner {
+			text = "👑 Передать команду"
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("setrole_%d_%s", member.UserID, role)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Назад", "wsmembers"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// ============================================================
+// НАЗНАЧЕННАЯ ЗАДАЧА — Inline-клавиатура под сообщением исполнителю
+// Статусы, в которые можно перейти, и «Открыть задачу»; все кнопки
+// через inSpace, чтобы работали, какое бы пространство ни было выбрано
+// ============================================================
+func assignedTaskKeyboard(task Task, wf Workflow, space int64) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, status := range wf.Targets(task.Status) {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				status.Label(),
+				inSpace(space, fmt.Sprintf("setstatus_%d_%s", task.ID, status.Code)),
+			),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("📌 Открыть задачу", inSpace(space, fmt.Sprintf("task_%d", task.ID))),
+		commentsButton(space, task.ID, 0),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// commentsButton — кнопка обсуждения задачи ("in_<пространство>_comments_<ID>")
+// Она же — метка, по которой ответ на сообщение становится комментарием
+func commentsButton(space int64, taskID, count int) tgbotapi.InlineKeyboardButton {
+	text := "💬 Обсуждение"
+	if count > 0 {
+		text = fmt.Sprintf("💬 Обсуждение (%d)", count)
+	}
+	return tgbotapi.NewInlineKeyboardButtonData(text, inSpace(space, fmt.Sprintf("comments_%d", taskID)))
+}
+
+// filesButton — кнопка вложений задачи ("files_<ID>")
+func filesButton(taskID, count int) tgbotapi.InlineKeyboardButton {
+	text := "📎 Файлы"
+	if count > 0 {
+		text = fmt.Sprintf("📎 Файлы (%d)", count)
+	}
+	return tgbotapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("files_%d", taskID))
+}
+
+// ============================================================
+// ВЛОЖЕНИЯ — Inline-клавиатура под списком файлов и подтверждением
+// «приложено»: обсуждение (оно же метка задачи для ответа файлом,
+// см. commentsButton) и открыть задачу
+// ============================================================
+func attachmentsKeyboard(space int64, taskID int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		commentsButton(space, taskID, 0),
+		tgbotapi.NewInlineKeyboardButtonData("📌 Открыть задачу", inSpace(space, fmt.Sprintf("task_%d", taskID))),
+	))
+}
+
+// timerButton — запустить таймер или остановить идущий
+func timerButton(taskID int, running bool) tgbotapi.InlineKeyboardButton {
+	text := "⏱ Запустить таймер"
+	if running {
+		text = "⏹ Остановить таймер"
+	}
+	return tgbotapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("timer_%d", taskID))
+}
+
+// ============================================================
+// УЧЁТ ВРЕМЕНИ — Inline-клавиатура под итогами:
+// добавить время вручную ("timeadd_<ID>") и открыть задачу
+// ============================================================
+func timeKeyboard(taskID int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("➕ Добавить время", fmt.Sprintf("timeadd_%d", taskID)),
+		tgbotapi.NewInlineKeyboardButtonData("📌 Открыть задачу", fmt.Sprintf("task_%d", taskID)),
+	))
+}
+
+// ============================================================
+// ПЕРЕСЛАННОЕ СООБЩЕНИЕ — кнопка под предложением сделать из него
+// задачу (см. forward.go). Пространство зашито в кнопку: задача
+// появится там, где пользователь был, когда переслал сообщение
+// ============================================================
+func forwardTaskKeyboard(space int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ Создать задачу", inSpace(space, "fwdtask")),
+	))
+}
+
+// ============================================================
+// ОБСУЖДЕНИЕ — Inline-клавиатура под комментариями и уведомлениями
+// о них: обновить ленту и открыть задачу
+// ============================================================
+func commentsKeyboard(space int64, taskID int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🔄 Обновить", inSpace(space, fmt.Sprintf("comments_%d", taskID))),
+		tgbotapi.NewInlineKeyboardButtonData("📌 Открыть задачу", inSpace(space, fmt.Sprintf("task_%d", taskID))),
+	))
+}
+
+// ============================================================
+// НАЗНАЧЕНО МНЕ / МНОЙ — Inline-клавиатура со списком задач
+// Задачи из разных пространств: перед задачей команды — её название,
+// callback data — inSpace(пространство, "task_<ID>")
+// names — названия команд по ID, workflows — наборы статусов
+// ============================================================
+func assignedListKeyboard(tasks []SpaceTask, names map[int64]string, workflows map[int64]Workflow, now time.Time, loc *time.Location) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, st := range tasks {
+		text := taskButtonText(st.Task, false, workflows[st.Space], now, loc)
+		if name, ok := names[st.Space]; ok {
+			text = "👥 " + name + " · " + text
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(truncate(text, 60), inSpace(st.Space, fmt.Sprintf("task_%d", st.Task.ID))),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🙋 Назначено мне", "assigned_me"),
+		tgbotapi.NewInlineKeyboardButtonData("📤 Назначено мной", "assigned_byme"),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// ============================================================
+// ПРОСТРАНСТВА — Inline-клавиатура (/team)
+// Личные задачи и команды пользователя; текущее пространство отмечено ✅
+// Callback data: "ws_<ID>" (личные — ID пользователя), "newws",
+// "assigned_me", "assigned_byme", а для текущей команды — "wsinvite"
+// и "wsleave_<ID>"
+// ============================================================
+func workspacesKeyboard(userID int64, workspaces []Workspace, active int64) tgbotapi.InlineKeyboardMarkup {
+	button := func(text string, space int64) []tgbotapi.InlineKeyboardButton {
+		if space == active {
+			text = "✅ " + text
+		}
+		return tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("ws_%d", space)))
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{button("👤 Личные задачи", userID)}
+	for _, w := range workspaces {
+		rows = append(rows, button(fmt.Sprintf("👥 %s (%d)", w.Name, len(w.Members)), w.ID))
+	}
+	if IsShared(active) {
+		rows = append(rows,
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("👑 Участники", "wsmembers"),
+				tgbotapi.NewInlineKeyboardButtonData("🔗 Пригласить", "wsinvite"),
+			),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🚪 Выйти", fmt.Sprintf("wsleave_%d", active)),
+			),
+		)
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🙋 Назначено мне", "assigned_me"),
+			tgbotapi.NewInlineKeyboardButtonData("📤 Назначено мной", "assigned_byme"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➕ Новая команда", "newws"),
+		),
+	)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -0,0 +1,396 @@
+package bot
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ============================================================
+// МИГРАЦИИ СХЕМЫ БАЗЫ ДАННЫХ
+//
+// Каждая миграция — пронумерованный набор SQL-команд "вверх".
+// При запуске SQLStore применяет все миграции, которых ещё нет
+// в таблице schema_migrations, строго по возрастанию номера.
+//
+// Правила:
+// - уже выпущенные миграции НЕ меняем — только добавляем новые в конец
+// - SQL должен работать и в PostgreSQL, и в SQLite
+// - время храним в колонках TIMESTAMP и всегда в UTC
+// ============================================================
+type migration struct {
+	Version    int      // Номер миграции (1, 2, 3...)
+	Name       string   // Короткое описание
+	Statements []string // SQL-команды (выполняются в одной транзакции)
+}
+
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "задачи и счётчики ID",
+		Statements: []string{
+			// Счётчик ID задач для каждого пользователя
+			// (ID задачи уникален только в пределах пользователя,
+			// как и в callback data "task_<ID>")
+			`CREATE TABLE task_counters (
+				user_id BIGINT  PRIMARY KEY,
+				next_id INTEGER NOT NULL
+			)`,
+			`CREATE TABLE tasks (
+				user_id     BIGINT    NOT NULL,
+				id          INTEGER   NOT NULL,
+				title       TEXT      NOT NULL,
+				description TEXT      NOT NULL DEFAULT '',
+				status      TEXT      NOT NULL,
+				created_at  TIMESTAMP NOT NULL,
+				PRIMARY KEY (user_id, id)
+			)`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "коды статусов вместо подписей",
+		Statements: []string{
+			`UPDATE tasks SET status = 'new'      WHERE status = '🆕 Новая'`,
+			`UPDATE tasks SET status = 'progress' WHERE status = '🔄 В работе'`,
+			`UPDATE tasks SET status = 'done'     WHERE status = '✅ Выполнена'`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "срок выполнения задачи",
+		Statements: []string{
+			`ALTER TABLE tasks ADD COLUMN deadline TIMESTAMP`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "состояние напоминаний",
+		Statements: []string{
+			// Ключи отправленных напоминаний через запятую ("24h0m0s,overdue")
+			`ALTER TABLE tasks ADD COLUMN reminders_sent TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE tasks ADD COLUMN snoozed_until TIMESTAMP`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "приоритет задачи",
+		Statements: []string{
+			`ALTER TABLE tasks ADD COLUMN priority TEXT NOT NULL DEFAULT 'normal'`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "теги задач",
+		Statements: []string{
+			// Теги пользователя (имя без "#", в нижнем регистре)
+			`CREATE TABLE tags (
+				user_id BIGINT NOT NULL,
+				name    TEXT   NOT NULL,
+				PRIMARY KEY (user_id, name)
+			)`,
+			// Связь задач и тегов (многие-ко-многим)
+			`CREATE TABLE task_tags (
+				user_id BIGINT  NOT NULL,
+				task_id INTEGER NOT NULL,
+				tag     TEXT    NOT NULL,
+				PRIMARY KEY (user_id, task_id, tag),
+				FOREIGN KEY (user_id, task_id) REFERENCES tasks (user_id, id) ON DELETE CASCADE,
+				FOREIGN KEY (user_id, tag) REFERENCES tags (user_id, name) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX task_tags_by_tag ON task_tags (user_id, tag)`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "чек-листы задач",
+		Statements: []string{
+			// id пункта уникален в пределах задачи, порядок показа — по id
+			`CREATE TABLE checklist_items (
+				user_id BIGINT  NOT NULL,
+				task_id INTEGER NOT NULL,
+				id      INTEGER NOT NULL,
+				text    TEXT    NOT NULL,
+				done    BOOLEAN NOT NULL DEFAULT FALSE,
+				PRIMARY KEY (user_id, task_id, id),
+				FOREIGN KEY (user_id, task_id) REFERENCES tasks (user_id, id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "зависимости между задачами",
+		Statements: []string{
+			// task_id заблокирована задачей blocker_id того же пользователя;
+			// при удалении любой из задач связь удаляется вместе с ней
+			`CREATE TABLE task_dependencies (
+				user_id    BIGINT  NOT NULL,
+				task_id    INTEGER NOT NULL,
+				blocker_id INTEGER NOT NULL,
+				PRIMARY KEY (user_id, task_id, blocker_id),
+				FOREIGN KEY (user_id, task_id) REFERENCES tasks (user_id, id) ON DELETE CASCADE,
+				FOREIGN KEY (user_id, blocker_id) REFERENCES tasks (user_id, id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX task_dependencies_blocker ON task_dependencies (user_id, blocker_id)`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "повторяющиеся задачи",
+		Statements: []string{
+			// Правило в виде RRULE ("FREQ=WEEKLY;BYDAY=MO"), '' — не повторяется
+			`ALTER TABLE tasks ADD COLUMN recurrence TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "история изменений задач",
+		Statements: []string{
+			// id события уникален в пределах задачи (1, 2, 3...).
+			// Внешнего ключа на tasks нет намеренно: история удалённой
+			// задачи остаётся в базе
+			`CREATE TABLE task_events (
+				user_id    BIGINT    NOT NULL,
+				task_id    INTEGER   NOT NULL,
+				id         INTEGER   NOT NULL,
+				type       TEXT      NOT NULL,
+				at         TIMESTAMP NOT NULL,
+				actor      BIGINT    NOT NULL,
+				field      TEXT      NOT NULL DEFAULT '',
+				from_value TEXT      NOT NULL DEFAULT '',
+				to_value   TEXT      NOT NULL DEFAULT '',
+				PRIMARY KEY (user_id, task_id, id)
+			)`,
+		},
+	},
+	{
+		Version: 11,
+		Name:    "корзина удалённых задач",
+		Statements: []string{
+			// NULL — задача не удалена; иначе — когда её убрали в корзину
+			`ALTER TABLE tasks ADD COLUMN deleted_at TIMESTAMP`,
+			`CREATE INDEX tasks_deleted_at ON tasks (deleted_at)`,
+		},
+	},
+	{
+		Version: 12,
+		Name:    "наборы статусов пользователей",
+		Statements: []string{
+			// Категория статуса копируется в задачу, чтобы напоминания
+			// и зависимости не зависели от набора статусов (см. status.go)
+			`ALTER TABLE tasks ADD COLUMN status_category TEXT NOT NULL DEFAULT 'todo'`,
+			`UPDATE tasks SET status_category = 'doing' WHERE status = 'progress'`,
+			`UPDATE tasks SET status_category = 'done' WHERE status = 'done'`,
+			// statuses — набор в JSON ({"statuses": [...]}); нет строки — статусы по умолчанию
+			`CREATE TABLE workflows (
+				user_id  BIGINT PRIMARY KEY,
+				statuses TEXT   NOT NULL
+			)`,
+		},
+	},
+	{
+		Version: 13,
+		Name:    "проекты",
+		Statements: []string{
+			// id проекта уникален в пределах пользователя (как у задач)
+			`CREATE TABLE projects (
+				user_id    BIGINT    NOT NULL,
+				id         INTEGER   NOT NULL,
+				name       TEXT      NOT NULL,
+				emoji      TEXT      NOT NULL DEFAULT '',
+				archived   BOOLEAN   NOT NULL DEFAULT FALSE,
+				created_at TIMESTAMP NOT NULL,
+				PRIMARY KEY (user_id, id)
+			)`,
+			// 0 — «Входящие»: такого проекта в таблице нет,
+			// поэтому внешнего ключа на projects не делаем
+			`ALTER TABLE tasks ADD COLUMN project_id INTEGER NOT NULL DEFAULT 0`,
+			`CREATE INDEX tasks_project ON tasks (user_id, project_id)`,
+		},
+	},
+	{
+		Version: 14,
+		Name:    "общие пространства",
+		Statements: []string{
+			// id отрицательный и служит user_id для задач пространства
+			// (см. workspaces.go), поэтому старые таблицы не меняются
+			`CREATE TABLE workspaces (
+				id          BIGINT    PRIMARY KEY,
+				name        TEXT      NOT NULL,
+				owner_id    BIGINT    NOT NULL,
+				invite_code TEXT      NOT NULL UNIQUE,
+				created_at  TIMESTAMP NOT NULL
+			)`,
+			`CREATE TABLE workspace_members (
+				workspace_id BIGINT    NOT NULL REFERENCES workspaces (id),
+				user_id      BIGINT    NOT NULL,
+				joined_at    TIMESTAMP NOT NULL,
+				PRIMARY KEY (workspace_id, user_id)
+			)`,
+			`CREATE INDEX workspace_members_user ON workspace_members (user_id)`,
+		},
+	},
+	{
+		Version: 15,
+		Name:    "исполнители задач",
+		Statements: []string{
+			// Имя участника из Telegram — для списка при назначении
+			`ALTER TABLE workspace_members ADD COLUMN name TEXT NOT NULL DEFAULT ''`,
+			// 0 — исполнитель не назначен (см. assignees.go)
+			`ALTER TABLE tasks ADD COLUMN assignee BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE tasks ADD COLUMN assigned_by BIGINT NOT NULL DEFAULT 0`,
+			`CREATE INDEX tasks_assignee ON tasks (assignee)`,
+		},
+	},
+	{
+		Version: 16,
+		Name:    "роли участников команд",
+		Statements: []string{
+			// Коды ролей — см. permissions.go; создатель команды — владелец
+			`ALTER TABLE workspace_members ADD COLUMN role TEXT NOT NULL DEFAULT 'member'`,
+			`UPDATE workspace_members SET role = 'owner'
+			 WHERE user_id = (SELECT owner_id FROM workspaces WHERE workspaces.id = workspace_members.workspace_id)`,
+		},
+	},
+	{
+		Version: 17,
+		Name:    "комментарии к задачам",
+		Statements: []string{
+			// id комментария уникален в пределах задачи (1, 2, 3...);
+			// комментарии удаляются вместе с задачей (в отличие от истории)
+			`CREATE TABLE task_comments (
+				user_id    BIGINT    NOT NULL,
+				task_id    INTEGER   NOT NULL,
+				id         INTEGER   NOT NULL,
+				author     BIGINT    NOT NULL,
+				text       TEXT      NOT NULL,
+				created_at TIMESTAMP NOT NULL,
+				PRIMARY KEY (user_id, task_id, id),
+				FOREIGN KEY (user_id, task_id) REFERENCES tasks (user_id, id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		Version: 18,
+		Name:    "вложения задач",
+		Statements: []string{
+			// Файл — либо file_id в Telegram, либо ключ файла на диске (blob_key),
+			// см. attachments.go; как и комментарии, удаляются вместе с задачей
+			`CREATE TABLE task_attachments (
+				user_id    BIGINT    NOT NULL,
+				task_id    INTEGER   NOT NULL,
+				id         INTEGER   NOT NULL,
+				kind       TEXT      NOT NULL,
+				name       TEXT      NOT NULL,
+				mime_type  TEXT      NOT NULL DEFAULT '',
+				size       BIGINT    NOT NULL DEFAULT 0,
+				caption    TEXT      NOT NULL DEFAULT '',
+				file_id    TEXT      NOT NULL DEFAULT '',
+				blob_key   TEXT      NOT NULL DEFAULT '',
+				author     BIGINT    NOT NULL,
+				created_at TIMESTAMP NOT NULL,
+				PRIMARY KEY (user_id, task_id, id),
+				FOREIGN KEY (user_id, task_id) REFERENCES tasks (user_id, id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		Version: 19,
+		Name:    "источник задачи из пересланного сообщения",
+		Statements: []string{
+			// source_date IS NULL — задача создана не из сообщения (см. forward.go)
+			`ALTER TABLE tasks ADD COLUMN source_sender TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE tasks ADD COLUMN source_sender_id BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE tasks ADD COLUMN source_chat TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE tasks ADD COLUMN source_date TIMESTAMP`,
+		},
+	},
+	{
+		Version: 20,
+		Name:    "учёт времени",
+		Statements: []string{
+			// end_at IS NULL — таймер ещё идёт (см. timetracking.go);
+			// member — кто работал, user_id — пространство задачи
+			`CREATE TABLE time_entries (
+				user_id  BIGINT    NOT NULL,
+				task_id  INTEGER   NOT NULL,
+				id       INTEGER   NOT NULL,
+				member   BIGINT    NOT NULL,
+				start_at TIMESTAMP NOT NULL,
+				end_at   TIMESTAMP,
+				manual   BOOLEAN   NOT NULL DEFAULT FALSE,
+				note     TEXT      NOT NULL DEFAULT '',
+				PRIMARY KEY (user_id, task_id, id),
+				FOREIGN KEY (user_id, task_id) REFERENCES tasks (user_id, id) ON DELETE CASCADE
+			)`,
+		},
+	},
+}
+
+// migrate применяет все ещё не применённые миграции
+func migrate(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER   PRIMARY KEY,
+		name       TEXT      NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("создание schema_migrations: %w", err)
+	}
+
+	// Узнаём, какие миграции уже применены
+	applied := make(map[int]bool)
+	rows, err := db.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("чтение schema_migrations: %w", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("миграция %d (%s): %w", m.Version, m.Name, err)
+		}
+		log.Printf("🗄  Применена миграция %d: %s", m.Version, m.Name)
+	}
+	return nil
+}
+
+// applyMigration выполняет одну миграцию в транзакции
+// и записывает её номер в schema_migrations
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // После Commit ничего не делает
+
+	for _, stmt := range m.Statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+		m.Version, m.Name, time.Now().UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrationsNumbering(t *testing.T) {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("миграция %q под номером %d, ожидался %d: номера идут подряд с 1", m.Name, m.Version, i+1)
+		}
+		if len(m.Statements) == 0 {
+			t.Errorf("миграция %d (%s) без SQL-команд", m.Version, m.Name)
+		}
+	}
+}
+
+// appliedVersions — номера миграций из schema_migrations по возрастанию
+func appliedVersions(t *testing.T, db *sql.DB) []int {
+	t.Helper()
+	rows, err := db.Query(`SELECT version FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		t.Fatalf("чтение schema_migrations: %v", err)
+	}
+	defer rows.Close()
+	var versions []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+func checkAllApplied(t *testing.T, db *sql.DB) {
+	t.Helper()
+	versions := appliedVersions(t, db)
+	if len(versions) != len(migrations) {
+		t.Fatalf("применены миграции %v, ожидались все %d", versions, len(migrations))
+	}
+	for i, v := range versions {
+		if v != i+1 {
+			t.Fatalf("применены миграции %v, ожидались 1..%d", versions, len(migrations))
+		}
+	}
+}
+
+func TestMigrateFromZeroSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite на пустой базе: %v", err)
+	}
+	checkAllApplied(t, s.db)
+
+	// Схема рабочая: задача проходит через основные таблицы
+	task, err := s.AddTask(testUser, testUser, TaskDraft{Title: "Сдать отчёт", Tags: []string{"учёба"}})
+	if err != nil {
+		t.Fatalf("AddTask: %v", err)
+	}
+	if _, err := s.AddChecklistItem(testUser, task.ID, testUser, "Черновик"); err != nil {
+		t.Fatalf("AddChecklistItem: %v", err)
+	}
+	if _, err := s.UpdateStatus(testUser, task.ID, testUser, StatusDone); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	s.Close()
+
+	// Повторный запуск ничего не применяет заново и не теряет данные
+	s, err = OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("повторное открытие: %v", err)
+	}
+	defer s.Close()
+	checkAllApplied(t, s.db)
+	got, err := s.GetTask(testUser, task.ID)
+	if err != nil {
+		t.Fatalf("GetTask после перезапуска: %v", err)
+	}
+	if got.Title != "Сдать отчёт" || !got.IsDone() || len(got.Checklist) != 1 {
+		t.Fatalf("задача после перезапуска: %+v", got)
+	}
+}
+
+func TestMigrateFromEveryVersionSQLite(t *testing.T) {
+	// База, остановившаяся на любой миграции, доводится до последней
+	for applied := 1; applied < len(migrations); applied++ {
+		path := filepath.Join(t.TempDir(), "tasks.db")
+		db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved := migrations
+		migrations = saved[:applied]
+		err = migrate(db)
+		migrations = saved
+		db.Close()
+		if err != nil {
+			t.Fatalf("миграции 1..%d: %v", applied, err)
+		}
+
+		s, err := OpenSQLite(path)
+		if err != nil {
+			t.Fatalf("доводка с версии %d: %v", applied, err)
+		}
+		checkAllApplied(t, s.db)
+		s.Close()
+	}
+}
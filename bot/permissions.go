@@ -0,0 +1,241 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ============================================================
+// РОЛИ И ПРАВА В КОМАНДАХ
+//
+// У каждого участника команды есть роль (Member.Role):
+//   владелец      — всё, в том числе передать команду другому
+//   администратор — удаляет задачи, настраивает статусы и проекты,
+//                   приглашает и меняет роли участников и наблюдателей
+//   участник      — создаёт и меняет задачи, но не удаляет их
+//   наблюдатель   — только смотрит
+//
+// Authorize — одна проверка для бота и API: «можно ли пользователю
+// это сделать в пространстве». В личном пространстве можно всё.
+// Бот проверяет права кнопок и шагов диалога (см. callbackPermissions
+// в handlers.go), API — каждого маршрута (см. api/router.go)
+// ============================================================
+
+// Role — роль участника команды
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+	RoleViewer Role = "viewer"
+)
+
+// Roles — все роли от старшей к младшей (в этом порядке идут кнопки)
+var Roles = []Role{RoleOwner, RoleAdmin, RoleMember, RoleViewer}
+
+// Ошибки ролей и прав
+var (
+	ErrForbidden      = errors.New("недостаточно прав")
+	ErrBadRole        = errors.New("неизвестная роль (допустимые: owner, admin, member, viewer)")
+	ErrMemberNotFound = errors.New("участник не найден")
+	ErrOwnerLeave     = errors.New("владелец не может выйти из команды: сначала передай её другому участнику")
+)
+
+// ParseRole проверяет код роли ("admin" → RoleAdmin)
+func ParseRole(code string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(code)))
+	if role.rank() == 0 {
+		return "", ErrBadRole
+	}
+	return role, nil
+}
+
+// Label — название роли для сообщений
+func (r Role) Label() string {
+	switch r {
+	case RoleOwner:
+		return "Владелец"
+	case RoleAdmin:
+		return "Администратор"
+	case RoleMember:
+		return "Участник"
+	case RoleViewer:
+		return "Наблюдатель"
+	}
+	return string(r)
+}
+
+// rank — старшинство роли (0 — неизвестная роль)
+func (r Role) rank() int {
+	switch r {
+	case RoleOwner:
+		return 4
+	case RoleAdmin:
+		return 3
+	case RoleMember:
+		return 2
+	case RoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// Permission — действие, на которое нужно право
+type Permission int
+
+const (
+	PermView   Permission = iota // Смотреть задачи, проекты и историю
+	PermEdit                     // Создавать и менять задачи (статус, чек-лист, исполнитель...)
+	PermDelete                   // Удалять задачи и возвращать их из корзины
+	PermManage                   // Статусы, проекты, приглашения и роли участников
+)
+
+// minRole — младшая роль, которой разрешено действие
+func (p Permission) minRole() Role {
+	switch p {
+	case PermView:
+		return RoleViewer
+	case PermEdit:
+		return RoleMember
+	}
+	return RoleAdmin
+}
+
+// text — действие для сообщения об отказе («наблюдатель не может ...»)
+func (p Permission) text() string {
+	switch p {
+	case PermView:
+		return "смотреть задачи"
+	case PermEdit:
+		return "менять задачи"
+	case PermDelete:
+		return "удалять задачи"
+	}
+	return "настраивать команду"
+}
+
+// Can — разрешено ли роли действие
+func (r Role) Can(p Permission) bool {
+	return r.rank() > 0 && r.rank() >= p.minRole().rank()
+}
+
+// forbidden — ErrForbidden с пояснением, чего роли нельзя
+func forbidden(role Role, p Permission) error {
+	return fmt.Errorf("%w: %s не может %s", ErrForbidden, strings.ToLower(role.Label()), p.text())
+}
+
+// Role возвращает роль участника (false — не состоит в пространстве)
+// Пространства из журнала до появления ролей хранят участников без
+// роли: создатель — владелец, остальные — участники
+func (w Workspace) Role(userID int64) (Role, bool) {
+	m, ok := w.Member(userID)
+	switch {
+	case !ok:
+		return "", false
+	case m.Role != "":
+		return m.Role, true
+	case userID == w.OwnerID:
+		return RoleOwner, true
+	}
+	return RoleMember, true
+}
+
+// withRoles проставляет роли участникам без роли (см. Role)
+func (w Workspace) withRoles() Workspace {
+	w = w.clone()
+	for i := range w.Members {
+		w.Members[i].Role, _ = w.Role(w.Members[i].UserID)
+	}
+	return w
+}
+
+// Authorize проверяет, что пользователь может сделать действие perm
+// в пространстве space. Не участник — ErrWorkspaceNotFound (как в
+// CheckAccess), не хватает роли — ErrForbidden с пояснением
+func Authorize(store TaskStore, userID, space int64, perm Permission) error {
+	if space == userID {
+		return nil
+	}
+	if !IsShared(space) {
+		return ErrWorkspaceNotFound
+	}
+	w, err := store.GetWorkspace(space)
+	if err != nil {
+		return err
+	}
+	role, ok := w.Role(userID)
+	if !ok {
+		return ErrWorkspaceNotFound
+	}
+	if !role.Can(perm) {
+		return forbidden(role, perm)
+	}
+	return nil
+}
+
+// setRole меняет роль участника userID по просьбе actorID (общая
+// логика для всех хранилищ). Администратор назначает только участников
+// и наблюдателей; владелец — кого угодно, а назначив другого владельцем,
+// сам становится администратором
+func setRole(w *Workspace, actorID, userID int64, role Role) error {
+	if role.rank() == 0 {
+		return ErrBadRole
+	}
+	actor, ok := w.Role(actorID)
+	if !ok {
+		return ErrWorkspaceNotFound
+	}
+	target, ok := w.Role(userID)
+	if !ok {
+		return ErrMemberNotFound
+	}
+	if !actor.Can(PermManage) {
+		return forbidden(actor, PermManage)
+	}
+	switch {
+	case actorID == userID:
+		return fmt.Errorf("%w: свою роль поменять нельзя", ErrForbidden)
+	case role == RoleOwner && actor != RoleOwner:
+		return fmt.Errorf("%w: передать команду может только владелец", ErrForbidden)
+	case actor != RoleOwner && (target.rank() >= actor.rank() || role.rank() >= actor.rank()):
+		return fmt.Errorf("%w: администратор назначает только участников и наблюдателей", ErrForbidden)
+	}
+
+	*w = w.withRoles()
+	if role == RoleOwner {
+		for i := range w.Members {
+			if w.Members[i].UserID == actorID {
+				w.Members[i].Role = RoleAdmin
+			}
+		}
+		w.OwnerID = userID
+	}
+	for i := range w.Members {
+		if w.Members[i].UserID == userID {
+			w.Members[i].Role = role
+		}
+	}
+	return nil
+}
+
+// CanSetRole — может ли actorID назначить участнику userID роль role
+// (для кнопок и подсказок; сама смена роли — TaskStore.SetMemberRole)
+func (w Workspace) CanSetRole(actorID, userID int64, role Role) bool {
+	c := w.clone()
+	return setRole(&c, actorID, userID, role) == nil
+}
+
+// checkLeave проверяет, что пользователь может выйти из пространства:
+// владелец уходит последним или после передачи команды
+func checkLeave(w Workspace, userID int64) error {
+	role, ok := w.Role(userID)
+	if !ok {
+		return ErrWorkspaceNotFound
+	}
+	if role == RoleOwner && len(w.Members) > 1 {
+		return ErrOwnerLeave
+	}
+	return nil
+}
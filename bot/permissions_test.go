@@ -0,0 +1,193 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+)
+
+// Участники тестовой команды
+const (
+	ownerID    int64 = 1
+	adminID    int64 = 2
+	memberID   int64 = 3
+	viewerID   int64 = 4
+	strangerID int64 = 9
+)
+
+// testWorkspace — команда со всеми ролями
+func testWorkspace() Workspace {
+	return Workspace{
+		ID:      -1,
+		OwnerID: ownerID,
+		Members: []Member{
+			{UserID: ownerID, Role: RoleOwner},
+			{UserID: adminID, Role: RoleAdmin},
+			{UserID: memberID, Role: RoleMember},
+			{UserID: viewerID, Role: RoleViewer},
+		},
+	}
+}
+
+func TestRoleCan(t *testing.T) {
+	// Какие действия разрешены роли: смотреть, менять, удалять, настраивать
+	matrix := map[Role][4]bool{
+		RoleOwner:  {true, true, true, true},
+		RoleAdmin:  {true, true, true, true},
+		RoleMember: {true, true, false, false},
+		RoleViewer: {true, false, false, false},
+		"":         {false, false, false, false},
+		"guest":    {false, false, false, false},
+	}
+	perms := []Permission{PermView, PermEdit, PermDelete, PermManage}
+
+	for role, want := range matrix {
+		for i, perm := range perms {
+			if got := role.Can(perm); got != want[i] {
+				t.Errorf("Role(%q).Can(%s) = %v, ожидалось %v", role, perm.text(), got, want[i])
+			}
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	store := NewStorage()
+	w, err := store.CreateWorkspace(Member{UserID: ownerID}, "Команда")
+	if err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	for _, id := range []int64{adminID, memberID, viewerID} {
+		if _, err := store.JoinWorkspace(Member{UserID: id}, w.InviteCode); err != nil {
+			t.Fatalf("JoinWorkspace: %v", err)
+		}
+	}
+	for id, role := range map[int64]Role{adminID: RoleAdmin, viewerID: RoleViewer} {
+		if _, err := store.SetMemberRole(ownerID, w.ID, id, role); err != nil {
+			t.Fatalf("SetMemberRole: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		userID int64
+		space  int64
+		perm   Permission
+		want   error
+	}{
+		{"своё личное пространство", memberID, memberID, PermManage, nil},
+		{"чужое личное пространство", memberID, adminID, PermView, ErrWorkspaceNotFound},
+		{"не участник команды", strangerID, w.ID, PermView, ErrWorkspaceNotFound},
+		{"несуществующая команда", ownerID, w.ID - 100, PermView, ErrWorkspaceNotFound},
+		{"наблюдатель смотрит", viewerID, w.ID, PermView, nil},
+		{"наблюдатель меняет", viewerID, w.ID, PermEdit, ErrForbidden},
+		{"участник меняет", memberID, w.ID, PermEdit, nil},
+		{"участник удаляет", memberID, w.ID, PermDelete, ErrForbidden},
+		{"участник настраивает", memberID, w.ID, PermManage, ErrForbidden},
+		{"администратор удаляет", adminID, w.ID, PermDelete, nil},
+		{"администратор настраивает", adminID, w.ID, PermManage, nil},
+		{"владелец настраивает", ownerID, w.ID, PermManage, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Authorize(store, tt.userID, tt.space, tt.perm); !errors.Is(err, tt.want) {
+				t.Fatalf("Authorize = %v, ожидалось %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetRole(t *testing.T) {
+	tests := []struct {
+		name           string
+		actor, target  int64
+		role           Role
+		want           error
+		owner          int64 // Владелец после смены (0 — прежний)
+		actorRoleAfter Role  // Роль actor после смены ("" — не проверяем)
+	}{
+		{name: "владелец назначает администратора", actor: ownerID, target: memberID, role: RoleAdmin},
+		{name: "владелец понижает администратора", actor: ownerID, target: adminID, role: RoleViewer},
+		{name: "владелец передаёт команду", actor: ownerID, target: memberID, role: RoleOwner,
+			owner: memberID, actorRoleAfter: RoleAdmin},
+		{name: "администратор назначает наблюдателя", actor: adminID, target: memberID, role: RoleViewer},
+		{name: "администратор назначает участника", actor: adminID, target: viewerID, role: RoleMember},
+		{name: "администратор назначает администратора", actor: adminID, target: memberID, role: RoleAdmin, want: ErrForbidden},
+		{name: "администратор меняет роль владельца", actor: adminID, target: ownerID, role: RoleMember, want: ErrForbidden},
+		{name: "администратор передаёт команду", actor: adminID, target: memberID, role: RoleOwner, want: ErrForbidden},
+		{name: "участник меняет роли", actor: memberID, target: viewerID, role: RoleMember, want: ErrForbidden},
+		{name: "наблюдатель меняет роли", actor: viewerID, target: memberID, role: RoleViewer, want: ErrForbidden},
+		{name: "своя роль", actor: ownerID, target: ownerID, role: RoleAdmin, want: ErrForbidden},
+		{name: "неизвестная роль", actor: ownerID, target: memberID, role: "boss", want: ErrBadRole},
+		{name: "не участник назначает", actor: strangerID, target: memberID, role: RoleViewer, want: ErrWorkspaceNotFound},
+		{name: "назначают не участника", actor: ownerID, target: strangerID, role: RoleViewer, want: ErrMemberNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := testWorkspace()
+			err := setRole(&w, tt.actor, tt.target, tt.role)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("setRole = %v, ожидалось %v", err, tt.want)
+			}
+			if err != nil {
+				if w.OwnerID != ownerID {
+					t.Fatalf("после отказа владелец сменился на %d", w.OwnerID)
+				}
+				return
+			}
+			if got, _ := w.Role(tt.target); got != tt.role {
+				t.Errorf("роль %d = %s, ожидалась %s", tt.target, got, tt.role)
+			}
+			wantOwner := ownerID
+			if tt.owner != 0 {
+				wantOwner = tt.owner
+			}
+			if w.OwnerID != wantOwner {
+				t.Errorf("владелец %d, ожидался %d", w.OwnerID, wantOwner)
+			}
+			if tt.actorRoleAfter != "" {
+				if got, _ := w.Role(tt.actor); got != tt.actorRoleAfter {
+					t.Errorf("роль %d после передачи = %s, ожидалась %s", tt.actor, got, tt.actorRoleAfter)
+				}
+			}
+			owners := 0
+			for _, m := range w.Members {
+				if m.Role == RoleOwner {
+					owners++
+				}
+			}
+			if owners != 1 {
+				t.Errorf("владельцев %d, должен быть один", owners)
+			}
+		})
+	}
+}
+
+func TestCheckLeave(t *testing.T) {
+	alone := Workspace{ID: -1, OwnerID: ownerID, Members: []Member{{UserID: ownerID, Role: RoleOwner}}}
+	// Команда из журнала до появления ролей: владелец — по OwnerID
+	legacy := Workspace{ID: -1, OwnerID: ownerID, Members: []Member{{UserID: ownerID}, {UserID: memberID}}}
+
+	tests := []struct {
+		name   string
+		w      Workspace
+		userID int64
+		want   error
+	}{
+		{"владелец с участниками", testWorkspace(), ownerID, ErrOwnerLeave},
+		{"владелец последним", alone, ownerID, nil},
+		{"администратор", testWorkspace(), adminID, nil},
+		{"наблюдатель", testWorkspace(), viewerID, nil},
+		{"не участник", testWorkspace(), strangerID, ErrWorkspaceNotFound},
+		{"владелец без роли в старой команде", legacy, ownerID, ErrOwnerLeave},
+		{"участник без роли в старой команде", legacy, memberID, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkLeave(tt.w, tt.userID); !errors.Is(err, tt.want) {
+				t.Fatalf("checkLeave = %v, ожидалось %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"sort"
+	"time"
+)
+
+// ============================================================
+// Приоритеты задач
+//
+// Устроены так же, как статусы (status.go): в задаче хранится
+// короткий код, а значок и подпись берутся из таблицы Priorities.
+// Таблица общая для бота, клавиатур и HTTP API.
+// ============================================================
+const (
+	PriorityLow    = "low"    // Низкий
+	PriorityNormal = "normal" // Обычный (по умолчанию)
+	PriorityHigh   = "high"   // Высокий
+	PriorityUrgent = "urgent" // Срочный
+)
+
+// PriorityInfo — код приоритета, значок и подпись
+type PriorityInfo struct {
+	Code  string `json:"code"`  // Код, который хранится в Task.Priority
+	Icon  string `json:"icon"`  // Значок для списка задач
+	Label string `json:"label"` // Подпись со значком
+}
+
+// Priorities — все приоритеты от низкого к срочному
+// Порядок в таблице задаёт и порядок сортировки
+var Priorities = []PriorityInfo{
+	{Code: PriorityLow, Icon: "🔵", Label: "🔵 Низкий"},
+	{Code: PriorityNormal, Icon: "⚪", Label: "⚪ Обычный"},
+	{Code: PriorityHigh, Icon: "🟠", Label: "🟠 Высокий"},
+	{Code: PriorityUrgent, Icon: "🔴", Label: "🔴 Срочный"},
+}
+
+// priorityInfo находит приоритет по коду
+// Пустой код (задачи, созданные до появления приоритетов) считается обычным
+func priorityInfo(code string) (PriorityInfo, bool) {
+	if code == "" {
+		code = PriorityNormal
+	}
+	for _, p := range Priorities {
+		if p.Code == code {
+			return p, true
+		}
+	}
+	return PriorityInfo{Code: code, Label: code}, false
+}
+
+// PriorityLabel возвращает подпись приоритета по коду
+func PriorityLabel(code string) string {
+	p, _ := priorityInfo(code)
+	return p.Label
+}
+
+// PriorityIcon возвращает значок приоритета по коду
+func PriorityIcon(code string) string {
+	p, _ := priorityInfo(code)
+	return p.Icon
+}
+
+// IsValidPriority проверяет, что код приоритета существует
+func IsValidPriority(code string) bool {
+	_, ok := priorityInfo(code)
+	return ok && code != ""
+}
+
+// PriorityCodes возвращает список всех кодов (для сообщений об ошибках)
+func PriorityCodes() []string {
+	codes := make([]string, len(Priorities))
+	for i, p := range Priorities {
+		codes[i] = p.Code
+	}
+	return codes
+}
+
+// priorityRank — место приоритета в таблице: чем больше, тем важнее
+func priorityRank(code string) int {
+	p, _ := priorityInfo(code)
+	for i := range Priorities {
+		if Priorities[i].Code == p.Code {
+			return i
+		}
+	}
+	return 0
+}
+
+// ============================================================
+// СОРТИРОВКА ПО ПРИОРИТЕТУ
+// Используется и в списке задач бота, и в GET /api/tasks?sort=priority
+// ============================================================
+
+// SortByPriority упорядочивает задачи: сначала важнее, при равном
+// приоритете — с более ранним сроком, задачи без срока — ниже
+func SortByPriority(tasks []Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return priorityLess(tasks[i], tasks[j])
+	})
+}
+
+// priorityLess — a стоит выше b при сортировке по приоритету
+func priorityLess(a, b Task) bool {
+	if ra, rb := priorityRank(a.Priority), priorityRank(b.Priority); ra != rb {
+		return ra > rb
+	}
+	return deadlineLess(a.Deadline, b.Deadline)
+}
+
+// deadlineLess — более ранний срок выше, без срока — в конце
+func deadlineLess(a, b *time.Time) bool {
+	switch {
+	case a == nil:
+		return false
+	case b == nil:
+		return true
+	default:
+		return a.Before(*b)
+	}
+}
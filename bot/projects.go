@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ============================================================
+// ПРОЕКТЫ
+//
+// Проект (список) группирует задачи: «📚 Учёба», «💼 Работа».
+// Задача лежит ровно в одном проекте (Task.ProjectID); проект 0 —
+// «📥 Входящие»: он есть у всех, его нельзя переименовать или удалить,
+// и туда попадают новые задачи, если проект не выбран.
+//
+// ID проекта уникален только в пределах пользователя — как ID задачи,
+// поэтому callback data вида "proj_<ID>" короткие.
+//
+// Проект можно убрать в архив: он пропадает из списка проектов,
+// а его задачи остаются на месте. Удалённый проект отдаёт свои
+// задачи во «Входящие».
+// ============================================================
+
+// InboxProjectID — ID проекта «Входящие» (задачи без проекта)
+const InboxProjectID = 0
+
+// maxProjectNameRunes — ограничение длины названия проекта
+// (название попадает в кнопку и в заголовок списка)
+const maxProjectNameRunes = 40
+
+// defaultProjectEmoji — значок проекта, если пользователь его не выбрал
+const defaultProjectEmoji = "📁"
+
+// Ошибки проектов
+var (
+	ErrProjectNotFound = errors.New("проект не найден")
+	ErrProjectArchived = errors.New("проект в архиве: сначала верни его из архива")
+	ErrBadProjectName  = fmt.Errorf("название проекта — от 1 до %d символов", maxProjectNameRunes)
+)
+
+// Project — проект пользователя
+type Project struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Emoji     string    `json:"emoji"`
+	Archived  bool      `json:"archived"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InboxProject — встроенный проект «Входящие» (в хранилище не лежит)
+func InboxProject() Project {
+	return Project{ID: InboxProjectID, Name: "Входящие", Emoji: "📥"}
+}
+
+// Label — подпись проекта с эмодзи: "📚 Учёба"
+func (p Project) Label() string {
+	return p.Emoji + " " + p.Name
+}
+
+// ProjectDraft — данные для создания проекта
+type ProjectDraft struct {
+	Name  string
+	Emoji string // "" — значок по умолчанию
+}
+
+// Validate проверяет, что из черновика можно создать проект
+func (d ProjectDraft) Validate() error {
+	return validateProjectName(d.Name)
+}
+
+// newProject создаёт проект из черновика (ID выдаёт хранилище)
+func (d ProjectDraft) newProject(id int, now time.Time) Project {
+	emoji := strings.TrimSpace(d.Emoji)
+	if emoji == "" {
+		emoji = defaultProjectEmoji
+	}
+	return Project{ID: id, Name: strings.TrimSpace(d.Name), Emoji: emoji, CreatedAt: now}
+}
+
+// ProjectPatch — частичное изменение проекта (nil — поле не меняется)
+type ProjectPatch struct {
+	Name     *string
+	Emoji    *string
+	Archived *bool
+}
+
+// Validate проверяет, что изменение допустимо
+func (p ProjectPatch) Validate() error {
+	if p.Name != nil {
+		return validateProjectName(*p.Name)
+	}
+	return nil
+}
+
+// apply применяет изменение к проекту
+func (p ProjectPatch) apply(project *Project) {
+	if p.Name != nil {
+		project.Name = strings.TrimSpace(*p.Name)
+	}
+	if p.Emoji != nil {
+		project.Emoji = strings.TrimSpace(*p.Emoji)
+		if project.Emoji == "" {
+			project.Emoji = defaultProjectEmoji
+		}
+	}
+	if p.Archived != nil {
+		project.Archived = *p.Archived
+	}
+}
+
+// validateProjectName проверяет название проекта
+func validateProjectName(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxProjectNameRunes {
+		return ErrBadProjectName
+	}
+	return nil
+}
+
+// ParseProjectName разбирает "📚 Учёба" на значок и название
+// Значок — первое слово, если в нём нет букв и цифр
+func ParseProjectName(text string) ProjectDraft {
+	text = strings.TrimSpace(text)
+	first, rest, found := strings.Cut(text, " ")
+	if found && !strings.ContainsFunc(first, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) {
+		return ProjectDraft{Name: strings.TrimSpace(rest), Emoji: first}
+	}
+	return ProjectDraft{Name: text}
+}
+
+// checkProject проверяет, что в проект projectID можно положить задачу:
+// он существует и не в архиве (projects — проекты пользователя)
+func checkProject(projects []Project, projectID int) error {
+	if projectID == InboxProjectID {
+		return nil
+	}
+	project, ok := findProject(projects, projectID)
+	if !ok {
+		return ErrProjectNotFound
+	}
+	if project.Archived {
+		return ErrProjectArchived
+	}
+	return nil
+}
+
+// findProject ищет проект по ID (Входящие тоже находит)
+func findProject(projects []Project, projectID int) (Project, bool) {
+	if projectID == InboxProjectID {
+		return InboxProject(), true
+	}
+	for _, p := range projects {
+		if p.ID == projectID {
+			return p, true
+		}
+	}
+	return Project{}, false
+}
+
+// nextProjectID — ID нового проекта: на единицу больше максимального
+// (как у пунктов чек-листа)
+func nextProjectID(projects []Project) int {
+	id := 1
+	for _, p := range projects {
+		if p.ID >= id {
+			id = p.ID + 1
+		}
+	}
+	return id
+}
+
+// FilterByProject возвращает задачи проекта
+// Используется и в списке задач бота, и в GET /api/tasks?project=
+func FilterByProject(tasks []Task, projectID int) []Task {
+	var result []Task
+	for _, task := range tasks {
+		if task.ProjectID == projectID {
+			result = append(result, task)
+		}
+	}
+	return result
+}
+
+// CountByProject считает задачи в каждом проекте (для счётчиков в боте и API)
+func CountByProject(tasks []Task) map[int]int {
+	counts := make(map[int]int)
+	for _, task := range tasks {
+		counts[task.ProjectID]++
+	}
+	return counts
+}
@@ -0,0 +1,410 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// ПОВТОРЯЮЩИЕСЯ ЗАДАЧИ
+//
+// Правило повторения записывается в стиле RRULE (RFC 5545):
+//   FREQ=DAILY;INTERVAL=3                — каждые 3 дня
+//   FREQ=WEEKLY;BYDAY=MO,WE              — по понедельникам и средам
+//   FREQ=MONTHLY;UNTIL=20261231          — каждый месяц до конца 2026 года
+//   FREQ=WEEKLY;TZID=Europe/Moscow       — дни считаются по Москве
+//
+// Когда повторяющаяся задача выполнена, хранилище создаёт
+// следующую: с тем же названием, тегами и чек-листом (пункты
+// снова не отмечены) и со сроком в следующую дату по правилу.
+// Правило "переезжает" в новую задачу, поэтому повторное
+// завершение старой задачи не плодит копии.
+// ============================================================
+
+// Частота повторения (FREQ)
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+// ErrBadRecurrence — правило повторения не удалось разобрать
+var ErrBadRecurrence = errors.New("неверное правило повторения")
+
+// recurrenceKeys — части RRULE, которые понимает ParseRecurrence
+var recurrenceKeys = []string{"FREQ", "INTERVAL", "BYDAY", "UNTIL", "TZID"}
+
+// maxRecurrenceInterval — "каждые 1000 дней" почти наверняка опечатка
+const maxRecurrenceInterval = 365
+
+// Дни недели в RRULE (BYDAY) и в сообщениях бота
+var (
+	rruleWeekdays = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+	shortWeekdays = [...]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"}
+)
+
+// Recurrence — правило повторения задачи
+// В JSON записывается строкой RRULE (см. MarshalText)
+type Recurrence struct {
+	Freq     string         // FreqDaily / FreqWeekly / FreqMonthly
+	Interval int            // Каждые N дней/недель/месяцев (1 — каждый)
+	Weekdays []time.Weekday // Для FreqWeekly: дни недели (пусто — день недели срока)
+	Until    *time.Time     // Последний день повторений (полночь в Location), nil — бессрочно
+	Location *time.Location // Часовой пояс, в котором считаются дни (nil — UTC)
+}
+
+// ParseRecurrence разбирает правило вида "FREQ=WEEKLY;BYDAY=MO,WE"
+// loc — часовой пояс по умолчанию, если в правиле нет TZID
+func ParseRecurrence(rule string, loc *time.Location) (*Recurrence, error) {
+	fields := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimSpace(rule), ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrBadRecurrence, part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if !slices.Contains(recurrenceKeys, key) {
+			// Молча выбросить COUNT или BYMONTHDAY нельзя: задача
+			// повторялась бы не так, как просили
+			return nil, fmt.Errorf("%w: %s не поддерживается", ErrBadRecurrence, key)
+		}
+		fields[key] = strings.TrimSpace(value)
+	}
+
+	r := &Recurrence{Freq: strings.ToUpper(fields["FREQ"]), Interval: 1, Location: loc}
+	if tz, ok := fields["TZID"]; ok {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("%w: неизвестный часовой пояс %q", ErrBadRecurrence, tz)
+		}
+		r.Location = l
+	}
+	if v, ok := fields["INTERVAL"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: INTERVAL=%s", ErrBadRecurrence, v)
+		}
+		r.Interval = n
+	}
+	if v, ok := fields["BYDAY"]; ok {
+		for _, code := range strings.Split(strings.ToUpper(v), ",") {
+			day := slices.Index(rruleWeekdays[:], strings.TrimSpace(code))
+			if day < 0 {
+				return nil, fmt.Errorf("%w: BYDAY=%s", ErrBadRecurrence, v)
+			}
+			r.Weekdays = append(r.Weekdays, time.Weekday(day))
+		}
+	}
+	if v, ok := fields["UNTIL"]; ok {
+		// Берём только дату: "20261231" или "20261231T235959Z"
+		if len(v) < 8 {
+			return nil, fmt.Errorf("%w: UNTIL=%s", ErrBadRecurrence, v)
+		}
+		until, err := time.ParseInLocation("20060102", v[:8], r.location())
+		if err != nil {
+			return nil, fmt.Errorf("%w: UNTIL=%s", ErrBadRecurrence, v)
+		}
+		r.Until = &until
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	r.normalize()
+	return r, nil
+}
+
+// Validate проверяет правило
+func (r Recurrence) Validate() error {
+	switch r.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly:
+	default:
+		return fmt.Errorf("%w: FREQ должен быть DAILY, WEEKLY или MONTHLY", ErrBadRecurrence)
+	}
+	if r.Interval < 1 || r.Interval > maxRecurrenceInterval {
+		return fmt.Errorf("%w: INTERVAL должен быть от 1 до %d", ErrBadRecurrence, maxRecurrenceInterval)
+	}
+	if len(r.Weekdays) > 0 && r.Freq != FreqWeekly {
+		return fmt.Errorf("%w: дни недели (BYDAY) бывают только у FREQ=WEEKLY", ErrBadRecurrence)
+	}
+	return nil
+}
+
+// normalize сортирует дни недели (с понедельника) и убирает повторы
+func (r *Recurrence) normalize() {
+	slices.SortFunc(r.Weekdays, func(a, b time.Weekday) int {
+		return mondayFirst(a) - mondayFirst(b)
+	})
+	r.Weekdays = slices.Compact(r.Weekdays)
+}
+
+// String — правило в виде RRULE
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.Weekdays) > 0 {
+		days := make([]string, len(r.Weekdays))
+		for i, d := range r.Weekdays {
+			days[i] = rruleWeekdays[d]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.In(r.location()).Format("20060102"))
+	}
+	// Пояс без имени (например, "+03:00" из JSON) по имени не загрузить
+	if r.Location != nil && r.Location.String() != "" && r.Location != time.UTC {
+		parts = append(parts, "TZID="+r.Location.String())
+	}
+	return strings.Join(parts, ";")
+}
+
+// MarshalText / UnmarshalText — в JSON правило хранится строкой RRULE
+func (r Recurrence) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Recurrence) UnmarshalText(text []byte) error {
+	parsed, err := ParseRecurrence(string(text), nil)
+	if err != nil {
+		return err
+	}
+	*r = *parsed
+	return nil
+}
+
+// Describe — правило по-русски: "каждые 2 недели по пн, ср до 31.12.2026"
+func (r Recurrence) Describe() string {
+	var text string
+	switch r.Freq {
+	case FreqDaily:
+		text = everyN(r.Interval, "каждый день", "день", "дня", "дней")
+	case FreqWeekly:
+		text = everyN(r.Interval, "каждую неделю", "неделю", "недели", "недель")
+		if len(r.Weekdays) > 0 {
+			days := make([]string, len(r.Weekdays))
+			for i, d := range r.Weekdays {
+				days[i] = shortWeekdays[d]
+			}
+			text += " по " + strings.Join(days, ", ")
+		}
+	case FreqMonthly:
+		text = everyN(r.Interval, "каждый месяц", "месяц", "месяца", "месяцев")
+	}
+	if r.Until != nil {
+		text += " до " + r.Until.In(r.location()).Format("02.01.2006")
+	}
+	return text
+}
+
+// everyN — "каждый день" для 1 и "каждые 3 дня" для остальных
+func everyN(n int, single, one, few, many string) string {
+	if n == 1 {
+		return single
+	}
+	return fmt.Sprintf("каждые %d %s", n, plural(n, one, few, many))
+}
+
+// location — часовой пояс правила (UTC, если не задан)
+func (r Recurrence) location() *time.Location {
+	if r.Location == nil {
+		return time.UTC
+	}
+	return r.Location
+}
+
+// ============================================================
+// Расчёт следующей даты
+// ============================================================
+
+// next возвращает первую дату повторения после from, которая ещё
+// не наступила к now (пропущенные повторения не создаём).
+// false — повторения закончились (UNTIL).
+func (r Recurrence) next(from, now time.Time) (time.Time, bool) {
+	t := from.In(r.location())
+	for {
+		t = r.step(t)
+		if r.Until != nil && !t.Before(r.Until.AddDate(0, 0, 1)) {
+			return time.Time{}, false
+		}
+		if t.After(now) {
+			return t, true
+		}
+	}
+}
+
+// step — следующая дата по правилу сразу после t (время суток сохраняется)
+func (r Recurrence) step(t time.Time) time.Time {
+	switch r.Freq {
+	case FreqDaily:
+		return t.AddDate(0, 0, r.Interval)
+	case FreqMonthly:
+		return addMonths(t, r.Interval)
+	}
+
+	// FreqWeekly
+	if len(r.Weekdays) == 0 {
+		return t.AddDate(0, 0, 7*r.Interval)
+	}
+	// Ищем ближайший подходящий день недели; при INTERVAL > 1
+	// подходят только недели, кратные INTERVAL от недели t
+	week := weekNumber(t)
+	for d := 1; ; d++ {
+		c := t.AddDate(0, 0, d)
+		if slices.Contains(r.Weekdays, c.Weekday()) && (weekNumber(c)-week)%r.Interval == 0 {
+			return c
+		}
+	}
+}
+
+// addMonths прибавляет месяцы; если такого числа в месяце нет,
+// берёт последний день (31 января + 1 месяц → 28/29 февраля)
+func addMonths(t time.Time, n int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, lastDay)-1)
+}
+
+// weekNumber — номер недели (с понедельника) от 1 января 1970 года
+func weekNumber(t time.Time) int {
+	year, month, day := t.Date()
+	days := int(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+	return (days + 3) / 7 // 1 января 1970 года — четверг
+}
+
+// mondayFirst — номер дня недели, если неделя начинается с понедельника
+func mondayFirst(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
+
+// nextOccurrence — следующая задача для выполненной повторяющейся
+// (без ID: его выдаёт хранилище). Срок считается от срока задачи,
+// а если его не было — от момента выполнения.
+// status — статус новых задач из набора пользователя
+func (t Task) nextOccurrence(now time.Time, status StatusDef) (Task, bool) {
+	if t.Recurrence == nil {
+		return Task{}, false
+	}
+	from := now
+	if t.Deadline != nil {
+		from = *t.Deadline
+	}
+	deadline, ok := t.Recurrence.next(from, now)
+	if !ok {
+		return Task{}, false
+	}
+
+	next := Task{
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      status.Code,
+		Category:    status.Category,
+		Priority:    t.Priority,
+		CreatedAt:   now,
+		Deadline:    &deadline,
+		Tags:        slices.Clone(t.Tags),
+		Recurrence:  t.Recurrence,
+		ProjectID:   t.ProjectID,
+		Assignee:    t.Assignee,
+		AssignedBy:  t.AssignedBy,
+	}
+	for _, item := range t.Checklist {
+		item.Done = false
+		next.Checklist = append(next.Checklist, item)
+	}
+	return next, true
+}
+
+// ============================================================
+// Правило из текста на русском (диалог в боте)
+// ============================================================
+
+// ParseRecurrenceText понимает:
+//
+//	"каждый день", "ежедневно", "каждые 3 дня"
+//	"каждую неделю", "каждые 2 недели по пн ср", "по будням"
+//	"по понедельникам и пятницам", "пн ср пт"
+//	"каждый месяц", "ежемесячно"
+//	"... до 31.12" — последний день повторений
+//	"FREQ=WEEKLY;BYDAY=MO" — правило RRULE как есть
+//
+// Дни считаются в часовом поясе now.
+func ParseRecurrenceText(text string, now time.Time) (*Recurrence, error) {
+	if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(text)), "FREQ=") {
+		return ParseRecurrence(text, now.Location())
+	}
+
+	s := normalizeDateText(text)
+	r := &Recurrence{Interval: 1, Location: now.Location()}
+
+	if rule, until, found := strings.Cut(s, " до "); found {
+		s = rule
+		date, err := ParseDeadline(until, now)
+		if err != nil {
+			return nil, err
+		}
+		year, month, day := date.Date()
+		untilDay := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+		r.Until = &untilDay
+	}
+
+	setFreq := func(freq string) error {
+		if r.Freq != "" && r.Freq != freq {
+			return ErrBadRecurrence
+		}
+		r.Freq = freq
+		return nil
+	}
+
+	words := strings.Fields(s)
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		var err error
+		switch {
+		case w == "каждый" || w == "каждую" || w == "каждые" || w == "каждое" ||
+			w == "по" || w == "и" || w == "дни" || w == "раз" || w == "в":
+			continue
+		case w == "будням" || w == "будни" || w == "будние":
+			err = setFreq(FreqWeekly)
+			r.Weekdays = append(r.Weekdays, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday)
+		case w == "ежедневно" || w == "день" || w == "дня" || w == "дней":
+			err = setFreq(FreqDaily)
+		case w == "еженедельно" || strings.HasPrefix(w, "недел"):
+			err = setFreq(FreqWeekly)
+		case w == "ежемесячно" || strings.HasPrefix(w, "месяц"):
+			err = setFreq(FreqMonthly)
+		default:
+			if n, convErr := strconv.Atoi(w); convErr == nil {
+				r.Interval = n
+				continue
+			}
+			day, ok := matchWeekday(w)
+			if !ok {
+				return nil, ErrBadRecurrence
+			}
+			err = setFreq(FreqWeekly)
+			r.Weekdays = append(r.Weekdays, day)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Сообщения Validate написаны в терминах RRULE — пользователю бота
+	// достаточно общей ошибки (бот покажет примеры)
+	if r.Validate() != nil {
+		return nil, ErrBadRecurrence
+	}
+	r.normalize()
+	return r, nil
+}
@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// onDay — дата в UTC, 09:00 (время суток при повторениях сохраняется)
+func onDay(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 9, 0, 0, 0, time.UTC)
+}
+
+func TestParseRecurrenceText(t *testing.T) {
+	now := onDay(2027, time.January, 4) // Понедельник
+
+	tests := []struct {
+		text string
+		want string // Правило в виде RRULE; "" — ошибка
+	}{
+		{"каждый день", "FREQ=DAILY"},
+		{"ежедневно", "FREQ=DAILY"},
+		{"каждые 3 дня", "FREQ=DAILY;INTERVAL=3"},
+		{"каждую неделю", "FREQ=WEEKLY"},
+		{"каждые 2 недели по пн ср", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"},
+		{"по будням", "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{"по пятницам и понедельникам", "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"вс сб вс", "FREQ=WEEKLY;BYDAY=SA,SU"},
+		{"Каждый месяц", "FREQ=MONTHLY"},
+		{"каждые 3 месяца до 31.12", "FREQ=MONTHLY;INTERVAL=3;UNTIL=20271231"},
+		{"FREQ=WEEKLY;BYDAY=MO", "FREQ=WEEKLY;BYDAY=MO"},
+		{"FREQ=MONTHLY;COUNT=3", ""},
+		{"каждый день по пн", ""}, // Дни недели — только у еженедельных
+		{"каждые 0 дней", ""},
+		{"каждые 400 дней", ""},
+		{"каждый год", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			r, err := ParseRecurrenceText(tt.text, now)
+			if tt.want == "" {
+				if !errors.Is(err, ErrBadRecurrence) {
+					t.Fatalf("ParseRecurrenceText(%q) = %v, %v; ожидалась ErrBadRecurrence", tt.text, r, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRecurrenceText(%q): %v", tt.text, err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Fatalf("ParseRecurrenceText(%q) = %s, ожидалось %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string // Правило после разбора; "" — ошибка
+	}{
+		{"FREQ=WEEKLY;BYDAY=WE,MO", "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{"freq=daily; interval=2", "FREQ=DAILY;INTERVAL=2"},
+		{"FREQ=MONTHLY;UNTIL=20271231T235959Z", "FREQ=MONTHLY;UNTIL=20271231"},
+		{"FREQ=MONTHLY;BYMONTHDAY=31", ""}, // Не поддерживаем — не выбрасываем молча
+		{"FREQ=WEEKLY;COUNT=5", ""},
+		{"FREQ=YEARLY", ""},
+		{"FREQ=DAILY;BYDAY=MO", ""},
+		{"FREQ=WEEKLY;BYDAY=XX", ""},
+		{"FREQ=DAILY;TZID=Нигде/Никак", ""},
+		{"FREQ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.rule, time.UTC)
+			if tt.want == "" {
+				if !errors.Is(err, ErrBadRecurrence) {
+					t.Fatalf("ParseRecurrence(%q) = %v, %v; ожидалась ErrBadRecurrence", tt.rule, r, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRecurrence(%q): %v", tt.rule, err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Fatalf("ParseRecurrence(%q) = %s, ожидалось %s", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      string
+		from, now time.Time
+		want      time.Time // Нулевое время — повторения закончились
+	}{
+		// Конец месяца: берём последний день, если такого числа нет
+		{"31 января → 28 февраля", "FREQ=MONTHLY",
+			onDay(2027, time.January, 31), onDay(2027, time.January, 31), onDay(2027, time.February, 28)},
+		{"31 января → 29 февраля в високосный год", "FREQ=MONTHLY",
+			onDay(2028, time.January, 31), onDay(2028, time.January, 31), onDay(2028, time.February, 29)},
+		{"31 августа → 30 сентября", "FREQ=MONTHLY",
+			onDay(2027, time.August, 31), onDay(2027, time.August, 31), onDay(2027, time.September, 30)},
+		{"31 декабря через 2 месяца → 29 февраля", "FREQ=MONTHLY;INTERVAL=2",
+			onDay(2027, time.December, 31), onDay(2027, time.December, 31), onDay(2028, time.February, 29)},
+		{"30 января → 28 февраля", "FREQ=MONTHLY",
+			onDay(2027, time.January, 30), onDay(2027, time.January, 30), onDay(2027, time.February, 28)},
+
+		// INTERVAL и пропущенные повторения
+		{"каждые 3 дня", "FREQ=DAILY;INTERVAL=3",
+			onDay(2027, time.January, 1), onDay(2027, time.January, 1), onDay(2027, time.January, 4)},
+		{"пропущенные повторения не создаются", "FREQ=DAILY;INTERVAL=3",
+			onDay(2027, time.January, 1), onDay(2027, time.January, 10).Add(time.Hour), onDay(2027, time.January, 13)},
+		{"каждые 2 недели", "FREQ=WEEKLY;INTERVAL=2",
+			onDay(2027, time.January, 4), onDay(2027, time.January, 4), onDay(2027, time.January, 18)},
+
+		// BYDAY: переход через конец недели
+		{"с пятницы на понедельник", "FREQ=WEEKLY;BYDAY=MO,WE",
+			onDay(2027, time.January, 1), onDay(2027, time.January, 1), onDay(2027, time.January, 4)},
+		{"с понедельника на среду той же недели", "FREQ=WEEKLY;BYDAY=MO,WE",
+			onDay(2027, time.January, 4), onDay(2027, time.January, 4), onDay(2027, time.January, 6)},
+		{"с воскресенья на воскресенье", "FREQ=WEEKLY;BYDAY=SU",
+			onDay(2027, time.January, 3), onDay(2027, time.January, 3), onDay(2027, time.January, 10)},
+		{"раз в 2 недели: со среды на понедельник через одну", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+			onDay(2027, time.January, 6), onDay(2027, time.January, 6), onDay(2027, time.January, 18)},
+		{"раз в 2 недели: воскресенье — конец недели, а не начало", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU",
+			onDay(2027, time.January, 4), onDay(2027, time.January, 4), onDay(2027, time.January, 10)},
+		{"через конец года", "FREQ=WEEKLY;BYDAY=MO",
+			onDay(2027, time.December, 31), onDay(2027, time.December, 31), onDay(2028, time.January, 3)},
+
+		// UNTIL — последний день включительно
+		{"последнее повторение в день UNTIL", "FREQ=DAILY;UNTIL=20270105",
+			onDay(2027, time.January, 4), onDay(2027, time.January, 4), onDay(2027, time.January, 5)},
+		{"после UNTIL повторений нет", "FREQ=DAILY;UNTIL=20270105",
+			onDay(2027, time.January, 5), onDay(2027, time.January, 5), time.Time{}},
+		{"UNTIL раньше конца месяца", "FREQ=MONTHLY;UNTIL=20270227",
+			onDay(2027, time.January, 31), onDay(2027, time.January, 31), time.Time{}},
+		{"UNTIL наступил, пока повторения были пропущены", "FREQ=DAILY;UNTIL=20270110",
+			onDay(2027, time.January, 1), onDay(2027, time.January, 20), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.rule, time.UTC)
+			if err != nil {
+				t.Fatalf("ParseRecurrence(%q): %v", tt.rule, err)
+			}
+			got, ok := r.next(tt.from, tt.now)
+			if tt.want.IsZero() {
+				if ok {
+					t.Fatalf("next = %v, ожидалось окончание повторений", got)
+				}
+				return
+			}
+			if !ok || !got.Equal(tt.want) {
+				t.Fatalf("next = %v, %v; ожидалось %v", got, ok, tt.want)
+			}
+		})
+	}
+}
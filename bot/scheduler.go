@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// ============================================================
+// ПЛАНИРОВЩИК НАПОМИНАНИЙ
+//
+// Раз в минуту просматривает задачи со сроком и присылает владельцу
+// напоминание:
+//   - за каждый из заданных интервалов до срока (по умолчанию за сутки и за час)
+//   - один раз, когда срок прошёл, а задача не выполнена
+//
+// Какие напоминания уже отправлены, хранится в самой задаче
+// (Task.RemindersSent), поэтому после перезапуска бота они не
+// приходят повторно. Отметку ставим ДО отправки: если Telegram
+// не ответит, лучше пропустить одно напоминание, чем прислать два.
+// ============================================================
+
+// DefaultReminderOffsets — за сколько до срока напоминать по умолчанию
+var DefaultReminderOffsets = []time.Duration{24 * time.Hour, time.Hour}
+
+const (
+	reminderTick    = time.Minute // Как часто проверять сроки
+	snoozeDuration  = time.Hour   // На сколько откладывает кнопка «Отложить»
+	reminderOverdue = "overdue"   // Ключ напоминания о просроченной задаче
+)
+
+// scheduler — фоновая проверка сроков задач
+type scheduler struct {
+	bot     *Bot
+	offsets []time.Duration // Интервалы до срока, по убыванию
+}
+
+func newScheduler(b *Bot, offsets []time.Duration) *scheduler {
+	if offsets == nil {
+		offsets = DefaultReminderOffsets
+	}
+	sorted := slices.Clone(offsets)
+	slices.Sort(sorted)
+	slices.Reverse(sorted)
+	return &scheduler{bot: b, offsets: sorted}
+}
+
+// run проверяет сроки сразу после запуска и затем каждые reminderTick
+func (s *scheduler) run() {
+	ticker := time.NewTicker(reminderTick)
+	defer ticker.Stop()
+
+	for {
+		s.tick(s.bot.now())
+		<-ticker.C
+	}
+}
+
+// tick отправляет все напоминания, которые пора отправить к моменту now
+func (s *scheduler) tick(now time.Time) {
+	horizon := now
+	if len(s.offsets) > 0 {
+		horizon = now.Add(s.offsets[0])
+	}
+
+	due, err := s.bot.storage.DueTasks(horizon)
+	if err != nil {
+		log.Printf("❌ Напоминания: ошибка хранилища: %v", err)
+		return
+	}
+	for _, item := range due {
+		s.remind(item.UserID, item.Task, now)
+	}
+}
+
+// remind решает, нужно ли напоминание по задаче, и отправляет его
+// space — пространство задачи (пользователь или команда, см. workspaces.go)
+func (s *scheduler) remind(space int64, task Task, now time.Time) {
+	storage := s.bot.storage
+
+	// Пользователь отложил напоминание — ждём
+	if task.SnoozedUntil != nil {
+		if now.Before(*task.SnoozedUntil) {
+			return
+		}
+		// Время вышло: снимаем откладывание и напоминаем ещё раз
+		if err := storage.SnoozeReminder(space, task.ID, nil); err != nil {
+			log.Printf("❌ Напоминания: задача %d: %v", task.ID, err)
+			return
+		}
+		s.send(space, task, fmt.Sprintf("⏰ Напоминаю о задаче «%s»", task.Title))
+		return
+	}
+
+	key, text := s.reminderFor(task, now)
+	if key == "" || slices.Contains(task.RemindersSent, key) {
+		return
+	}
+	if err := storage.MarkReminded(space, task.ID, key); err != nil {
+		log.Printf("❌ Напоминания: задача %d: %v", task.ID, err)
+		return
+	}
+	s.send(space, task, text)
+}
+
+// reminderFor возвращает ключ и текст напоминания, которое положено сейчас
+// Из нескольких интервалов выбираем ближайший к сроку: задача, созданная
+// за 30 минут до срока, получит одно напоминание "за час", а не два
+func (s *scheduler) reminderFor(task Task, now time.Time) (key, text string) {
+	left := task.Deadline.Sub(now)
+	if left <= 0 {
+		return reminderOverdue, fmt.Sprintf("🔥 Срок задачи «%s» истёк", task.Title)
+	}
+	for i := len(s.offsets) - 1; i >= 0; i-- {
+		if left <= s.offsets[i] {
+			return s.offsets[i].String(), fmt.Sprintf("⏰ Срок задачи «%s» через %s",
+				task.Title, formatOffset(s.offsets[i]))
+		}
+	}
+	return "", ""
+}
+
+// send отправляет напоминание в личный чат пользователя, а о задаче
+// команды — каждому участнику (в личном чате ID чата совпадает с ID пользователя)
+func (s *scheduler) send(space int64, task Task, text string) {
+	text += fmt.Sprintf("\n⏰ Срок: %s", formatDeadline(*task.Deadline, s.bot.loc))
+	keyboard := reminderKeyboard(task, s.bot.workflow(space), space)
+	for _, chatID := range s.bot.recipients(space) {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ReplyMarkup = keyboard
+		s.bot.send(msg)
+	}
+}
+
+// ============================================================
+// Кнопка «Отложить на 1 час»
+// ============================================================
+func (b *Bot) handleSnooze(chatID, userID int64, taskID int) {
+	until := b.now().Add(snoozeDuration)
+	if err := b.storage.SnoozeReminder(b.space(userID), taskID, &until); err != nil {
+		b.sendStorageError(chatID, err)
+		return
+	}
+	b.sendText(chatID, fmt.Sprintf("⏰ Хорошо, напомню в %s", until.Format("15:04")))
+}
+
+// formatOffset — интервал по-русски для "через ...": "1 день", "3 часа", "1 минуту"
+func formatOffset(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		n := int(d / (24 * time.Hour))
+		return fmt.Sprintf("%d %s", n, plural(n, "день", "дня", "дней"))
+	case d >= time.Hour && d%time.Hour == 0:
+		n := int(d / time.Hour)
+		return fmt.Sprintf("%d %s", n, plural(n, "час", "часа", "часов"))
+	default:
+		n := int(d.Round(time.Minute) / time.Minute)
+		return fmt.Sprintf("%d %s", n, plural(n, "минуту", "минуты", "минут"))
+	}
+}
+
+// plural выбирает форму слова для числа n (1 день, 2 дня, 5 дней)
+func plural(n int, one, few, many string) string {
+	n %= 100
+	switch {
+	case n >= 11 && n <= 14:
+		return many
+	case n%10 == 1:
+		return one
+	case n%10 >= 2 && n%10 <= 4:
+		return few
+	default:
+		return many
+	}
+}
@@ -0,0 +1,423 @@
+package bot
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ============================================================
+// ПОЛНОТЕКСТОВЫЙ ПОИСК ПО ЗАДАЧАМ
+//
+// Обратный (инвертированный) индекс: для каждого слова храним,
+// в каких задачах оно встречается, а сами слова — ещё и в
+// отсортированном списке. Слова, начинающиеся со слова запроса,
+// стоят в нём подряд: их находит двоичный поиск, без перебора
+// всех задач и всех слов.
+//
+// Слова приводятся к "основе" упрощённым стеммером для русского:
+//   "лабораторная", "лабораторной", "лабораторные" → "лабораторн"
+// а слово запроса может быть началом слова из задачи:
+//   "лаб" найдёт "Лабораторная №3"
+// Буквы "ё" и "е" не различаются.
+// ============================================================
+
+// Searcher — хранилище, которое умеет искать задачи по тексту
+// Реализуется IndexedStore; бот и API проверяют его через приведение типа,
+// а для остальных хранилищ ищут перебором (см. SearchTasks)
+type Searcher interface {
+	// Search возвращает задачи пользователя, в названии или описании
+	// которых есть все слова запроса; самые подходящие — первыми
+	Search(userID int64, query string) ([]Task, error)
+}
+
+// SearchTasks ищет задачи через индекс, если хранилище его поддерживает,
+// иначе — перебором задач пользователя (результат тот же, только медленнее)
+func SearchTasks(store TaskStore, userID int64, query string) ([]Task, error) {
+	if s, ok := store.(Searcher); ok {
+		return s.Search(userID, query)
+	}
+	tasks, err := store.GetTasks(userID)
+	if err != nil {
+		return nil, err
+	}
+	idx := newUserIndex()
+	for _, task := range tasks {
+		idx.put(task)
+	}
+	return pickTasks(tasks, idx.search(query)), nil
+}
+
+// ============================================================
+// Разбор текста на слова
+// ============================================================
+
+// minStemLength — короче основу не обрезаем ("дом" остаётся "дом")
+const minStemLength = 3
+
+// russianEndings — окончания и суффиксы, которые отрезает стеммер
+// Сначала длинные: из "лабораторными" отрежется "ыми", а не "и"
+var russianEndings = func() []string {
+	endings := []string{
+		// Прилагательные и причастия
+		"ыми", "ими", "ого", "его", "ому", "ему", "ая", "яя", "ое", "ее",
+		"ые", "ие", "ый", "ий", "ой", "ую", "юю", "ых", "их", "ым", "им",
+		// Существительные
+		"иями", "ями", "ами", "иях", "ях", "ах", "ов", "ев", "ей", "ам",
+		"ям", "ом", "ем", "ию", "ия", "ии", "ие",
+		// Глаголы (без "ет", "ат" и т.п.: иначе "отчет" → "отч", "формат" → "форм")
+		"ться", "тся", "ите", "ют", "ит", "ят", "ила", "ило", "или",
+		"ала", "ало", "али", "ил", "ал", "ть",
+		// Одиночные гласные и знаки
+		"а", "я", "о", "е", "ы", "и", "у", "ю", "ь", "й",
+	}
+	sort.SliceStable(endings, func(i, j int) bool {
+		return len([]rune(endings[i])) > len([]rune(endings[j]))
+	})
+	return endings
+}()
+
+// stem приводит слово к основе (слово уже в нижнем регистре, без "ё")
+// Латиница и числа не меняются
+func stem(word string) string {
+	runes := []rune(word)
+	if len(runes) <= minStemLength || !isCyrillic(runes[0]) {
+		return word
+	}
+	// Возвратные глаголы: "готовиться" → "готовить" → "готови"
+	for _, suffix := range []string{"ся", "сь"} {
+		if strings.HasSuffix(word, suffix) && len(runes)-2 > minStemLength {
+			word = strings.TrimSuffix(word, suffix)
+			runes = runes[:len(runes)-2]
+			break
+		}
+	}
+	for _, ending := range russianEndings {
+		if strings.HasSuffix(word, ending) && len(runes)-len([]rune(ending)) >= minStemLength {
+			return strings.TrimSuffix(word, ending)
+		}
+	}
+	return word
+}
+
+func isCyrillic(r rune) bool {
+	return unicode.Is(unicode.Cyrillic, r)
+}
+
+// terms разбивает текст на основы слов
+// "Сдать Лабораторную №3!" → ["сда", "лабораторн", "3"]
+func terms(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	result := make([]string, 0, len(words))
+	for _, w := range words {
+		result = append(result, stem(w))
+	}
+	return result
+}
+
+// ============================================================
+// Индекс задач одного пользователя
+// ============================================================
+
+// Вес совпадения: слово в названии важнее слова в описании
+const (
+	weightTitle       = 2
+	weightDescription = 1
+)
+
+type userIndex struct {
+	postings map[string]map[int]int // основа → ID задачи → вес
+	terms    []string               // Все основы из postings по алфавиту (поиск по началу слова)
+	docs     map[int][]string       // ID задачи → её основы (чтобы убрать задачу из индекса)
+}
+
+func newUserIndex() *userIndex {
+	return &userIndex{
+		postings: make(map[string]map[int]int),
+		docs:     make(map[int][]string),
+	}
+}
+
+// put добавляет задачу в индекс или обновляет её
+func (idx *userIndex) put(task Task) {
+	idx.remove(task.ID)
+
+	weights := make(map[string]int)
+	for _, t := range terms(task.Description) {
+		weights[t] = weightDescription
+	}
+	for _, t := range terms(task.Title) {
+		weights[t] = weightTitle
+	}
+	for t, w := range weights {
+		if idx.postings[t] == nil {
+			idx.postings[t] = make(map[int]int)
+			i, _ := slices.BinarySearch(idx.terms, t)
+			idx.terms = slices.Insert(idx.terms, i, t)
+		}
+		idx.postings[t][task.ID] = w
+		idx.docs[task.ID] = append(idx.docs[task.ID], t)
+	}
+}
+
+// remove убирает задачу из индекса
+func (idx *userIndex) remove(taskID int) {
+	for _, t := range idx.docs[taskID] {
+		delete(idx.postings[t], taskID)
+		if len(idx.postings[t]) == 0 {
+			delete(idx.postings, t)
+			if i, ok := slices.BinarySearch(idx.terms, t); ok {
+				idx.terms = slices.Delete(idx.terms, i, i+1)
+			}
+		}
+	}
+	delete(idx.docs, taskID)
+}
+
+// match возвращает ID задач с весами, где есть слово запроса q
+// Точное совпадение основы весит вдвое больше, чем совпадение по началу
+func (idx *userIndex) match(q string) map[int]int {
+	found := make(map[int]int)
+	start, _ := slices.BinarySearch(idx.terms, q)
+	for _, t := range idx.terms[start:] {
+		if !strings.HasPrefix(t, q) {
+			break // Дальше по алфавиту слова с другим началом
+		}
+		factor := 1
+		if t == q {
+			factor = 2
+		}
+		for id, w := range idx.postings[t] {
+			found[id] = max(found[id], w*factor)
+		}
+	}
+	return found
+}
+
+// search находит задачи, где есть все слова запроса, и возвращает их ID,
+// упорядоченные по сумме весов (при равенстве — новые выше)
+func (idx *userIndex) search(query string) []int {
+	queryTerms := terms(query)
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	var scores map[int]int
+	for _, q := range queryTerms {
+		found := idx.match(q)
+		if scores == nil {
+			scores = found
+			continue
+		}
+		// Оставляем только задачи, где нашлись и прежние слова
+		for id := range scores {
+			if w, ok := found[id]; ok {
+				scores[id] += w
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	ids := make([]int, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		si, sj := scores[ids[i]], scores[ids[j]]
+		if si != sj {
+			return si > sj
+		}
+		return ids[i] > ids[j]
+	})
+	return ids
+}
+
+// pickTasks выбирает из tasks задачи с ID из ids в том же порядке
+func pickTasks(tasks []Task, ids []int) []Task {
+	byID := make(map[int]Task, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+	}
+	result := make([]Task, 0, len(ids))
+	for _, id := range ids {
+		if task, ok := byID[id]; ok {
+			result = append(result, task)
+		}
+	}
+	return result
+}
+
+// ============================================================
+// IndexedStore — хранилище с поисковым индексом
+//
+// Оборачивает любое TaskStore (декоратор): чтение и прочие методы
+// передаются как есть, а после изменений названия или описания
+// задача переиндексируется.
+//
+// Индекс пользователя строится при первом поиске (из GetTasks),
+// поэтому запуск бота не замедляется, а память тратится только
+// на тех, кто ищет.
+//
+// ⚠️ Если в TaskStore появится новый метод, меняющий название,
+// описание или набор задач, его нужно переопределить здесь.
+// ============================================================
+type IndexedStore struct {
+	TaskStore // Оборачиваемое хранилище
+
+	mu     sync.Mutex            // Защищает только map spaces
+	spaces map[int64]*spaceIndex // Пространства, которые уже искали или меняли
+}
+
+// spaceIndex — индекс одного пространства и его блокировка
+// mu держится и во время изменения в хранилище, и во время обновления
+// индекса, чтобы индекс менялся в том же порядке, что и задачи.
+// Пространства друг друга не ждут
+type spaceIndex struct {
+	mu  sync.Mutex
+	idx *userIndex // nil — в пространстве ещё не искали
+}
+
+// put обновляет задачи в индексе (вызывать под блокировкой mu)
+// Пока в пространстве не искали, индекса нет — обновлять нечего
+func (sp *spaceIndex) put(tasks ...Task) {
+	if sp.idx != nil {
+		for _, task := range tasks {
+			sp.idx.put(task)
+		}
+	}
+}
+
+// maxPointReads — до скольких найденных задач читаем их по одной;
+// если нашлось больше, дешевле прочитать все задачи одним GetTasks
+const maxPointReads = 10
+
+// NewIndexedStore добавляет к хранилищу полнотекстовый поиск
+func NewIndexedStore(store TaskStore) *IndexedStore {
+	return &IndexedStore{
+		TaskStore: store,
+		spaces:    make(map[int64]*spaceIndex),
+	}
+}
+
+// space возвращает индекс пространства (создаёт пустой при первом обращении)
+func (s *IndexedStore) space(userID int64) *spaceIndex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sp := s.spaces[userID]
+	if sp == nil {
+		sp = &spaceIndex{}
+		s.spaces[userID] = sp
+	}
+	return sp
+}
+
+// Search ищет задачи пользователя по словам запроса
+// Порядок и набор задач берём из индекса, а сами задачи читаем из
+// хранилища, чтобы статус, срок и прочее были свежими
+func (s *IndexedStore) Search(userID int64, query string) ([]Task, error) {
+	sp := s.space(userID)
+	sp.mu.Lock()
+	if sp.idx == nil {
+		tasks, err := s.TaskStore.GetTasks(userID)
+		if err != nil {
+			sp.mu.Unlock()
+			return nil, err
+		}
+		sp.idx = newUserIndex()
+		for _, task := range tasks {
+			sp.idx.put(task)
+		}
+	}
+	ids := sp.idx.search(query)
+	sp.mu.Unlock()
+
+	if len(ids) > maxPointReads {
+		tasks, err := s.TaskStore.GetTasks(userID)
+		if err != nil {
+			return nil, err
+		}
+		return pickTasks(tasks, ids), nil
+	}
+	found := make([]Task, 0, len(ids))
+	for _, id := range ids {
+		task, err := s.TaskStore.GetTask(userID, id)
+		if errors.Is(err, ErrTaskNotFound) {
+			continue // Удалили, пока мы читали остальные
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, task)
+	}
+	return found, nil
+}
+
+func (s *IndexedStore) AddTask(userID, actor int64, draft TaskDraft) (Task, error) {
+	sp := s.space(userID)
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	task, err := s.TaskStore.AddTask(userID, actor, draft)
+	if err == nil {
+		sp.put(task)
+	}
+	return task, err
+}
+
+func (s *IndexedStore) UpdateTask(userID int64, taskID int, actor int64, patch TaskPatch) (Task, error) {
+	sp := s.space(userID)
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	task, err := s.TaskStore.UpdateTask(userID, taskID, actor, patch)
+	if err == nil {
+		sp.put(task)
+	}
+	return task, err
+}
+
+// UpdateStatus — повторяющаяся задача при выполнении создаёт новую
+func (s *IndexedStore) UpdateStatus(userID int64, taskID int, actor int64, newStatus string) (StatusChange, error) {
+	sp := s.space(userID)
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	result, err := s.TaskStore.UpdateStatus(userID, taskID, actor, newStatus)
+	if err == nil && result.Next != nil {
+		sp.put(*result.Next)
+	}
+	return result, err
+}
+
+func (s *IndexedStore) DeleteTask(userID int64, taskID int, actor int64) error {
+	sp := s.space(userID)
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	err := s.TaskStore.DeleteTask(userID, taskID, actor)
+	if err == nil && sp.idx != nil {
+		sp.idx.remove(taskID)
+	}
+	return err
+}
+
+// RestoreTask — задача из корзины снова находится поиском
+func (s *IndexedStore) RestoreTask(userID int64, taskID int, actor int64) (Task, error) {
+	sp := s.space(userID)
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	task, err := s.TaskStore.RestoreTask(userID, taskID, actor)
+	if err == nil {
+		sp.put(task)
+	}
+	return task, err
+}
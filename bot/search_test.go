@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStemInflectedForms(t *testing.T) {
+	// Формы одного слова должны сводиться к одной основе
+	tests := [][]string{
+		{"задача", "задачи", "задачу", "задаче", "задачей", "задачам", "задачами", "задачах"},
+		{"лабораторная", "лабораторной", "лабораторную", "лабораторные", "лабораторными"},
+		{"отчет", "отчета", "отчету", "отчетом", "отчеты", "отчетов"},
+		{"курсовая", "курсовой", "курсовую", "курсовые"},
+		{"готовиться", "готовить"},
+	}
+
+	for _, forms := range tests {
+		t.Run(forms[0], func(t *testing.T) {
+			want := stem(forms[0])
+			for _, form := range forms[1:] {
+				if got := stem(form); got != want {
+					t.Errorf("stem(%q) = %q, а stem(%q) = %q", form, got, forms[0], want)
+				}
+			}
+		})
+	}
+}
+
+func TestStemKeepsShortAndLatinWords(t *testing.T) {
+	for _, word := range []string{"дом", "пн", "go", "api", "postgres", "2026"} {
+		if got := stem(word); got != word {
+			t.Errorf("stem(%q) = %q, слово должно остаться как есть", word, got)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	got := terms("Сдать ЗАДАЧИ по матану, ёлки!")
+	want := []string{stem("сдать"), stem("задачи"), stem("по"), stem("матану"), stem("елки")}
+	if !slices.Equal(got, want) {
+		t.Fatalf("terms = %q, ожидалось %q", got, want)
+	}
+}
+
+func TestSearchFindsInflectedForms(t *testing.T) {
+	store := NewIndexedStore(NewStorage())
+	for _, title := range []string{"Решить задачи по матану", "Купить хлеб", "Отчёт по лабораторной"} {
+		if _, err := store.AddTask(testUser, testUser, TaskDraft{Title: title}); err != nil {
+			t.Fatalf("AddTask: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"задачу", []string{"Решить задачи по матану"}},
+		{"задачам", []string{"Решить задачи по матану"}},
+		{"отчетом лабораторные", []string{"Отчёт по лабораторной"}},
+		{"хлеба", []string{"Купить хлеб"}},
+		{"задачу хлеб", nil}, // Должны найтись все слова запроса
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			tasks, err := store.Search(testUser, tt.query)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			var got []string
+			for _, task := range tasks {
+				got = append(got, task.Title)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Search(%q) = %q, ожидалось %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserIndexPrefixAfterUpdates(t *testing.T) {
+	idx := newUserIndex()
+	idx.put(Task{ID: 1, Title: "Лабораторная по физике"})
+	idx.put(Task{ID: 2, Title: "Лабиринт"})
+	idx.put(Task{ID: 3, Title: "Купить лампу"})
+
+	if got := idx.search("лаб"); !slices.Equal(got, []int{2, 1}) {
+		t.Fatalf(`search("лаб") = %v, ожидалось [2 1]`, got)
+	}
+
+	// Задача поменяла название — старые слова из индекса уходят
+	idx.put(Task{ID: 2, Title: "Купить хлеб"})
+	idx.remove(1)
+	if got := idx.search("лаб"); len(got) != 0 {
+		t.Fatalf(`search("лаб") после изменений = %v, ожидалось пусто`, got)
+	}
+	if !slices.IsSorted(idx.terms) || len(idx.terms) != len(idx.postings) {
+		t.Fatalf("список основ %q не совпадает с индексом", idx.terms)
+	}
+	if got := idx.search("куп"); !slices.Equal(got, []int{3, 2}) {
+		t.Fatalf(`search("куп") = %v, ожидалось [3 2]`, got)
+	}
+}
@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// openTestSQLite — чистая база SQLite во временной папке теста
+func openTestSQLite(t *testing.T) *SQLStore {
+	t.Helper()
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLCreateWorkspaceConcurrent(t *testing.T) {
+	s := openTestSQLite(t)
+
+	const n = 10
+	ids := make(chan int64, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func(owner int64) {
+			defer wg.Done()
+			w, err := s.CreateWorkspace(Member{UserID: owner}, "Команда")
+			if err != nil {
+				t.Errorf("CreateWorkspace: %v", err)
+				return
+			}
+			ids <- w.ID
+		}(int64(100 + i))
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int64]bool)
+	for id := range ids {
+		if id >= 0 || seen[id] {
+			t.Fatalf("ID команды %d: повтор или не отрицательный", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != n {
+		t.Fatalf("создано команд: %d, ожидалось %d", len(seen), n)
+	}
+}
+
+// Две одновременные передачи команды: проходит только одна, владелец —
+// ровно один. Владелец, которому передают команду, не успевает выйти
+func TestSQLOwnershipTransferConcurrent(t *testing.T) {
+	s := openTestSQLite(t)
+	const owner, a, b = 1, 2, 3
+
+	for range 20 {
+		w, err := s.CreateWorkspace(Member{UserID: owner}, "Команда")
+		if err != nil {
+			t.Fatalf("CreateWorkspace: %v", err)
+		}
+		for _, id := range []int64{a, b} {
+			if _, err := s.JoinWorkspace(Member{UserID: id}, w.InviteCode); err != nil {
+				t.Fatalf("JoinWorkspace: %v", err)
+			}
+		}
+
+		var wg sync.WaitGroup
+		for _, target := range []int64{a, b} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.SetMemberRole(owner, w.ID, target, RoleOwner)
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.LeaveWorkspace(a, w.ID)
+		}()
+		wg.Wait()
+
+		got, err := s.GetWorkspace(w.ID)
+		if err != nil {
+			t.Fatalf("GetWorkspace: %v", err)
+		}
+		var owners []int64
+		for _, m := range got.Members {
+			if m.Role == RoleOwner {
+				owners = append(owners, m.UserID)
+			}
+		}
+		if len(owners) != 1 || owners[0] != got.OwnerID {
+			t.Fatalf("владельцы %v, owner_id %d: должен быть ровно один владелец-участник", owners, got.OwnerID)
+		}
+	}
+}
+
+// Одинаковые операции дают одинаковый результат в памяти и в SQLite
+func TestSQLMatchesMemory(t *testing.T) {
+	run := func(t *testing.T, s TaskStore) []Task {
+		t.Helper()
+		must := func(err error) {
+			t.Helper()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		a, err := s.AddTask(testUser, testUser, TaskDraft{Title: "Лабораторная", Priority: PriorityHigh, Tags: []string{"#учёба"}})
+		must(err)
+		b, err := s.AddTask(testUser, testUser, TaskDraft{Title: "Отчёт", Description: "по лабораторной"})
+		must(err)
+		c, err := s.AddTask(testUser, testUser, TaskDraft{Title: "Удалить"})
+		must(err)
+
+		_, err = s.AddDependency(testUser, b.ID, a.ID, testUser)
+		must(err)
+		_, err = s.AddChecklistItem(testUser, a.ID, testUser, "Собрать стенд")
+		must(err)
+		_, err = s.ToggleChecklistItem(testUser, a.ID, 1, testUser)
+		must(err)
+		_, err = s.UpdateStatus(testUser, b.ID, testUser, StatusDone)
+		if !errors.Is(err, ErrTaskBlocked) {
+			t.Fatalf("выполнение заблокированной задачи: %v, ожидалась ErrTaskBlocked", err)
+		}
+		change, err := s.UpdateStatus(testUser, a.ID, testUser, StatusDone)
+		must(err)
+		if len(change.Unblocked) != 1 || change.Unblocked[0].ID != b.ID {
+			t.Fatalf("разблокированы %+v, ожидалась задача %d", change.Unblocked, b.ID)
+		}
+		title := "Отчёт по лабе"
+		_, err = s.UpdateTask(testUser, b.ID, testUser, TaskPatch{Title: &title})
+		must(err)
+		must(s.DeleteTask(testUser, c.ID, testUser))
+		_, err = s.GetTask(testUser, c.ID)
+		if !errors.Is(err, ErrTaskNotFound) {
+			t.Fatalf("задача в корзине: %v, ожидалась ErrTaskNotFound", err)
+		}
+
+		tasks, err := s.GetTasks(testUser)
+		must(err)
+		for i := range tasks {
+			tasks[i].CreatedAt = time.Time{} // Время создания у хранилищ разное
+		}
+		return tasks
+	}
+
+	memory := run(t, NewStorage())
+	sqlite := run(t, openTestSQLite(t))
+	if !reflect.DeepEqual(normalizeTasks(memory), normalizeTasks(sqlite)) {
+		t.Fatalf("задачи различаются:\nпамять: %+v\nSQLite: %+v", memory, sqlite)
+	}
+}
+
+// normalizeTasks — nil вместо пустых срезов, чтобы сравнивать задачи
+// из разных хранилищ по содержимому
+func normalizeTasks(tasks []Task) []Task {
+	for i := range tasks {
+		t := &tasks[i]
+		if len(t.Tags) == 0 {
+			t.Tags = nil
+		}
+		if len(t.Checklist) == 0 {
+			t.Checklist = nil
+		}
+		if len(t.BlockedBy) == 0 {
+			t.BlockedBy = nil
+		}
+		if len(t.RemindersSent) == 0 {
+			t.RemindersSent = nil
+		}
+	}
+	return tasks
+}
@@ -0,0 +1,231 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+)
+
+// ============================================================
+// СТАТИСТИКА
+//
+// Показатели пространства считаются по задачам и их истории:
+//   создано и выполнено за период — всего и по неделям
+//   среднее время выполнения — от создания задачи до перехода
+//                              в завершающий статус
+//   сколько задач сейчас в каждом статусе и сколько просрочено
+//
+// Когда задачу выполнили, знает только история (в самой задаче —
+// лишь текущий статус), поэтому выполненной за период считается
+// задача, которая сейчас выполнена и последний раз перешла в
+// завершающий статус внутри периода. Задачи в корзине не считаются.
+//
+// В пространстве команды то же видно по участникам: выполненные за
+// период — тому, кто перевёл задачу в завершающий статус, открытые —
+// исполнителю
+//
+// Бот показывает статистику командой /stats, API — GET /api/stats
+// ============================================================
+
+// Ограничения периода статистики
+const (
+	DefaultStatsWeeks = 4   // Период по умолчанию — последние 4 недели
+	maxStatsDays      = 366 // Самый длинный период
+)
+
+// ErrBadStatsPeriod — период задан неверно
+var ErrBadStatsPeriod = fmt.Errorf("неверный период: начало не позже конца и не больше %d дней", maxStatsDays)
+
+// Stats — показатели пространства за период [From, To)
+type Stats struct {
+	From, To time.Time
+
+	Created   int           // Создано за период
+	Completed int           // Выполнено за период
+	Weeks     []WeekStats   // То же по неделям (с понедельника), от старых к новым
+	LeadTime  time.Duration // Среднее время от создания до выполнения (у выполненных за период)
+
+	// Текущее состояние — от периода не зависит
+	ByStatus []StatusCount // Сколько задач в каждом статусе (в порядке набора)
+	Overdue  int           // Сколько задач просрочено
+
+	ByMember []MemberStats // По участникам — только в пространстве команды
+}
+
+// WeekStats — создано и выполнено за одну неделю
+type WeekStats struct {
+	Start     time.Time // Понедельник, 00:00
+	Created   int
+	Completed int
+}
+
+// MemberStats — показатели одного участника команды
+type MemberStats struct {
+	UserID    int64
+	Completed int // Выполнил за период (перевёл в завершающий статус)
+	Open      int // Сейчас открыто задач, где он исполнитель
+}
+
+// StatusCount — сколько задач в статусе
+type StatusCount struct {
+	Status StatusDef
+	Count  int
+}
+
+// StatsPeriod — период по дням from..to включительно (в часовом поясе loc):
+// возвращает начало from и начало дня после to
+func StatsPeriod(from, to time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	start := startOfDay(from, loc)
+	end := startOfDay(to, loc).AddDate(0, 0, 1)
+	if !start.Before(end) || start.AddDate(0, 0, maxStatsDays).Before(end) {
+		return time.Time{}, time.Time{}, ErrBadStatsPeriod
+	}
+	return start, end, nil
+}
+
+// DefaultStatsPeriod — последние DefaultStatsWeeks недель, включая сегодня
+func DefaultStatsPeriod(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	from, to, _ := StatsPeriod(now.AddDate(0, 0, -7*DefaultStatsWeeks+1), now, loc)
+	return from, to
+}
+
+// LoadStats читает задачи пространства с историей и считает показатели
+func LoadStats(store TaskStore, space int64, from, to, now time.Time, loc *time.Location) (Stats, error) {
+	tasks, err := store.GetTasks(space)
+	if err != nil {
+		return Stats{}, err
+	}
+	wf, err := store.GetWorkflow(space)
+	if err != nil {
+		return Stats{}, err
+	}
+	// Из истории нужно только, когда задачу выполнили, — читаем смены
+	// статуса всего пространства разом
+	history, err := store.GetStatusEvents(space)
+	if err != nil {
+		return Stats{}, err
+	}
+	var members []Member
+	if IsShared(space) {
+		w, err := store.GetWorkspace(space)
+		if err != nil {
+			return Stats{}, err
+		}
+		members = w.Members
+	}
+	return ComputeStats(tasks, history, wf, members, from, to, now, loc), nil
+}
+
+// ComputeStats считает показатели по задачам и их истории (history — по ID
+// задачи). members — участники команды для разбивки по ним (nil — личное
+// пространство, разбивки нет)
+func ComputeStats(tasks []Task, history map[int][]TaskEvent, wf Workflow, members []Member, from, to, now time.Time, loc *time.Location) Stats {
+	stats := Stats{From: from, To: to}
+	for start := startOfWeek(from, loc); start.Before(to); start = start.AddDate(0, 0, 7) {
+		stats.Weeks = append(stats.Weeks, WeekStats{Start: start})
+	}
+	week := func(t time.Time) *WeekStats {
+		for i := len(stats.Weeks) - 1; i >= 0; i-- {
+			if !t.Before(stats.Weeks[i].Start) {
+				return &stats.Weeks[i]
+			}
+		}
+		return &stats.Weeks[0]
+	}
+	inPeriod := func(t time.Time) bool {
+		return !t.Before(from) && t.Before(to)
+	}
+
+	// Участники — в порядке вступления; кто уже вышел из команды, но
+	// успел что-то выполнить или остался исполнителем, — следом
+	listed := make(map[int64]bool)
+	member := func(userID int64) {
+		if !listed[userID] {
+			listed[userID] = true
+			stats.ByMember = append(stats.ByMember, MemberStats{UserID: userID})
+		}
+	}
+	for _, m := range members {
+		member(m.UserID)
+	}
+
+	counts := make(map[string]int)
+	var leadTotal time.Duration
+	completedBy := make(map[int64]int)
+	openFor := make(map[int64]int)
+	for _, task := range tasks {
+		counts[task.Status]++
+		if task.IsOverdue(now) {
+			stats.Overdue++
+		}
+		if inPeriod(task.CreatedAt) {
+			stats.Created++
+			week(task.CreatedAt).Created++
+		}
+		if done, ok := completedAt(history[task.ID], wf); ok && task.IsDone() && inPeriod(done.At) {
+			stats.Completed++
+			week(done.At).Completed++
+			leadTotal += done.At.Sub(task.CreatedAt)
+			if members != nil && done.Actor != 0 {
+				member(done.Actor)
+				completedBy[done.Actor]++
+			}
+		}
+		if members != nil && !task.IsDone() && task.Assignee != 0 {
+			member(task.Assignee)
+			openFor[task.Assignee]++
+		}
+	}
+	for i := range stats.ByMember {
+		stats.ByMember[i].Completed = completedBy[stats.ByMember[i].UserID]
+		stats.ByMember[i].Open = openFor[stats.ByMember[i].UserID]
+	}
+	if stats.Completed > 0 {
+		stats.LeadTime = leadTotal / time.Duration(stats.Completed)
+	}
+
+	// Статусы — в порядке набора; статусы без задач тоже показываем
+	for _, status := range wf.Statuses {
+		stats.ByStatus = append(stats.ByStatus, StatusCount{Status: status, Count: counts[status.Code]})
+	}
+	return stats
+}
+
+// completedAt — событие, которым задача последний раз перешла в
+// завершающий статус (когда и кто)
+func completedAt(events []TaskEvent, wf Workflow) (TaskEvent, bool) {
+	for i := len(events) - 1; i >= 0; i-- {
+		e := events[i]
+		if e.Type != EventStatusChanged {
+			continue
+		}
+		if status, ok := wf.Status(e.To); ok && status.Category == CategoryDone {
+			return e, true
+		}
+	}
+	return TaskEvent{}, false
+}
+
+// startOfDay — полночь дня t в часовом поясе loc
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// startOfWeek — понедельник недели t, 00:00
+func startOfWeek(t time.Time, loc *time.Location) time.Time {
+	day := startOfDay(t, loc)
+	offset := (int(day.Weekday()) + 6) % 7 // Понедельник — 0, воскресенье — 6
+	return day.AddDate(0, 0, -offset)
+}
+
+// FormatLeadTime — время выполнения по-русски: "2 дн 4 ч", "5 ч 30 мин"
+func FormatLeadTime(d time.Duration) string {
+	if d < 24*time.Hour {
+		return FormatTracked(d)
+	}
+	days, hours := int(d/(24*time.Hour)), int(d%(24*time.Hour)/time.Hour)
+	if hours == 0 {
+		return fmt.Sprintf("%d дн", days)
+	}
+	return fmt.Sprintf("%d дн %d ч", days, hours)
+}
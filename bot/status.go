@@ -0,0 +1,338 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// ============================================================
+// Статусы задач
+//
+// В задаче (и в хранилище) лежит только короткий код статуса —
+// он не меняется, даже если поменять эмодзи или текст.
+//
+// Набор статусов у каждого пользователя свой (Workflow): можно
+// добавить, например, «👀 На проверке» или «⏸ Отложено», задать
+// порядок и разрешённые переходы. Пока пользователь ничего не
+// настраивал, действует DefaultWorkflow — три привычных статуса.
+//
+// Смысл статуса для бота задаёт его категория (todo/doing/done):
+// напоминания, зависимости и повторения смотрят только на неё,
+// поэтому «✅ Принята заказчиком» работает так же, как «Выполнена».
+// ============================================================
+
+// Коды статусов по умолчанию
+const (
+	StatusNew        = "new"      // Новая
+	StatusInProgress = "progress" // В работе
+	StatusDone       = "done"     // Выполнена
+)
+
+// Категории статусов
+const (
+	CategoryTodo  = "todo"  // Ещё не начата
+	CategoryDoing = "doing" // В работе (в том числе «на проверке», «отложено»)
+	CategoryDone  = "done"  // Завершена: не напоминаем, не блокирует другие задачи
+)
+
+// categoryLabels — категории по-русски (для бота)
+var categoryLabels = map[string]string{
+	CategoryTodo:  "не начата",
+	CategoryDoing: "в работе",
+	CategoryDone:  "завершена",
+}
+
+// Ограничения на набор статусов
+const (
+	maxStatuses        = 10 // Статусов в наборе (столько кнопок помещается в чат)
+	maxStatusCodeLen   = 20 // Длина кода (он попадает в callback data)
+	maxStatusNameRunes = 30 // Длина названия
+)
+
+// Ошибки статусов
+var (
+	ErrUnknownStatus = errors.New("неизвестный статус")
+	ErrBadTransition = errors.New("такой переход между статусами не разрешён")
+	ErrBadWorkflow   = errors.New("неверный набор статусов")
+	ErrStatusInUse   = errors.New("статус используется задачами")
+)
+
+// StatusDef — один статус из набора пользователя
+type StatusDef struct {
+	Code     string   `json:"code"`           // Код, который хранится в Task.Status
+	Emoji    string   `json:"emoji"`          // Значок для кнопок
+	Name     string   `json:"name"`           // Название
+	Category string   `json:"category"`       // Категория (CategoryTodo...)
+	Next     []string `json:"next,omitempty"` // В какие статусы можно перейти (пусто — в любые)
+}
+
+// Label — подпись статуса с эмодзи: "🆕 Новая"
+func (d StatusDef) Label() string {
+	return d.Emoji + " " + d.Name
+}
+
+// Workflow — упорядоченный набор статусов пользователя
+// Первый статус получают новые задачи
+type Workflow struct {
+	Statuses []StatusDef `json:"statuses"`
+}
+
+// DefaultWorkflow — статусы по умолчанию: переходы между ними не ограничены
+func DefaultWorkflow() Workflow {
+	return Workflow{Statuses: []StatusDef{
+		{Code: StatusNew, Emoji: "🆕", Name: "Новая", Category: CategoryTodo},
+		{Code: StatusInProgress, Emoji: "🔄", Name: "В работе", Category: CategoryDoing},
+		{Code: StatusDone, Emoji: "✅", Name: "Выполнена", Category: CategoryDone},
+	}}
+}
+
+// clone возвращает копию набора, не делящую с оригиналом срезы
+func (wf Workflow) clone() Workflow {
+	statuses := make([]StatusDef, len(wf.Statuses))
+	for i, s := range wf.Statuses {
+		s.Next = slices.Clone(s.Next)
+		statuses[i] = s
+	}
+	return Workflow{Statuses: statuses}
+}
+
+// Status возвращает статус по коду
+func (wf Workflow) Status(code string) (StatusDef, bool) {
+	for _, s := range wf.Statuses {
+		if s.Code == code {
+			return s, true
+		}
+	}
+	return StatusDef{}, false
+}
+
+// Label возвращает подпись статуса по коду
+// Для неизвестного кода возвращает сам код
+func (wf Workflow) Label(code string) string {
+	if s, ok := wf.Status(code); ok {
+		return s.Label()
+	}
+	return code
+}
+
+// Initial — статус новых задач (первый в наборе)
+func (wf Workflow) Initial() StatusDef {
+	return wf.Statuses[0]
+}
+
+// Codes возвращает список всех кодов (для сообщений об ошибках)
+func (wf Workflow) Codes() []string {
+	codes := make([]string, len(wf.Statuses))
+	for i, s := range wf.Statuses {
+		codes[i] = s.Code
+	}
+	return codes
+}
+
+// Targets — статусы, в которые можно перейти из from (в порядке набора)
+func (wf Workflow) Targets(from string) []StatusDef {
+	var targets []StatusDef
+	for _, s := range wf.Statuses {
+		if s.Code != from && wf.canMove(from, s.Code) {
+			targets = append(targets, s)
+		}
+	}
+	return targets
+}
+
+// DoneTarget — первый завершающий статус, доступный из from
+// (для кнопки «✅ Выполнено» под напоминанием)
+func (wf Workflow) DoneTarget(from string) (StatusDef, bool) {
+	for _, s := range wf.Targets(from) {
+		if s.Category == CategoryDone {
+			return s, true
+		}
+	}
+	return StatusDef{}, false
+}
+
+// canMove — разрешён ли переход from → to
+// Из статуса без списка Next (или из неизвестного) можно перейти куда угодно
+func (wf Workflow) canMove(from, to string) bool {
+	s, ok := wf.Status(from)
+	return !ok || len(s.Next) == 0 || slices.Contains(s.Next, to)
+}
+
+// transition проверяет переход from → to и возвращает новый статус
+// Оставить статус прежним можно всегда
+func (wf Workflow) transition(from, to string) (StatusDef, error) {
+	target, ok := wf.Status(to)
+	if !ok {
+		return StatusDef{}, fmt.Errorf("%w %q (допустимые: %s)", ErrUnknownStatus, to, strings.Join(wf.Codes(), ", "))
+	}
+	if from != to && !wf.canMove(from, to) {
+		return StatusDef{}, fmt.Errorf("%w: %s → %s", ErrBadTransition, wf.Label(from), target.Label())
+	}
+	return target, nil
+}
+
+// Validate проверяет набор статусов
+func (wf Workflow) Validate() error {
+	if len(wf.Statuses) < 2 || len(wf.Statuses) > maxStatuses {
+		return fmt.Errorf("%w: нужно от 2 до %d статусов", ErrBadWorkflow, maxStatuses)
+	}
+	hasDone := false
+	seen := make(map[string]bool)
+	for _, s := range wf.Statuses {
+		if !isStatusCode(s.Code) {
+			return fmt.Errorf("%w: код %q — только латиница, цифры и _, до %d символов",
+				ErrBadWorkflow, s.Code, maxStatusCodeLen)
+		}
+		if seen[s.Code] {
+			return fmt.Errorf("%w: код %q повторяется", ErrBadWorkflow, s.Code)
+		}
+		seen[s.Code] = true
+		if s.Emoji == "" || strings.ContainsAny(s.Emoji, " \t\n") {
+			return fmt.Errorf("%w: у статуса %q нет значка", ErrBadWorkflow, s.Code)
+		}
+		if strings.TrimSpace(s.Name) == "" || utf8.RuneCountInString(s.Name) > maxStatusNameRunes {
+			return fmt.Errorf("%w: название статуса %q — от 1 до %d символов",
+				ErrBadWorkflow, s.Code, maxStatusNameRunes)
+		}
+		if _, ok := categoryLabels[s.Category]; !ok {
+			return fmt.Errorf("%w: у статуса %q неизвестная категория %q (допустимые: todo, doing, done)",
+				ErrBadWorkflow, s.Code, s.Category)
+		}
+		for _, next := range s.Next {
+			if _, ok := wf.Status(next); !ok || next == s.Code {
+				return fmt.Errorf("%w: из %q нельзя перейти в %q", ErrBadWorkflow, s.Code, next)
+			}
+		}
+		hasDone = hasDone || s.Category == CategoryDone
+	}
+	if wf.Statuses[0].Category == CategoryDone {
+		return fmt.Errorf("%w: первый статус (для новых задач) не может быть завершающим", ErrBadWorkflow)
+	}
+	if !hasDone {
+		return fmt.Errorf("%w: нужен хотя бы один статус с категорией done", ErrBadWorkflow)
+	}
+	return nil
+}
+
+// isStatusCode — код из латиницы, цифр и "_" (он попадает в callback data)
+func isStatusCode(code string) bool {
+	if code == "" || len(code) > maxStatusCodeLen {
+		return false
+	}
+	for _, r := range code {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// checkStatusesInUse проверяет, что в новом наборе остались все статусы,
+// в которых сейчас есть задачи (lists — задачи пользователя и его корзина)
+func checkStatusesInUse(wf Workflow, lists ...[]Task) error {
+	for _, tasks := range lists {
+		for _, task := range tasks {
+			if _, ok := wf.Status(task.Status); !ok {
+				return fmt.Errorf("%w: %q (сначала переведи задачи в другой статус)", ErrStatusInUse, task.Status)
+			}
+		}
+	}
+	return nil
+}
+
+// ============================================================
+// Текстовая запись набора статусов (для настройки в боте)
+// Одна строка — один статус:
+//
+//	код: эмодзи Название (категория) -> код, код
+//
+// например:
+//
+//	review: 👀 На проверке (doing) -> done, progress
+//
+// Часть "-> ..." необязательна: без неё переходы не ограничены
+// ============================================================
+
+// Format записывает набор статусов в текстовом виде
+func (wf Workflow) Format() string {
+	lines := make([]string, len(wf.Statuses))
+	for i, s := range wf.Statuses {
+		lines[i] = fmt.Sprintf("%s: %s %s (%s)", s.Code, s.Emoji, s.Name, s.Category)
+		if len(s.Next) > 0 {
+			lines[i] += " -> " + strings.Join(s.Next, ", ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ParseWorkflow разбирает текстовую запись набора статусов и проверяет его
+func ParseWorkflow(text string) (Workflow, error) {
+	var wf Workflow
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		s, err := parseStatusLine(line)
+		if err != nil {
+			return Workflow{}, err
+		}
+		wf.Statuses = append(wf.Statuses, s)
+	}
+	return wf, wf.Validate()
+}
+
+// parseStatusLine разбирает строку "код: эмодзи Название (категория) -> код, код"
+func parseStatusLine(line string) (StatusDef, error) {
+	bad := fmt.Errorf("%w: не понял строку %q", ErrBadWorkflow, line)
+
+	code, rest, ok := strings.Cut(line, ":")
+	if !ok {
+		return StatusDef{}, bad
+	}
+	rest = strings.ReplaceAll(rest, "→", "->")
+	rest, nextPart, _ := strings.Cut(rest, "->")
+
+	rest = strings.TrimSpace(rest)
+	open := strings.LastIndex(rest, "(")
+	if open < 0 || !strings.HasSuffix(rest, ")") {
+		return StatusDef{}, bad
+	}
+	emoji, name, _ := strings.Cut(strings.TrimSpace(rest[:open]), " ")
+
+	s := StatusDef{
+		Code:     strings.ToLower(strings.TrimSpace(code)),
+		Emoji:    emoji,
+		Name:     strings.TrimSpace(name),
+		Category: strings.ToLower(strings.TrimSpace(rest[open+1 : len(rest)-1])),
+	}
+	for _, next := range strings.Split(nextPart, ",") {
+		if next = strings.ToLower(strings.TrimSpace(next)); next != "" && !slices.Contains(s.Next, next) {
+			s.Next = append(s.Next, next)
+		}
+	}
+	return s, nil
+}
+
+// ============================================================
+// Миграция старых данных
+// Раньше в Task.Status сохранялась сама подпись ("🆕 Новая").
+// legacyStatuses переводит такие значения в коды.
+// ============================================================
+var legacyStatuses = map[string]string{
+	"🆕 Новая":     StatusNew,
+	"🔄 В работе":  StatusInProgress,
+	"✅ Выполнена": StatusDone,
+}
+
+// normalizeStatus возвращает код статуса для старого значения-подписи
+// Второе значение — true, если значение пришлось исправить
+func normalizeStatus(status string) (string, bool) {
+	if code, ok := legacyStatuses[strings.TrimSpace(status)]; ok {
+		return code, true
+	}
+	return status, false
+}
@@ -1,56 +1,129 @@
 package bot
 
 import (
+	"slices"
 	"sync"
 	"time"
 )
 
-// ============================================================
-// Статусы задач
-// Можешь изменить эмодзи и текст на свой вкус
-// ============================================================
-const (
-	StatusNew        = "🆕 Новая"
-	StatusInProgress = "🔄 В работе"
-	StatusDone       = "✅ Выполнена"
-)
-
 // ============================================================
 // Task — модель задачи
-// Позже сюда можно добавить новые поля:
-// - Deadline time.Time   // Дедлайн
-// - Priority int         // Приоритет (1-5)
-// - Assignee string      // Исполнитель
 // ============================================================
 type Task struct {
-	ID          int       `json:"id"`          // Уникальный номер задачи
-	Title       string    `json:"title"`       // Название
-	Description string    `json:"description"` // Описание (может быть пустым)
-	Status      string    `json:"status"`      // Текущий статус (одна из констант выше)
-	CreatedAt   time.Time `json:"created_at"`  // Когда задача была создана
+	ID          int        `json:"id"`                 // Уникальный номер задачи
+	Title       string     `json:"title"`              // Название
+	Description string     `json:"description"`        // Описание (может быть пустым)
+	Status      string     `json:"status"`             // Код текущего статуса (см. status.go)
+	Category    string     `json:"status_category"`    // Категория статуса (todo/doing/done) — копия из набора статусов
+	Priority    string     `json:"priority"`           // Код приоритета (см. priority.go)
+	CreatedAt   time.Time  `json:"created_at"`         // Когда задача была создана
+	Deadline    *time.Time `json:"deadline,omitempty"` // Срок выполнения (nil — без срока)
+	Tags        []string   `json:"tags,omitempty"`     // Теги без "#" (см. tags.go)
+
+	Checklist []ChecklistItem `json:"checklist,omitempty"`  // Пункты чек-листа (см. checklist.go)
+	BlockedBy []int           `json:"blocked_by,omitempty"` // ID задач, которые нужно выполнить раньше (см. dependencies.go)
+
+	Recurrence *Recurrence `json:"recurrence,omitempty"` // Правило повторения, nil — не повторяется (см. recurrence.go)
+
+	ProjectID int `json:"project_id"` // Проект задачи, 0 — «Входящие» (см. projects.go)
+
+	Assignee   int64 `json:"assignee,omitempty"`    // Исполнитель (ID пользователя Telegram), 0 — не назначен (см. assignees.go)
+	AssignedBy int64 `json:"assigned_by,omitempty"` // Кто назначил исполнителя
+
+	Source *TaskSource `json:"source,omitempty"` // Пересланное сообщение, из которого сделана задача (см. forward.go)
+
+	// Служебные поля планировщика напоминаний (см. scheduler.go)
+	RemindersSent []string   `json:"reminders_sent,omitempty"` // Какие напоминания по текущему сроку уже отправлены
+	SnoozedUntil  *time.Time `json:"snoozed_until,omitempty"`  // Напоминания отложены до этого момента
+
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // Когда задачу убрали в корзину (nil — не в корзине, см. trash.go)
+}
+
+// clone возвращает копию задачи, не делящую с оригиналом срезы
+func (t Task) clone() Task {
+	t.Tags = append([]string(nil), t.Tags...)
+	t.Checklist = append([]ChecklistItem(nil), t.Checklist...)
+	t.BlockedBy = append([]int(nil), t.BlockedBy...)
+	t.RemindersSent = append([]string(nil), t.RemindersSent...)
+	return t
+}
+
+// IsDone — задача в завершающем статусе (категория done)
+// Категорию хранит сама задача, поэтому её можно проверить,
+// не зная набора статусов пользователя
+func (t Task) IsDone() bool {
+	return t.Category == CategoryDone
+}
+
+// setStatus переводит задачу в статус из набора
+func (t *Task) setStatus(status StatusDef) {
+	t.Status = status.Code
+	t.Category = status.Category
+}
+
+// IsOverdue — срок задачи прошёл, а она ещё не выполнена
+func (t Task) IsOverdue(now time.Time) bool {
+	return t.Deadline != nil && !t.IsDone() && t.Deadline.Before(now)
 }
 
 // ============================================================
 // Storage — хранилище задач в оперативной памяти
+// Одна из реализаций интерфейса TaskStore (см. store.go)
 //
-// Сейчас данные хранятся в map (словарь/хеш-таблица):
-//   ключ   = ID пользователя Telegram (int64)
-//   значение = срез (slice) его задач
+// Сейчас данные хранятся в map (словарь/хеш-таблица): ключ — ID
+// пользователя Telegram (int64) или общего пространства (см. workspaces.go),
+// значение — срез (slice) его задач
 //
 // ⚠️ При перезапуске бота все данные теряются!
-// Позже заменим на PostgreSQL
+// Для сохранения на диск используй FileStore (filestore.go)
 // ============================================================
 type Storage struct {
 	tasks  map[int64][]Task // Задачи каждого пользователя
+	trash  map[int64][]Task // Удалённые задачи в корзине (см. trash.go)
 	nextID map[int64]int    // Счётчик ID задач для каждого пользователя
 	mu     sync.RWMutex     // RWMutex позволяет нескольким горутинам читать одновременно
+
+	// Наборы статусов пользователей (см. status.go); нет записи — DefaultWorkflow
+	workflows map[int64]Workflow
+
+	// Проекты пользователей в порядке создания (см. projects.go)
+	projects map[int64][]Project
+
+	// Общие пространства команд в порядке создания (см. workspaces.go)
+	workspaces []Workspace
+
+	// История изменений: пользователь → ID задачи → события (см. history.go)
+	// Хранится отдельно от задач, поэтому переживает их удаление
+	history map[int64]map[int][]TaskEvent
+
+	// Комментарии: пользователь → ID задачи → комментарии (см. comments.go)
+	comments map[int64]map[int][]Comment
+
+	// Вложения: пользователь → ID задачи → вложения (см. attachments.go)
+	attachments map[int64]map[int][]Attachment
+
+	// Учёт времени: пользователь → ID задачи → записи (см. timetracking.go)
+	timeEntries map[int64]map[int][]TimeEntry
+
+	// onChange вызывается после каждого изменения (под блокировкой mu)
+	// Через него FileStore записывает изменения в журнал; для чистой памяти — nil
+	onChange func(change)
 }
 
 // NewStorage создаёт пустое хранилище
 func NewStorage() *Storage {
 	return &Storage{
-		tasks:  make(map[int64][]Task),
-		nextID: make(map[int64]int),
+		tasks:   make(map[int64][]Task),
+		trash:   make(map[int64][]Task),
+		nextID:  make(map[int64]int),
+		history: make(map[int64]map[int][]TaskEvent),
+
+		comments:  make(map[int64]map[int][]Comment),
+		workflows: make(map[int64]Workflow),
+		projects:  make(map[int64][]Project),
+
+		attachments: make(map[int64]map[int][]Attachment),
+		timeEntries: make(map[int64]map[int][]TimeEntry),
 	}
 }
 
@@ -58,86 +131,1363 @@ func NewStorage() *Storage {
 // AddTask добавляет новую задачу для пользователя
 // Возвращает созданную задачу
 // ============================================================
-func (s *Storage) AddTask(userID int64, title, description string) Task {
+func (s *Storage) AddTask(userID, actor int64, draft TaskDraft) (Task, error) {
+	if err := draft.Validate(); err != nil {
+		return Task{}, err
+	}
+
 	s.mu.Lock()         // Блокируем запись (другие горутины ждут)
 	defer s.mu.Unlock() // Разблокируем при выходе из функции
 
+	if err := checkProject(s.projects[userID], draft.ProjectID); err != nil {
+		return Task{}, err
+	}
+
 	// Увеличиваем счётчик и получаем новый ID
 	s.nextID[userID]++
 	id := s.nextID[userID]
 
-	task := Task{
-		ID:          id,
-		Title:       title,
-		Description: description,
-		Status:      StatusNew,       // Новая задача всегда имеет статус "Новая"
-		CreatedAt:   time.Now(),
-	}
+	now := time.Now()
+	task := draft.newTask(id, now, s.workflow(userID).Initial())
 
 	// append добавляет элемент в конец среза
 	s.tasks[userID] = append(s.tasks[userID], task)
-	return task
+	s.emit(change{Op: opAdd, UserID: userID, TaskID: id, Task: &task,
+		Events: taskEvents(nil, &task, actor, now)})
+	return task, nil
 }
 
 // ============================================================
 // GetTasks возвращает все задачи пользователя
+// Возвращаем копию среза, чтобы вызывающий код не читал его
+// одновременно с изменением из другой горутины
 // ============================================================
-func (s *Storage) GetTasks(userID int64) []Task {
-	s.mu.RLock()         // Блокируем только чтение (другие читатели не ждут)
+func (s *Storage) GetTasks(userID int64) ([]Task, error) {
+	s.mu.RLock() // Блокируем только чтение (другие читатели не ждут)
 	defer s.mu.RUnlock()
 
-	return s.tasks[userID]
+	tasks := make([]Task, len(s.tasks[userID]))
+	copy(tasks, s.tasks[userID])
+	return tasks, nil
 }
 
 // ============================================================
 // GetTask возвращает одну задачу по ID
-// Второе значение (bool) говорит, найдена ли задача
+// Если задачи нет — возвращает ErrTaskNotFound
 // ============================================================
-func (s *Storage) GetTask(userID int64, taskID int) (Task, bool) {
+func (s *Storage) GetTask(userID int64, taskID int) (Task, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	for _, task := range s.tasks[userID] {
 		if task.ID == taskID {
-			return task, true // Нашли!
+			return task, nil // Нашли!
 		}
 	}
-	return Task{}, false // Не нашли
+	return Task{}, ErrTaskNotFound // Не нашли
+}
+
+// ============================================================
+// UpdateTask частично изменяет задачу (см. TaskPatch)
+// Возвращает обновлённую задачу или ErrTaskNotFound
+// ============================================================
+func (s *Storage) UpdateTask(userID int64, taskID int, actor int64, patch TaskPatch) (Task, error) {
+	if err := patch.Validate(); err != nil {
+		return Task{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.modify(userID, taskID, actor, opUpdate, func(task *Task) error {
+		// Переносить задачу можно только в существующий проект не из архива;
+		// если проект не меняется — не проверяем
+		if patch.ProjectID != nil && *patch.ProjectID != task.ProjectID {
+			if err := checkProject(s.projects[userID], *patch.ProjectID); err != nil {
+				return err
+			}
+		}
+		if patch.Assignee != nil {
+			if err := checkAssignee(s.members(userID), *patch.Assignee); err != nil {
+				return err
+			}
+		}
+		patch.apply(task)
+		return nil
+	})
 }
 
 // ============================================================
 // UpdateStatus меняет статус задачи
-// Возвращает true, если задача найдена и обновлена
+// Если задачи нет — возвращает ErrTaskNotFound,
+// если её ещё блокируют невыполненные задачи — *BlockedError.
+// Выполненная повторяющаяся задача порождает следующую (см. recurrence.go)
 // ============================================================
-func (s *Storage) UpdateStatus(userID int64, taskID int, newStatus string) bool {
+func (s *Storage) UpdateStatus(userID int64, taskID int, actor int64, newStatus string) (StatusChange, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for i, task := range s.tasks[userID] {
-		if task.ID == taskID {
-			s.tasks[userID][i].Status = newStatus
-			return true
+	current, ok := findTask(s.tasks[userID], taskID)
+	if !ok {
+		return StatusChange{}, ErrTaskNotFound
+	}
+	wf := s.workflow(userID)
+	target, err := wf.transition(current.Status, newStatus)
+	if err != nil {
+		return StatusChange{}, err
+	}
+	if target.Category == CategoryDone {
+		if blockers := OpenBlockers(s.tasks[userID], current); len(blockers) > 0 {
+			return StatusChange{}, &BlockedError{Blockers: blockers}
+		}
+	}
+
+	now := time.Now()
+	var next Task
+	hasNext := false
+	task, err := s.modify(userID, taskID, actor, opStatus, func(task *Task) error {
+		// Следующее повторение создаём только при завершении задачи;
+		// правило переезжает в новую задачу
+		if target.Category == CategoryDone && !task.IsDone() {
+			next, hasNext = task.nextOccurrence(now, wf.Initial())
+			task.Recurrence = nil
+		}
+		task.setStatus(target)
+		return nil
+	})
+	if err != nil {
+		return StatusChange{}, err
+	}
+
+	result := StatusChange{Task: task}
+	// Повторное «Выполнено» ничего не разблокирует и не останавливает:
+	// это уже сделали, когда задачу завершили в первый раз
+	if target.Category == CategoryDone && !current.IsDone() {
+		result.Unblocked = unblockedBy(s.tasks[userID], taskID)
+		result.StoppedTimers = s.stopTimers(userID, taskID, now)
+	}
+	if hasNext {
+		s.nextID[userID]++
+		next.ID = s.nextID[userID]
+		s.tasks[userID] = append(s.tasks[userID], next)
+		s.emit(change{Op: opAdd, UserID: userID, TaskID: next.ID, Task: &next,
+			Events: taskEvents(nil, &next, actor, now)})
+		result.Next = &next
+	}
+	return result, nil
+}
+
+// ============================================================
+// DeleteTask убирает задачу в корзину (см. trash.go)
+// Если задачи нет — возвращает ErrTaskNotFound
+// Удалённая задача больше никого не блокирует
+// ============================================================
+func (s *Storage) DeleteTask(userID int64, taskID int, actor int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := findTask(s.tasks[userID], taskID)
+	if !ok {
+		return ErrTaskNotFound
+	}
+
+	now := time.Now()
+	trashed := task.clone()
+	trashed.DeletedAt = &now
+	s.trashTask(userID, trashed)
+	s.emit(change{Op: opTrash, UserID: userID, TaskID: taskID, Task: &trashed,
+		Events: taskEvents(&task, nil, actor, now)})
+
+	// Убираем удалённую задачу из зависимостей остальных
+	for _, other := range s.tasks[userID] {
+		if slices.Contains(other.BlockedBy, taskID) {
+			s.modify(userID, other.ID, actor, opUpdate, func(t *Task) error {
+				t.BlockedBy = slices.DeleteFunc(t.BlockedBy, func(id int) bool { return id == taskID })
+				return nil
+			})
+		}
+	}
+	return nil
+}
+
+// ============================================================
+// КОРЗИНА
+// GetTrash / RestoreTask / PurgeTrash — задачи в корзине,
+// их восстановление и окончательное удаление (см. trash.go)
+// ============================================================
+
+// GetTrash возвращает задачи в корзине: недавно удалённые — первыми
+func (s *Storage) GetTrash(userID int64) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := append([]Task(nil), s.trash[userID]...)
+	sortTrash(tasks)
+	return tasks, nil
+}
+
+// RestoreTask возвращает задачу из корзины
+// Если в корзине её нет — ErrTaskNotFound
+func (s *Storage) RestoreTask(userID int64, taskID int, actor int64) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := findTask(s.trash[userID], taskID)
+	if !ok {
+		return Task{}, ErrTaskNotFound
+	}
+
+	task = task.clone()
+	task.DeletedAt = nil
+	s.restoreTask(userID, task)
+	s.emit(change{Op: opRestore, UserID: userID, TaskID: taskID, Task: &task,
+		Events: []TaskEvent{restoredEvent(taskID, actor, time.Now())}})
+	return task, nil
+}
+
+// PurgeTrash навсегда удаляет задачи, попавшие в корзину раньше before
+// Возвращает количество удалённых задач
+func (s *Storage) PurgeTrash(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	purged := 0
+	for userID, tasks := range s.trash {
+		// Сначала выбираем ID: removeTask меняет срез, по которому идём
+		var expired []int
+		for _, task := range tasks {
+			if task.DeletedAt != nil && task.DeletedAt.Before(before) {
+				expired = append(expired, task.ID)
+			}
+		}
+		for _, id := range expired {
+			s.removeTask(userID, id)
+			delete(s.comments[userID], id)
+			delete(s.attachments[userID], id)
+			delete(s.timeEntries[userID], id)
+			s.emit(change{Op: opDelete, UserID: userID, TaskID: id,
+				Events: []TaskEvent{purgedEvent(id, now)}})
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// ============================================================
+// ЗАВИСИМОСТИ
+// AddDependency / RemoveDependency добавляют и убирают задачу
+// blockerID из тех, что блокируют taskID (см. dependencies.go)
+// ============================================================
+func (s *Storage) AddDependency(userID int64, taskID, blockerID int, actor int64) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkDependency(s.tasks[userID], taskID, blockerID); err != nil {
+		return Task{}, err
+	}
+	return s.modify(userID, taskID, actor, opUpdate, func(task *Task) error {
+		if !slices.Contains(task.BlockedBy, blockerID) {
+			task.BlockedBy = append(task.BlockedBy, blockerID)
+			slices.Sort(task.BlockedBy)
+		}
+		return nil
+	})
+}
+
+func (s *Storage) RemoveDependency(userID int64, taskID, blockerID int, actor int64) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.modify(userID, taskID, actor, opUpdate, func(task *Task) error {
+		task.BlockedBy = slices.DeleteFunc(task.BlockedBy, func(id int) bool { return id == blockerID })
+		return nil
+	})
+}
+
+// ============================================================
+// ЧЕК-ЛИСТ
+// AddChecklistItem / ToggleChecklistItem / RemoveChecklistItem
+// меняют пункты чек-листа задачи (см. checklist.go)
+// ============================================================
+func (s *Storage) AddChecklistItem(userID int64, taskID int, actor int64, text string) (ChecklistItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var item ChecklistItem
+	_, err := s.modify(userID, taskID, actor, opUpdate, func(task *Task) error {
+		var err error
+		item, err = task.addChecklistItem(text)
+		return err
+	})
+	return item, err
+}
+
+func (s *Storage) ToggleChecklistItem(userID int64, taskID, itemID int, actor int64) (ChecklistItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var item ChecklistItem
+	_, err := s.modify(userID, taskID, actor, opUpdate, func(task *Task) error {
+		var err error
+		item, err = task.toggleChecklistItem(itemID)
+		return err
+	})
+	return item, err
+}
+
+func (s *Storage) RemoveChecklistItem(userID int64, taskID, itemID int, actor int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.modify(userID, taskID, actor, opUpdate, func(task *Task) error {
+		return task.removeChecklistItem(itemID)
+	})
+	return err
+}
+
+// ============================================================
+// GetHistory возвращает события задачи от старых к новым
+// У удалённой задачи история остаётся; ErrTaskNotFound —
+// только если такой задачи не было вовсе
+// ============================================================
+func (s *Storage) GetHistory(userID int64, taskID int) ([]TaskEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := s.history[userID][taskID]
+	if len(events) == 0 {
+		if _, ok := findTask(s.tasks[userID], taskID); !ok {
+			return nil, ErrTaskNotFound
+		}
+	}
+	return append([]TaskEvent(nil), events...), nil
+}
+
+// GetStatusEvents возвращает смены статуса всех задач пользователя
+func (s *Storage) GetStatusEvents(userID int64) (map[int][]TaskEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[int][]TaskEvent)
+	for taskID, events := range s.history[userID] {
+		for _, e := range events {
+			if e.Type == EventStatusChanged {
+				result[taskID] = append(result[taskID], e)
+			}
+		}
+	}
+	return result, nil
+}
+
+// ============================================================
+// КОММЕНТАРИИ
+// AddComment / GetComments (см. comments.go)
+// ============================================================
+func (s *Storage) AddComment(userID int64, taskID int, author int64, text string) (Comment, error) {
+	comment, err := newComment(taskID, author, text, time.Now())
+	if err != nil {
+		return Comment{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := findTask(s.tasks[userID], taskID); !ok {
+		return Comment{}, ErrTaskNotFound
+	}
+	comment.ID = nextCommentID(s.comments[userID][taskID])
+	s.putComment(userID, comment)
+	s.emit(change{Op: opComment, UserID: userID, TaskID: taskID, Comment: &comment})
+	return comment, nil
+}
+
+func (s *Storage) GetComments(userID int64, taskID int) ([]Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := findTask(s.tasks[userID], taskID); !ok {
+		return nil, ErrTaskNotFound
+	}
+	return append([]Comment(nil), s.comments[userID][taskID]...), nil
+}
+
+// ============================================================
+// ВЛОЖЕНИЯ
+// AddAttachment / GetAttachments / RemoveAttachment (см. attachments.go)
+// ============================================================
+func (s *Storage) AddAttachment(userID int64, taskID int, att Attachment) (Attachment, error) {
+	att, err := newAttachment(taskID, att, time.Now())
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := findTask(s.tasks[userID], taskID); !ok {
+		return Attachment{}, ErrTaskNotFound
+	}
+	attachments := s.attachments[userID][taskID]
+	if len(attachments) >= maxAttachments {
+		return Attachment{}, ErrTooManyAttachments
+	}
+	att.ID = nextAttachmentID(attachments)
+	s.putAttachment(userID, att)
+	s.emit(change{Op: opAttach, UserID: userID, TaskID: taskID, Attachment: &att})
+	return att, nil
+}
+
+func (s *Storage) GetAttachments(userID int64, taskID int) ([]Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := findTask(s.tasks[userID], taskID); !ok {
+		return nil, ErrTaskNotFound
+	}
+	return append([]Attachment(nil), s.attachments[userID][taskID]...), nil
+}
+
+func (s *Storage) RemoveAttachment(userID int64, taskID, attachmentID int) (Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := findTask(s.tasks[userID], taskID); !ok {
+		return Attachment{}, ErrTaskNotFound
+	}
+	att, ok := FindAttachment(s.attachments[userID][taskID], attachmentID)
+	if !ok {
+		return Attachment{}, ErrAttachmentNotFound
+	}
+	s.removeAttachment(userID, taskID, attachmentID)
+	s.emit(change{Op: opDetach, UserID: userID, TaskID: taskID, Attachment: &att})
+	return att, nil
+}
+
+// ============================================================
+// УЧЁТ ВРЕМЕНИ
+// StartTimer / StopTimer / AddTimeEntry / GetTimeEntries /
+// RemoveTimeEntry (см. timetracking.go)
+// ============================================================
+func (s *Storage) StartTimer(userID int64, taskID int, member int64) (TimeEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := findTask(s.tasks[userID], taskID)
+	if !ok {
+		return TimeEntry{}, ErrTaskNotFound
+	}
+	if task.IsDone() {
+		return TimeEntry{}, ErrTimerTaskDone
+	}
+	entries := s.timeEntries[userID][taskID]
+	if _, running := RunningTimer(entries, member); running {
+		return TimeEntry{}, ErrTimerRunning
+	}
+	if len(entries) >= maxTimeEntries {
+		return TimeEntry{}, ErrTooManyTimeEntries
+	}
+	entry := newTimer(taskID, member, time.Now())
+	entry.ID = nextTimeEntryID(entries)
+	s.putTimeEntry(userID, entry)
+	s.emit(change{Op: opTime, UserID: userID, TaskID: taskID, TimeEntry: &entry})
+	return entry, nil
+}
+
+func (s *Storage) StopTimer(userID int64, taskID int, member int64) (TimeEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := findTask(s.tasks[userID], taskID); !ok {
+		return TimeEntry{}, ErrTaskNotFound
+	}
+	entry, running := RunningTimer(s.timeEntries[userID][taskID], member)
+	if !running {
+		return TimeEntry{}, ErrTimerNotRunning
+	}
+	now := time.Now()
+	entry.End = &now
+	s.putTimeEntry(userID, entry)
+	s.emit(change{Op: opTime, UserID: userID, TaskID: taskID, TimeEntry: &entry})
+	return entry, nil
+}
+
+func (s *Storage) AddTimeEntry(userID int64, taskID int, entry TimeEntry) (TimeEntry, error) {
+	entry, err := newManualEntry(taskID, entry, time.Now())
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := findTask(s.tasks[userID], taskID); !ok {
+		return TimeEntry{}, ErrTaskNotFound
+	}
+	entries := s.timeEntries[userID][taskID]
+	if len(entries) >= maxTimeEntries {
+		return TimeEntry{}, ErrTooManyTimeEntries
+	}
+	entry.ID = nextTimeEntryID(entries)
+	s.putTimeEntry(userID, entry)
+	s.emit(change{Op: opTime, UserID: userID, TaskID: taskID, TimeEntry: &entry})
+	return entry, nil
+}
+
+func (s *Storage) GetTimeEntries(userID int64, taskID int) ([]TimeEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := findTask(s.tasks[userID], taskID); !ok {
+		return nil, ErrTaskNotFound
+	}
+	return append([]TimeEntry(nil), s.timeEntries[userID][taskID]...), nil
+}
+
+func (s *Storage) RemoveTimeEntry(userID int64, taskID, entryID int) (TimeEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := findTask(s.tasks[userID], taskID); !ok {
+		return TimeEntry{}, ErrTaskNotFound
+	}
+	entries := s.timeEntries[userID][taskID]
+	i := slices.IndexFunc(entries, func(e TimeEntry) bool { return e.ID == entryID })
+	if i < 0 {
+		return TimeEntry{}, ErrTimeEntryNotFound
+	}
+	entry := entries[i]
+	s.removeTimeEntry(userID, taskID, entryID)
+	s.emit(change{Op: opTimeRemove, UserID: userID, TaskID: taskID, TimeEntry: &entry})
+	return entry, nil
+}
+
+// ============================================================
+// НАБОР СТАТУСОВ
+// GetWorkflow / SetWorkflow — статусы пользователя (см. status.go)
+// ============================================================
+func (s *Storage) GetWorkflow(userID int64) (Workflow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.workflow(userID).clone(), nil
+}
+
+// SetWorkflow заменяет набор статусов пользователя
+// Убрать статус, в котором есть задачи (в том числе в корзине), нельзя
+func (s *Storage) SetWorkflow(userID int64, wf Workflow) error {
+	if err := wf.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkStatusesInUse(wf, s.tasks[userID], s.trash[userID]); err != nil {
+		return err
+	}
+	wf = wf.clone()
+	s.setWorkflow(userID, wf)
+	s.emit(change{Op: opWorkflow, UserID: userID, Workflow: &wf})
+	return nil
+}
+
+// ============================================================
+// ПРОЕКТЫ
+// GetProjects / AddProject / UpdateProject / DeleteProject (см. projects.go)
+// ============================================================
+func (s *Storage) GetProjects(userID int64) ([]Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]Project(nil), s.projects[userID]...), nil
+}
+
+func (s *Storage) AddProject(userID int64, draft ProjectDraft) (Project, error) {
+	if err := draft.Validate(); err != nil {
+		return Project{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	project := draft.newProject(nextProjectID(s.projects[userID]), time.Now())
+	s.putProject(userID, project)
+	s.emit(change{Op: opProject, UserID: userID, Project: &project})
+	return project, nil
+}
+
+func (s *Storage) UpdateProject(userID int64, projectID int, patch ProjectPatch) (Project, error) {
+	if err := patch.Validate(); err != nil {
+		return Project{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.projects[userID], func(p Project) bool { return p.ID == projectID })
+	if i < 0 {
+		return Project{}, ErrProjectNotFound
+	}
+	project := s.projects[userID][i]
+	patch.apply(&project)
+	s.putProject(userID, project)
+	s.emit(change{Op: opProject, UserID: userID, Project: &project})
+	return project, nil
+}
+
+func (s *Storage) DeleteProject(userID int64, projectID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.ContainsFunc(s.projects[userID], func(p Project) bool { return p.ID == projectID }) {
+		return ErrProjectNotFound
+	}
+	s.removeProject(userID, projectID)
+	s.emit(change{Op: opProjectDelete, UserID: userID, ProjectID: projectID})
+	return nil
+}
+
+// ============================================================
+// ОБЩИЕ ПРОСТРАНСТВА
+// GetWorkspaces / GetWorkspace / CreateWorkspace / JoinWorkspace /
+// LeaveWorkspace / SetMemberRole (см. workspaces.go и permissions.go)
+// ============================================================
+func (s *Storage) GetWorkspaces(userID int64) ([]Workspace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []Workspace
+	for _, w := range s.workspaces {
+		if w.HasMember(userID) {
+			result = append(result, w.clone())
+		}
+	}
+	return result, nil
+}
+
+func (s *Storage) GetWorkspace(workspaceID int64) (Workspace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, w := range s.workspaces {
+		if w.ID == workspaceID {
+			return w.clone(), nil
+		}
+	}
+	return Workspace{}, ErrWorkspaceNotFound
+}
+
+func (s *Storage) CreateWorkspace(owner Member, name string) (Workspace, error) {
+	if err := validateWorkspaceName(name); err != nil {
+		return Workspace{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, err := newWorkspace(nextWorkspaceID(s.workspaces), owner, name, time.Now())
+	if err != nil {
+		return Workspace{}, err
+	}
+	s.putWorkspace(w)
+	s.emit(change{Op: opWorkspace, Workspace: &w})
+	return w.clone(), nil
+}
+
+func (s *Storage) JoinWorkspace(member Member, code string) (Workspace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.workspaces, func(w Workspace) bool { return w.InviteCode == code })
+	if i < 0 || code == "" {
+		return Workspace{}, ErrBadInvite
+	}
+	w := s.workspaces[i].clone()
+	// Уже участник — только обновляем имя, если оно сменилось
+	j := slices.IndexFunc(w.Members, func(m Member) bool { return m.UserID == member.UserID })
+	switch {
+	case j < 0:
+		member.Role = RoleMember
+		w.Members = append(w.Members, member)
+	case w.Members[j].Name != member.Name && member.Name != "":
+		w.Members[j].Name = member.Name
+	default:
+		return w, nil
+	}
+	s.putWorkspace(w)
+	s.emit(change{Op: opWorkspace, Workspace: &w})
+	return w.clone(), nil
+}
+
+func (s *Storage) LeaveWorkspace(userID, workspaceID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.workspaces, func(w Workspace) bool { return w.ID == workspaceID })
+	if i < 0 {
+		return ErrWorkspaceNotFound
+	}
+	if err := checkLeave(s.workspaces[i], userID); err != nil {
+		return err
+	}
+	w := s.workspaces[i].clone()
+	w.Members = slices.DeleteFunc(w.Members, func(m Member) bool { return m.UserID == userID })
+	s.putWorkspace(w)
+	s.emit(change{Op: opWorkspace, Workspace: &w})
+	return nil
+}
+
+func (s *Storage) SetMemberRole(actorID, workspaceID, userID int64, role Role) (Workspace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.workspaces, func(w Workspace) bool { return w.ID == workspaceID })
+	if i < 0 {
+		return Workspace{}, ErrWorkspaceNotFound
+	}
+	w := s.workspaces[i].clone()
+	if err := setRole(&w, actorID, userID, role); err != nil {
+		return Workspace{}, err
+	}
+	s.putWorkspace(w)
+	s.emit(change{Op: opWorkspace, Workspace: &w})
+	return w.clone(), nil
+}
+
+// ============================================================
+// GetTags возвращает теги пользователя с количеством задач
+// ============================================================
+func (s *Storage) GetTags(userID int64) ([]TagInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return countTags(s.tasks[userID]), nil
+}
+
+// ============================================================
+// DueTasks возвращает невыполненные задачи всех пользователей,
+// срок которых наступает не позже until (для напоминаний)
+// ============================================================
+func (s *Storage) DueTasks(until time.Time) ([]DueTask, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var due []DueTask
+	for userID, tasks := range s.tasks {
+		for _, task := range tasks {
+			if task.Deadline != nil && !task.IsDone() && !task.Deadline.After(until) {
+				due = append(due, DueTask{UserID: userID, Task: task})
+			}
 		}
 	}
-	return false
+	return due, nil
+}
+
+// ============================================================
+// MarkReminded запоминает, что напоминание key уже отправлено
+// Служебные поля в историю не попадают, поэтому actor не нужен (0)
+// ============================================================
+func (s *Storage) MarkReminded(userID int64, taskID int, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.modify(userID, taskID, 0, opUpdate, func(task *Task) error {
+		task.RemindersSent = append(task.RemindersSent, key)
+		return nil
+	})
+	return err
+}
+
+// ============================================================
+// SnoozeReminder откладывает напоминания по задаче до until
+// nil — снять откладывание
+// ============================================================
+func (s *Storage) SnoozeReminder(userID int64, taskID int, until *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.modify(userID, taskID, 0, opUpdate, func(task *Task) error {
+		task.SnoozedUntil = until
+		return nil
+	})
+	return err
+}
+
+// Close ничего не делает: в памяти нечего закрывать
+// Нужен, чтобы Storage удовлетворял интерфейсу TaskStore
+func (s *Storage) Close() error {
+	return nil
 }
 
 // ============================================================
-// DeleteTask удаляет задачу по ID
-// Возвращает true, если задача найдена и удалена
+// ЖУРНАЛ ИЗМЕНЕНИЙ
+// Используется FileStore: каждое изменение описывается структурой change,
+// которую можно записать на диск и потом "проиграть" заново
 // ============================================================
-func (s *Storage) DeleteTask(userID int64, taskID int) bool {
+
+// Типы изменений
+const (
+	opAdd     = "add"     // Задача создана
+	opStatus  = "status"  // Изменён статус задачи
+	opUpdate  = "update"  // Изменены поля задачи (название, описание...)
+	opTrash   = "trash"   // Задача убрана в корзину (Task — её состояние в корзине)
+	opRestore = "restore" // Задача возвращена из корзины
+	opDelete  = "delete"  // Задача удалена навсегда
+
+	opWorkflow = "workflow" // Изменён набор статусов пользователя
+
+	opProject       = "project"        // Проект создан или изменён (Project — его новое состояние)
+	opProjectDelete = "project_delete" // Проект удалён, его задачи — во «Входящих»
+
+	opWorkspace = "workspace" // Пространство создано или изменились участники (Workspace — новое состояние)
+
+	opComment = "comment" // Добавлен комментарий к задаче
+
+	opAttach = "attach" // К задаче приложен файл (Attachment)
+	opDetach = "detach" // Вложение убрано (Attachment — каким оно было)
+
+	opTime       = "time"        // Запись времени добавлена или таймер остановлен (TimeEntry — новое состояние)
+	opTimeRemove = "time_remove" // Запись времени удалена
+)
+
+// change — одно изменение хранилища
+// Task хранит состояние задачи ПОСЛЕ изменения, а события истории
+// пронумерованы, поэтому повторное применение того же изменения
+// ничего не ломает (идемпотентно)
+type change struct {
+	Op     string      `json:"op"`
+	UserID int64       `json:"user_id"`
+	TaskID int         `json:"task_id"`
+	Task   *Task       `json:"task,omitempty"`   // nil для удаления
+	Events []TaskEvent `json:"events,omitempty"` // События истории (см. history.go)
+
+	Workflow *Workflow `json:"workflow,omitempty"` // Новый набор статусов (для opWorkflow)
+
+	Project   *Project `json:"project,omitempty"`    // Проект (для opProject)
+	ProjectID int      `json:"project_id,omitempty"` // ID удалённого проекта (для opProjectDelete)
+
+	Workspace *Workspace `json:"workspace,omitempty"` // Пространство (для opWorkspace)
+
+	Comment *Comment `json:"comment,omitempty"` // Комментарий (для opComment)
+
+	Attachment *Attachment `json:"attachment,omitempty"` // Вложение (для opAttach и opDetach)
+
+	TimeEntry *TimeEntry `json:"time_entry,omitempty"` // Запись времени (для opTime и opTimeRemove)
+}
+
+// storageState — полное состояние хранилища (для снимков на диске)
+type storageState struct {
+	Tasks   map[int64][]Task              `json:"tasks"`
+	Trash   map[int64][]Task              `json:"trash,omitempty"`
+	NextID  map[int64]int                 `json:"next_id"`
+	History map[int64]map[int][]TaskEvent `json:"history,omitempty"`
+
+	Workflows map[int64]Workflow  `json:"workflows,omitempty"`
+	Projects  map[int64][]Project `json:"projects,omitempty"`
+
+	Workspaces []Workspace `json:"workspaces,omitempty"`
+
+	Comments map[int64]map[int][]Comment `json:"comments,omitempty"`
+
+	Attachments map[int64]map[int][]Attachment `json:"attachments,omitempty"`
+
+	TimeEntries map[int64]map[int][]TimeEntry `json:"time_entries,omitempty"`
+}
+
+// modify находит задачу, применяет fn к её копии и сохраняет результат
+// (вызывать под блокировкой mu). Копия нужна, чтобы срезы внутри задачи,
+// уже отданной наружу через GetTasks, не менялись у читателя "на лету".
+// actor — кто меняет задачу (его записываем в историю)
+func (s *Storage) modify(userID int64, taskID int, actor int64, op string, fn func(*Task) error) (Task, error) {
+	for i := range s.tasks[userID] {
+		if s.tasks[userID][i].ID == taskID {
+			before := s.tasks[userID][i]
+			task := before.clone()
+			if err := fn(&task); err != nil {
+				return Task{}, err
+			}
+			s.tasks[userID][i] = task
+			s.emit(change{Op: op, UserID: userID, TaskID: taskID, Task: &task,
+				Events: taskEvents(&before, &task, actor, time.Now())})
+			return task, nil
+		}
+	}
+	return Task{}, ErrTaskNotFound
+}
+
+// emit нумерует и сохраняет события истории, затем сообщает подписчику
+// об изменении (вызывать под блокировкой mu)
+func (s *Storage) emit(c change) {
+	for i := range c.Events {
+		events := s.history[c.UserID][c.Events[i].TaskID]
+		c.Events[i].ID = len(events) + 1
+		if len(events) > 0 {
+			c.Events[i].ID = events[len(events)-1].ID + 1
+		}
+		s.putEvent(c.UserID, c.Events[i])
+	}
+	if s.onChange != nil {
+		s.onChange(c)
+	}
+}
+
+// apply применяет изменение из журнала, не вызывая onChange
+func (s *Storage) apply(c change) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	for _, e := range c.Events {
+		s.putEvent(c.UserID, e)
+	}
+	switch c.Op {
+	case opAdd, opStatus, opUpdate:
+		if c.Task == nil {
+			return
+		}
+		s.putTask(c.UserID, *c.Task)
+	case opTrash:
+		if c.Task != nil {
+			s.trashTask(c.UserID, *c.Task)
+		}
+	case opRestore:
+		if c.Task != nil {
+			s.restoreTask(c.UserID, *c.Task)
+		}
+	case opDelete:
+		s.removeTask(c.UserID, c.TaskID)
+		delete(s.comments[c.UserID], c.TaskID)
+		delete(s.attachments[c.UserID], c.TaskID)
+		delete(s.timeEntries[c.UserID], c.TaskID)
+	case opWorkflow:
+		if c.Workflow != nil {
+			s.setWorkflow(c.UserID, *c.Workflow)
+		}
+	case opProject:
+		if c.Project != nil {
+			s.putProject(c.UserID, *c.Project)
+		}
+	case opProjectDelete:
+		s.removeProject(c.UserID, c.ProjectID)
+	case opWorkspace:
+		if c.Workspace != nil {
+			s.putWorkspace(c.Workspace.clone())
+		}
+	case opComment:
+		if c.Comment != nil {
+			s.putComment(c.UserID, *c.Comment)
+		}
+	case opAttach:
+		if c.Attachment != nil {
+			s.putAttachment(c.UserID, *c.Attachment)
+		}
+	case opDetach:
+		if c.Attachment != nil {
+			s.removeAttachment(c.UserID, c.TaskID, c.Attachment.ID)
+		}
+	case opTime:
+		if c.TimeEntry != nil {
+			s.putTimeEntry(c.UserID, *c.TimeEntry)
+		}
+	case opTimeRemove:
+		if c.TimeEntry != nil {
+			s.removeTimeEntry(c.UserID, c.TaskID, c.TimeEntry.ID)
+		}
+	}
+}
+
+// members — участники пространства (вызывать под блокировкой mu):
+// владелец личного пространства или участники команды
+func (s *Storage) members(space int64) []int64 {
+	if !IsShared(space) {
+		return []int64{space}
+	}
+	for _, w := range s.workspaces {
+		if w.ID == space {
+			return w.MemberIDs()
+		}
+	}
+	return nil
+}
+
+// putWorkspace вставляет или заменяет пространство (вызывать под блокировкой mu)
+// Участникам из старого журнала сразу проставляем роли (см. Workspace.Role)
+func (s *Storage) putWorkspace(w Workspace) {
+	w = w.withRoles()
+	for i := range s.workspaces {
+		if s.workspaces[i].ID == w.ID {
+			s.workspaces[i] = w
+			return
+		}
+	}
+	s.workspaces = append(s.workspaces, w)
+}
+
+// putProject вставляет или заменяет проект (вызывать под блокировкой mu)
+func (s *Storage) putProject(userID int64, project Project) {
+	for i := range s.projects[userID] {
+		if s.projects[userID][i].ID == project.ID {
+			s.projects[userID][i] = project
+			return
+		}
+	}
+	s.projects[userID] = append(s.projects[userID], project)
+}
+
+// removeProject удаляет проект и переносит его задачи — в списке
+// и в корзине — во «Входящие» (вызывать под блокировкой mu)
+func (s *Storage) removeProject(userID int64, projectID int) {
+	s.projects[userID] = slices.DeleteFunc(s.projects[userID], func(p Project) bool { return p.ID == projectID })
+	for _, tasks := range [][]Task{s.tasks[userID], s.trash[userID]} {
+		for i := range tasks {
+			if tasks[i].ProjectID == projectID {
+				tasks[i].ProjectID = InboxProjectID
+			}
+		}
+	}
+}
+
+// workflow — набор статусов пользователя (вызывать под блокировкой mu)
+func (s *Storage) workflow(userID int64) Workflow {
+	if wf, ok := s.workflows[userID]; ok {
+		return wf
+	}
+	return DefaultWorkflow()
+}
+
+// setWorkflow сохраняет набор статусов и обновляет категории задач
+// пользователя — в списке и в корзине (вызывать под блокировкой mu)
+func (s *Storage) setWorkflow(userID int64, wf Workflow) {
+	s.workflows[userID] = wf
+	for _, tasks := range [][]Task{s.tasks[userID], s.trash[userID]} {
+		for i := range tasks {
+			if status, ok := wf.Status(tasks[i].Status); ok {
+				tasks[i].Category = status.Category
+			}
+		}
+	}
+}
+
+// putTask вставляет или заменяет задачу (вызывать под блокировкой mu)
+// Счётчик nextID не даёт новым задачам получить уже занятый ID
+func (s *Storage) putTask(userID int64, task Task) {
+	if task.ID > s.nextID[userID] {
+		s.nextID[userID] = task.ID
+	}
+	for i := range s.tasks[userID] {
+		if s.tasks[userID][i].ID == task.ID {
+			s.tasks[userID][i] = task
+			return
+		}
+	}
+	s.tasks[userID] = append(s.tasks[userID], task)
+}
+
+// putEvent добавляет событие в историю задачи, если его там ещё нет
+// (вызывать под блокировкой mu)
+func (s *Storage) putEvent(userID int64, e TaskEvent) {
+	if s.history[userID] == nil {
+		s.history[userID] = make(map[int][]TaskEvent)
+	}
+	events := s.history[userID][e.TaskID]
+	if len(events) > 0 && events[len(events)-1].ID >= e.ID {
+		return // Уже есть (журнал проигрывается повторно)
+	}
+	s.history[userID][e.TaskID] = append(events, e)
+}
+
+// putComment добавляет комментарий, если его ещё нет (вызывать под блокировкой mu)
+func (s *Storage) putComment(userID int64, comment Comment) {
+	if s.comments[userID] == nil {
+		s.comments[userID] = make(map[int][]Comment)
+	}
+	comments := s.comments[userID][comment.TaskID]
+	if len(comments) > 0 && comments[len(comments)-1].ID >= comment.ID {
+		return // Уже есть (журнал проигрывается повторно)
+	}
+	s.comments[userID][comment.TaskID] = append(comments, comment)
+}
+
+// putAttachment добавляет вложение, если его ещё нет (вызывать под блокировкой mu)
+func (s *Storage) putAttachment(userID int64, att Attachment) {
+	if s.attachments[userID] == nil {
+		s.attachments[userID] = make(map[int][]Attachment)
+	}
+	attachments := s.attachments[userID][att.TaskID]
+	if len(attachments) > 0 && attachments[len(attachments)-1].ID >= att.ID {
+		return // Уже есть (журнал проигрывается повторно)
+	}
+	s.attachments[userID][att.TaskID] = append(attachments, att)
+}
+
+// removeAttachment убирает вложение задачи (вызывать под блокировкой mu)
+func (s *Storage) removeAttachment(userID int64, taskID, attachmentID int) {
+	attachments := s.attachments[userID][taskID]
+	for i, a := range attachments {
+		if a.ID == attachmentID {
+			s.attachments[userID][taskID] = slices.Delete(slices.Clone(attachments), i, i+1)
+			return
+		}
+	}
+}
+
+// putTimeEntry добавляет запись времени или заменяет запись с тем же ID
+// (таймер остановлен) — вызывать под блокировкой mu
+func (s *Storage) putTimeEntry(userID int64, entry TimeEntry) {
+	if s.timeEntries[userID] == nil {
+		s.timeEntries[userID] = make(map[int][]TimeEntry)
+	}
+	entries := slices.Clone(s.timeEntries[userID][entry.TaskID])
+	if i := slices.IndexFunc(entries, func(e TimeEntry) bool { return e.ID == entry.ID }); i >= 0 {
+		entries[i] = entry
+	} else {
+		entries = append(entries, entry)
+	}
+	s.timeEntries[userID][entry.TaskID] = entries
+}
+
+// removeTimeEntry удаляет запись времени задачи (вызывать под блокировкой mu)
+func (s *Storage) removeTimeEntry(userID int64, taskID, entryID int) {
+	s.timeEntries[userID][taskID] = slices.DeleteFunc(slices.Clone(s.timeEntries[userID][taskID]),
+		func(e TimeEntry) bool { return e.ID == entryID })
+}
+
+// stopTimers останавливает все таймеры задачи (вызывать под блокировкой mu)
+func (s *Storage) stopTimers(userID int64, taskID int, now time.Time) []TimeEntry {
+	var stopped []TimeEntry
+	for _, entry := range s.timeEntries[userID][taskID] {
+		if entry.Running() {
+			entry.End = &now
+			s.putTimeEntry(userID, entry)
+			s.emit(change{Op: opTime, UserID: userID, TaskID: taskID, TimeEntry: &entry})
+			stopped = append(stopped, entry)
+		}
+	}
+	return stopped
+}
+
+// removeTask удаляет задачу навсегда — и из списка, и из корзины
+// (вызывать под блокировкой mu)
+func (s *Storage) removeTask(userID int64, taskID int) {
+	isTask := func(t Task) bool { return t.ID == taskID }
+	s.tasks[userID] = slices.DeleteFunc(s.tasks[userID], isTask)
+	s.trash[userID] = slices.DeleteFunc(s.trash[userID], isTask)
+}
+
+// trashTask переносит задачу в корзину (вызывать под блокировкой mu)
+// Задачи в корзине тоже перестают зависеть от удалённой: если её
+// восстановят, связь всё равно уже потеряна — как и в SQLStore
+func (s *Storage) trashTask(userID int64, task Task) {
+	s.removeTask(userID, task.ID)
+	for i := range s.trash[userID] {
+		other := &s.trash[userID][i]
+		if slices.Contains(other.BlockedBy, task.ID) {
+			blockedBy := slices.DeleteFunc(slices.Clone(other.BlockedBy), func(id int) bool { return id == task.ID })
+			other.BlockedBy = blockedBy
+		}
+	}
+	if task.ID > s.nextID[userID] {
+		s.nextID[userID] = task.ID
+	}
+	s.trash[userID] = append(s.trash[userID], task)
+}
+
+// restoreTask возвращает задачу из корзины на её место в списке
+// (по порядку ID, т.е. создания) (вызывать под блокировкой mu)
+func (s *Storage) restoreTask(userID int64, task Task) {
+	s.removeTask(userID, task.ID)
 	tasks := s.tasks[userID]
-	for i, task := range tasks {
-		if task.ID == taskID {
-			// Удаляем элемент из среза:
-			// берём всё до элемента + всё после элемента
-			s.tasks[userID] = append(tasks[:i], tasks[i+1:]...)
-			return true
+	i := slices.IndexFunc(tasks, func(t Task) bool { return t.ID > task.ID })
+	if i < 0 {
+		i = len(tasks)
+	}
+	s.tasks[userID] = slices.Insert(tasks, i, task)
+}
+
+// exportState возвращает копию всего состояния хранилища
+func (s *Storage) exportState() storageState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st := storageState{
+		Tasks:   make(map[int64][]Task, len(s.tasks)),
+		Trash:   make(map[int64][]Task, len(s.trash)),
+		NextID:  make(map[int64]int, len(s.nextID)),
+		History: make(map[int64]map[int][]TaskEvent, len(s.history)),
+
+		Workflows: make(map[int64]Workflow, len(s.workflows)),
+		Projects:  make(map[int64][]Project, len(s.projects)),
+		Comments:  make(map[int64]map[int][]Comment, len(s.comments)),
+
+		Attachments: make(map[int64]map[int][]Attachment, len(s.attachments)),
+		TimeEntries: make(map[int64]map[int][]TimeEntry, len(s.timeEntries)),
+	}
+	for userID, tasks := range s.tasks {
+		st.Tasks[userID] = append([]Task(nil), tasks...)
+	}
+	for userID, tasks := range s.trash {
+		if len(tasks) > 0 {
+			st.Trash[userID] = append([]Task(nil), tasks...)
+		}
+	}
+	for userID, id := range s.nextID {
+		st.NextID[userID] = id
+	}
+	for userID, wf := range s.workflows {
+		st.Workflows[userID] = wf.clone()
+	}
+	for userID, projects := range s.projects {
+		st.Projects[userID] = append([]Project(nil), projects...)
+	}
+	for _, w := range s.workspaces {
+		st.Workspaces = append(st.Workspaces, w.clone())
+	}
+	for userID, byTask := range s.history {
+		st.History[userID] = make(map[int][]TaskEvent, len(byTask))
+		for taskID, events := range byTask {
+			st.History[userID][taskID] = append([]TaskEvent(nil), events...)
+		}
+	}
+	for userID, byTask := range s.comments {
+		st.Comments[userID] = make(map[int][]Comment, len(byTask))
+		for taskID, comments := range byTask {
+			st.Comments[userID][taskID] = append([]Comment(nil), comments...)
+		}
+	}
+	for userID, byTask := range s.attachments {
+		st.Attachments[userID] = make(map[int][]Attachment, len(byTask))
+		for taskID, attachments := range byTask {
+			st.Attachments[userID][taskID] = append([]Attachment(nil), attachments...)
+		}
+	}
+	for userID, byTask := range s.timeEntries {
+		st.TimeEntries[userID] = make(map[int][]TimeEntry, len(byTask))
+		for taskID, entries := range byTask {
+			st.TimeEntries[userID][taskID] = append([]TimeEntry(nil), entries...)
+		}
+	}
+	return st
+}
+
+// migrateLegacyStatuses переводит старые статусы-подписи ("🆕 Новая")
+// в коды статусов. Возвращает количество исправленных задач.
+func (s *Storage) migrateLegacyStatuses() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fixed := 0
+	for userID := range s.tasks {
+		for i := range s.tasks[userID] {
+			if code, ok := normalizeStatus(s.tasks[userID][i].Status); ok {
+				s.tasks[userID][i].Status = code
+				fixed++
+			}
+		}
+	}
+	return fixed
+}
+
+// fillDefaultPriorities ставит обычный приоритет задачам, сохранённым
+// до появления приоритетов. Возвращает количество исправленных задач.
+func (s *Storage) fillDefaultPriorities() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fixed := 0
+	for userID := range s.tasks {
+		for i := range s.tasks[userID] {
+			if s.tasks[userID][i].Priority == "" {
+				s.tasks[userID][i].Priority = PriorityNormal
+				fixed++
+			}
+		}
+	}
+	return fixed
+}
+
+// fillStatusCategories проставляет категорию статуса задачам, сохранённым
+// до появления наборов статусов. Возвращает количество исправленных задач.
+func (s *Storage) fillStatusCategories() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fixed := 0
+	for _, byUser := range []map[int64][]Task{s.tasks, s.trash} {
+		for userID, tasks := range byUser {
+			wf := s.workflow(userID)
+			for i := range tasks {
+				if tasks[i].Category != "" {
+					continue
+				}
+				if status, ok := wf.Status(tasks[i].Status); ok {
+					tasks[i].Category = status.Category
+					fixed++
+				}
+			}
+		}
+	}
+	return fixed
+}
+
+// importState заменяет состояние хранилища загруженным снимком
+func (s *Storage) importState(st storageState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tasks = make(map[int64][]Task, len(st.Tasks))
+	s.trash = make(map[int64][]Task, len(st.Trash))
+	s.nextID = make(map[int64]int, len(st.NextID))
+	s.history = make(map[int64]map[int][]TaskEvent, len(st.History))
+	s.comments = make(map[int64]map[int][]Comment, len(st.Comments))
+	s.attachments = make(map[int64]map[int][]Attachment, len(st.Attachments))
+	s.timeEntries = make(map[int64]map[int][]TimeEntry, len(st.TimeEntries))
+	s.workflows = make(map[int64]Workflow, len(st.Workflows))
+	s.projects = make(map[int64][]Project, len(st.Projects))
+	for userID, tasks := range st.Tasks {
+		s.tasks[userID] = append([]Task(nil), tasks...)
+	}
+	for userID, tasks := range st.Trash {
+		s.trash[userID] = append([]Task(nil), tasks...)
+	}
+	for userID, id := range st.NextID {
+		s.nextID[userID] = id
+	}
+	for userID, wf := range st.Workflows {
+		s.workflows[userID] = wf.clone()
+	}
+	for userID, projects := range st.Projects {
+		s.projects[userID] = append([]Project(nil), projects...)
+	}
+	s.workspaces = nil
+	for _, w := range st.Workspaces {
+		s.workspaces = append(s.workspaces, w.withRoles())
+	}
+	for userID, byTask := range st.History {
+		for _, events := range byTask {
+			for _, e := range events {
+				s.putEvent(userID, e)
+			}
+		}
+	}
+	for userID, byTask := range st.Comments {
+		for _, comments := range byTask {
+			for _, c := range comments {
+				s.putComment(userID, c)
+			}
+		}
+	}
+	for userID, byTask := range st.Attachments {
+		for _, attachments := range byTask {
+			for _, a := range attachments {
+				s.putAttachment(userID, a)
+			}
+		}
+	}
+	for userID, byTask := range st.TimeEntries {
+		for _, entries := range byTask {
+			for _, e := range entries {
+				s.putTimeEntry(userID, e)
+			}
 		}
 	}
-	return false
 }
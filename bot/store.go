@@ -0,0 +1,361 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// Ошибки хранилища
+// Обработчики бота и API сравнивают с ними через errors.Is,
+// чтобы отличить "задача не найдена" от сбоя хранилища
+// ============================================================
+var (
+	ErrTaskNotFound = errors.New("задача не найдена")
+	ErrEmptyTitle   = errors.New("название задачи не может быть пустым")
+	ErrBadPriority  = errors.New("неизвестный приоритет")
+)
+
+// ============================================================
+// TaskDraft — данные для создания новой задачи
+// ============================================================
+type TaskDraft struct {
+	Title       string
+	Description string
+	Deadline    *time.Time  // nil — без срока
+	Priority    string      // Код приоритета ("" — обычный)
+	Tags        []string    // Теги ("#матан" или "матан")
+	Recurrence  *Recurrence // Правило повторения (nil — не повторяется)
+	ProjectID   int         // Проект (0 — «Входящие», см. projects.go)
+	Source      *TaskSource // Исходное сообщение (nil — задача создана вручную, см. forward.go)
+}
+
+// Validate проверяет, что из черновика можно создать задачу
+func (d TaskDraft) Validate() error {
+	if strings.TrimSpace(d.Title) == "" {
+		return ErrEmptyTitle
+	}
+	if d.Priority != "" && !IsValidPriority(d.Priority) {
+		return ErrBadPriority
+	}
+	if _, err := normalizeTags(d.Tags); err != nil {
+		return err
+	}
+	if d.Recurrence != nil {
+		return d.Recurrence.Validate()
+	}
+	return nil
+}
+
+// newTask создаёт задачу из черновика (ID выдаёт хранилище)
+// status — первый статус из набора пользователя
+func (d TaskDraft) newTask(id int, now time.Time, status StatusDef) Task {
+	priority := d.Priority
+	if priority == "" {
+		priority = PriorityNormal
+	}
+	tags, _ := normalizeTags(d.Tags) // Уже проверено в Validate
+	return Task{
+		ID:          id,
+		Title:       d.Title,
+		Description: d.Description,
+		Status:      status.Code,
+		Category:    status.Category,
+		Priority:    priority,
+		CreatedAt:   now,
+		Deadline:    d.Deadline,
+		Tags:        tags,
+		Recurrence:  d.Recurrence,
+		ProjectID:   d.ProjectID,
+		Source:      d.Source,
+	}
+}
+
+// ============================================================
+// TaskPatch — частичное изменение задачи
+// nil означает "поле не меняется", поэтому можно передать
+// только то, что действительно нужно исправить
+// ============================================================
+type TaskPatch struct {
+	Title         *string
+	Description   *string
+	Deadline      *time.Time // Новый срок
+	ClearDeadline bool       // Убрать срок (важнее, чем Deadline)
+	Priority      *string    // Новый код приоритета
+	Tags          *[]string  // Новый набор тегов (заменяет старый; пустой — убрать все)
+
+	Recurrence      *Recurrence // Новое правило повторения
+	ClearRecurrence bool        // Убрать повторение (важнее, чем Recurrence)
+
+	ProjectID *int // Перенести в другой проект (0 — во «Входящие»)
+
+	Assignee   *int64 // Назначить исполнителя (NoAssignee — снять), см. assignees.go
+	AssignedBy int64  // Кто назначает (вместе с Assignee)
+}
+
+// Validate проверяет, что изменение допустимо
+func (p TaskPatch) Validate() error {
+	if p.Title != nil && strings.TrimSpace(*p.Title) == "" {
+		return ErrEmptyTitle
+	}
+	if p.Priority != nil && !IsValidPriority(*p.Priority) {
+		return ErrBadPriority
+	}
+	if p.Tags != nil {
+		if _, err := normalizeTags(*p.Tags); err != nil {
+			return err
+		}
+	}
+	if p.Recurrence != nil && !p.ClearRecurrence {
+		return p.Recurrence.Validate()
+	}
+	return nil
+}
+
+// apply применяет изменение к задаче
+func (p TaskPatch) apply(task *Task) {
+	if p.Title != nil {
+		task.Title = *p.Title
+	}
+	if p.Description != nil {
+		task.Description = *p.Description
+	}
+	if p.ClearDeadline {
+		task.Deadline = nil
+	} else if p.Deadline != nil {
+		deadline := *p.Deadline
+		task.Deadline = &deadline
+	}
+	if p.Priority != nil {
+		task.Priority = *p.Priority
+	}
+	if p.Tags != nil {
+		task.Tags, _ = normalizeTags(*p.Tags)
+	}
+	if p.ClearRecurrence {
+		task.Recurrence = nil
+	} else if p.Recurrence != nil {
+		task.Recurrence = p.Recurrence
+	}
+	if p.ProjectID != nil {
+		task.ProjectID = *p.ProjectID
+	}
+	if p.Assignee != nil && *p.Assignee != task.Assignee {
+		task.Assignee = *p.Assignee
+		task.AssignedBy = p.AssignedBy
+		if task.Assignee == NoAssignee {
+			task.AssignedBy = NoAssignee
+		}
+	}
+	if p.changesDeadline() {
+		// Новый срок — напоминания по нему ещё не отправлялись
+		task.RemindersSent = nil
+		task.SnoozedUntil = nil
+	}
+}
+
+// changesDeadline — изменение затрагивает срок задачи
+func (p TaskPatch) changesDeadline() bool {
+	return p.ClearDeadline || p.Deadline != nil
+}
+
+// DueTask — задача вместе с ID её владельца
+// Нужна планировщику напоминаний, который обходит задачи всех пользователей
+type DueTask struct {
+	UserID int64
+	Task   Task
+}
+
+// StatusChange — результат смены статуса
+type StatusChange struct {
+	Task      Task   // Задача после изменения
+	Unblocked []Task // Задачи, у которых только что выполнен последний блокер (см. dependencies.go)
+	Next      *Task  // Следующее повторение (см. recurrence.go), если оно создано
+
+	StoppedTimers []TimeEntry // Таймеры, остановленные потому, что задача выполнена (см. timetracking.go)
+}
+
+// ============================================================
+// TaskStore — интерфейс хранилища задач
+//
+// Бот и HTTP API работают только через этот интерфейс,
+// поэтому хранилище можно заменить (память, файл, база данных)
+// или подставить тестовый двойник, не трогая обработчики.
+//
+// Все методы должны быть безопасны для вызова из разных горутин.
+//
+// userID в методах задач — ключ пространства: ID пользователя для
+// личных задач или отрицательный ID общего пространства команды
+// (см. workspaces.go). Права доступа проверяют бот и API.
+//
+// actor в методах, которые меняют задачи, — кто вносит изменение
+// (ID пользователя Telegram): его записываем в историю задачи
+// (см. history.go), в том числе в задачах команды.
+// ============================================================
+type TaskStore interface {
+	// AddTask создаёт задачу пользователя и возвращает её
+	// (ErrProjectNotFound, ErrProjectArchived — в проект draft.ProjectID нельзя)
+	AddTask(userID, actor int64, draft TaskDraft) (Task, error)
+
+	// GetTasks возвращает все задачи пользователя (копию, а не внутренний срез)
+	GetTasks(userID int64) ([]Task, error)
+
+	// GetTask возвращает задачу по ID или ErrTaskNotFound
+	GetTask(userID int64, taskID int) (Task, error)
+
+	// UpdateTask частично изменяет задачу (название, описание, срок, приоритет,
+	// теги, повторение, проект)
+	// и возвращает её новое состояние
+	UpdateTask(userID int64, taskID int, actor int64, patch TaskPatch) (Task, error)
+
+	// UpdateStatus меняет статус задачи или возвращает ErrTaskNotFound
+	// Статус и переход проверяются по набору статусов пользователя
+	// (ErrUnknownStatus, ErrBadTransition).
+	// Завершить задачу с невыполненными блокерами нельзя — *BlockedError.
+	// В результате — задачи, которые после этого больше ничего не ждут,
+	// и следующее повторение, если выполнена повторяющаяся задача
+	UpdateStatus(userID int64, taskID int, actor int64, newStatus string) (StatusChange, error)
+
+	// DeleteTask убирает задачу в корзину или возвращает ErrTaskNotFound
+	// (из зависимостей других задач она пропадает сразу)
+	DeleteTask(userID int64, taskID int, actor int64) error
+
+	// GetTrash возвращает задачи в корзине, недавно удалённые первыми
+	GetTrash(userID int64) ([]Task, error)
+
+	// RestoreTask возвращает задачу из корзины или ErrTaskNotFound,
+	// если в корзине её нет
+	RestoreTask(userID int64, taskID int, actor int64) (Task, error)
+
+	// PurgeTrash навсегда удаляет задачи всех пользователей, убранные
+	// в корзину раньше before, и возвращает их количество
+	PurgeTrash(before time.Time) (int, error)
+
+	// AddDependency отмечает, что taskID заблокирована задачей blockerID
+	// Возвращает ErrSelfDependency или ErrDependencyCycle, если связь недопустима
+	AddDependency(userID int64, taskID, blockerID int, actor int64) (Task, error)
+
+	// RemoveDependency убирает blockerID из блокеров задачи taskID
+	RemoveDependency(userID int64, taskID, blockerID int, actor int64) (Task, error)
+
+	// AddChecklistItem добавляет пункт в чек-лист задачи
+	AddChecklistItem(userID int64, taskID int, actor int64, text string) (ChecklistItem, error)
+
+	// ToggleChecklistItem переключает отметку "выполнено" у пункта
+	// и возвращает его новое состояние (или ErrItemNotFound)
+	ToggleChecklistItem(userID int64, taskID, itemID int, actor int64) (ChecklistItem, error)
+
+	// RemoveChecklistItem удаляет пункт чек-листа
+	RemoveChecklistItem(userID int64, taskID, itemID int, actor int64) error
+
+	// GetHistory возвращает историю изменений задачи от старых событий к новым
+	// (см. history.go); история удалённой задачи тоже доступна
+	GetHistory(userID int64, taskID int) ([]TaskEvent, error)
+
+	// GetStatusEvents возвращает смены статуса всех задач пользователя
+	// разом (ID задачи → события от старых к новым) — для статистики
+	GetStatusEvents(userID int64) (map[int][]TaskEvent, error)
+
+	// AddComment добавляет к задаче комментарий пользователя author
+	// (см. comments.go; ErrTaskNotFound, если задачи нет или она в корзине)
+	AddComment(userID int64, taskID int, author int64, text string) (Comment, error)
+
+	// GetComments возвращает комментарии задачи от старых к новым
+	GetComments(userID int64, taskID int) ([]Comment, error)
+
+	// AddAttachment прикладывает к задаче файл (см. attachments.go;
+	// ErrTaskNotFound, если задачи нет или она в корзине,
+	// ErrTooManyAttachments — вложений уже слишком много)
+	AddAttachment(userID int64, taskID int, att Attachment) (Attachment, error)
+
+	// GetAttachments возвращает вложения задачи от старых к новым
+	GetAttachments(userID int64, taskID int) ([]Attachment, error)
+
+	// RemoveAttachment убирает вложение и возвращает его (файл на диске,
+	// если он есть, удаляет вызывающий — см. FileVault)
+	RemoveAttachment(userID int64, taskID, attachmentID int) (Attachment, error)
+
+	// StartTimer запускает таймер участника member (см. timetracking.go;
+	// ErrTimerRunning — его таймер этой задачи уже идёт,
+	// ErrTimerTaskDone — задача выполнена)
+	StartTimer(userID int64, taskID int, member int64) (TimeEntry, error)
+
+	// StopTimer останавливает таймер участника member и возвращает
+	// готовую запись (ErrTimerNotRunning — таймер не запущен)
+	StopTimer(userID int64, taskID int, member int64) (TimeEntry, error)
+
+	// AddTimeEntry добавляет запись времени вручную (ErrBadTimeEntry —
+	// отрезок короче минуты, длиннее суток или ещё не закончился)
+	AddTimeEntry(userID int64, taskID int, entry TimeEntry) (TimeEntry, error)
+
+	// GetTimeEntries возвращает записи времени задачи от старых к новым
+	GetTimeEntries(userID int64, taskID int) ([]TimeEntry, error)
+
+	// RemoveTimeEntry удаляет запись времени и возвращает её
+	RemoveTimeEntry(userID int64, taskID, entryID int) (TimeEntry, error)
+
+	// GetWorkflow возвращает набор статусов пользователя
+	// (DefaultWorkflow, если он его не настраивал)
+	GetWorkflow(userID int64) (Workflow, error)
+
+	// SetWorkflow заменяет набор статусов пользователя (ErrBadWorkflow —
+	// набор неверный, ErrStatusInUse — убран статус, в котором есть задачи)
+	SetWorkflow(userID int64, wf Workflow) error
+
+	// GetProjects возвращает проекты пользователя (и архивные) в порядке
+	// создания; «Входящие» в список не входят — они есть всегда
+	GetProjects(userID int64) ([]Project, error)
+
+	// AddProject создаёт проект
+	AddProject(userID int64, draft ProjectDraft) (Project, error)
+
+	// UpdateProject переименовывает проект, меняет значок или убирает
+	// в архив (ErrProjectNotFound, если проекта нет)
+	UpdateProject(userID int64, projectID int, patch ProjectPatch) (Project, error)
+
+	// DeleteProject удаляет проект; его задачи (и в корзине тоже)
+	// переезжают во «Входящие»
+	DeleteProject(userID int64, projectID int) error
+
+	// GetWorkspaces возвращает общие пространства, в которых состоит
+	// пользователь, в порядке создания
+	GetWorkspaces(userID int64) ([]Workspace, error)
+
+	// GetWorkspace возвращает пространство по ID или ErrWorkspaceNotFound
+	GetWorkspace(workspaceID int64) (Workspace, error)
+
+	// CreateWorkspace создаёт пространство; создатель — первый участник
+	CreateWorkspace(owner Member, name string) (Workspace, error)
+
+	// JoinWorkspace добавляет пользователя в пространство по коду
+	// приглашения (ErrBadInvite — кода нет); повторное вступление — не
+	// ошибка, а только обновляет имя участника
+	JoinWorkspace(member Member, code string) (Workspace, error)
+
+	// LeaveWorkspace убирает пользователя из пространства
+	// (ErrWorkspaceNotFound, если он в нём не состоит); задачи остаются.
+	// Владелец выходит последним (иначе ErrOwnerLeave)
+	LeaveWorkspace(userID, workspaceID int64) error
+
+	// SetMemberRole меняет роль участника userID по просьбе actorID
+	// (ErrForbidden, если роли actorID не хватает — см. permissions.go)
+	SetMemberRole(actorID, workspaceID, userID int64, role Role) (Workspace, error)
+
+	// GetTags возвращает теги пользователя с количеством задач,
+	// популярные первыми
+	GetTags(userID int64) ([]TagInfo, error)
+
+	// DueTasks возвращает невыполненные задачи всех пользователей
+	// со сроком не позже until (для планировщика напоминаний)
+	DueTasks(until time.Time) ([]DueTask, error)
+
+	// MarkReminded запоминает, что напоминание key по задаче отправлено
+	MarkReminded(userID int64, taskID int, key string) error
+
+	// SnoozeReminder откладывает напоминания по задаче до until (nil — отменить)
+	SnoozeReminder(userID int64, taskID int, until *time.Time) error
+
+	// Close освобождает ресурсы хранилища (файлы, соединения с БД)
+	Close() error
+}
@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ============================================================
+// ТЕГИ ЗАДАЧ
+//
+// Тег — короткая метка вроде "#матан" или "#работа". У задачи может
+// быть несколько тегов, один тег — у многих задач (многие-ко-многим).
+//
+// Храним теги без "#" и в нижнем регистре: "#Матан" и "#матан" — один тег.
+// В боте теги можно написать прямо в названии задачи:
+//   "Решить ДЗ #матан #срочно" → название "Решить ДЗ", теги матан, срочно
+// ============================================================
+
+// ErrBadTag — тег пустой, слишком длинный или содержит недопустимые символы
+var ErrBadTag = errors.New("неверный тег: допустимы буквы, цифры, «_» и «-», до 28 символов")
+
+// maxTagLength — ограничение длины тега в символах
+// Callback data в Telegram не длиннее 64 байт, а кириллица занимает
+// по 2 байта: "tag_" + 28 букв укладываются в лимит
+const maxTagLength = 28
+
+var (
+	reTagName = regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)
+	reHashtag = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_-]+)`)
+)
+
+// TagInfo — тег и количество задач с ним (для списка тегов)
+type TagInfo struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// NormalizeTag приводит тег к виду, в котором он хранится:
+// без "#", без пробелов по краям, в нижнем регистре
+func NormalizeTag(tag string) string {
+	tag = strings.TrimSpace(tag)
+	tag = strings.TrimPrefix(tag, "#")
+	return strings.ToLower(strings.ReplaceAll(tag, "ё", "е"))
+}
+
+// normalizeTags нормализует список тегов, убирает повторы
+// и сортирует по алфавиту (так теги одинаково выглядят в любом хранилище)
+func normalizeTags(tags []string) ([]string, error) {
+	var result []string
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if !reTagName.MatchString(tag) || utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, ErrBadTag
+		}
+		if !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// ExtractTags вынимает #хештеги из текста
+// Возвращает текст без хештегов и найденные теги
+// Если кроме хештегов в тексте ничего нет, текст остаётся как был
+func ExtractTags(text string) (string, []string) {
+	var tags []string
+	for _, m := range reHashtag.FindAllStringSubmatch(text, -1) {
+		tags = append(tags, m[1])
+	}
+	if len(tags) == 0 {
+		return text, nil
+	}
+
+	clean := strings.Join(strings.Fields(reHashtag.ReplaceAllString(text, " ")), " ")
+	if clean == "" {
+		clean = text
+	}
+	return clean, tags
+}
+
+// HasTag проверяет, есть ли у задачи тег
+func (t Task) HasTag(tag string) bool {
+	return slices.Contains(t.Tags, NormalizeTag(tag))
+}
+
+// FilterByTag возвращает задачи с указанным тегом
+// Используется и в списке задач бота, и в GET /api/tasks?tag=
+func FilterByTag(tasks []Task, tag string) []Task {
+	var result []Task
+	for _, task := range tasks {
+		if task.HasTag(tag) {
+			result = append(result, task)
+		}
+	}
+	return result
+}
+
+// countTags считает, сколько задач у каждого тега
+// Теги упорядочены по убыванию количества, затем по алфавиту
+func countTags(tasks []Task) []TagInfo {
+	counts := make(map[string]int)
+	for _, task := range tasks {
+		for _, tag := range task.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]TagInfo, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, TagInfo{Name: name, Count: count})
+	}
+	sortTagInfos(tags)
+	return tags
+}
+
+// sortTagInfos — популярные теги первыми, при равенстве — по алфавиту
+func sortTagInfos(tags []TagInfo) {
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Name < tags[j].Name
+	})
+}
+
+// formatTags — теги для сообщений: "#матан #работа"
+func formatTags(tags []string) string {
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = "#" + tag
+	}
+	return strings.Join(parts, " ")
+}
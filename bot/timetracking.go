@@ -0,0 +1,226 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================
+// УЧЁТ ВРЕМЕНИ
+//
+// Сколько времени ушло на лабораторную или заявку, считают записи
+// времени (TimeEntry) задачи. Запись появляется двумя способами:
+//   таймер  — «⏱ Запустить таймер»: запись без конца (End == nil),
+//             пока таймер идёт; «⏹ Остановить» ставит конец
+//   вручную — «➕ Добавить время»: готовый отрезок (Manual)
+//
+// У каждого участника — свой таймер задачи, у одного участника может
+// идти несколько таймеров разных задач. Когда задача выполнена,
+// хранилище само останавливает все её таймеры (см. UpdateStatus).
+//
+// Номера записей идут по порядку внутри задачи, как у комментариев;
+// записи переживают корзину, но удаляются вместе с задачей навсегда
+// ============================================================
+
+// Ограничения записей времени
+const (
+	maxTimeEntries  = 500            // Записей у одной задачи
+	maxTimeEntry    = 24 * time.Hour // Длина записи, добавленной вручную
+	maxTimeEntryGap = time.Minute    // Насколько конец записи может быть «в будущем» (часы у клиентов врут)
+	maxTimeNote     = 200            // Длина заметки к записи
+)
+
+// Ошибки учёта времени
+var (
+	ErrTimeEntryNotFound  = errors.New("запись времени не найдена")
+	ErrTimerRunning       = errors.New("таймер этой задачи уже идёт")
+	ErrTimerNotRunning    = errors.New("таймер этой задачи не запущен")
+	ErrTimerTaskDone      = errors.New("задача уже выполнена — таймер для неё не запустить")
+	ErrTooManyTimeEntries = fmt.Errorf("у задачи может быть не больше %d записей времени", maxTimeEntries)
+	ErrBadTimeEntry       = errors.New("укажи время от 1 минуты до 24 часов, например: 45м, 1ч 30м или 1:30")
+)
+
+// TimeEntry — отрезок времени, потраченный на задачу
+type TimeEntry struct {
+	ID     int        `json:"id"` // Номер в пределах задачи
+	TaskID int        `json:"task_id"`
+	UserID int64      `json:"user_id"` // Кто работал (Telegram ID)
+	Start  time.Time  `json:"start"`
+	End    *time.Time `json:"end,omitempty"`    // nil — таймер ещё идёт
+	Manual bool       `json:"manual,omitempty"` // Добавлена вручную, а не таймером
+	Note   string     `json:"note,omitempty"`
+}
+
+// Running — таймер записи ещё идёт
+func (e TimeEntry) Running() bool {
+	return e.End == nil
+}
+
+// Duration — длина записи; у идущего таймера — сколько прошло к now
+func (e TimeEntry) Duration(now time.Time) time.Duration {
+	end := now
+	if e.End != nil {
+		end = *e.End
+	}
+	if end.Before(e.Start) {
+		return 0
+	}
+	return end.Sub(e.Start)
+}
+
+// newTimer — запись для запуска таймера участником member (ID назначит хранилище)
+func newTimer(taskID int, member int64, now time.Time) TimeEntry {
+	return TimeEntry{TaskID: taskID, UserID: member, Start: now}
+}
+
+// newManualEntry проверяет запись, добавленную вручную:
+// отрезок от 1 минуты до 24 часов, закончившийся не позже now
+func newManualEntry(taskID int, entry TimeEntry, now time.Time) (TimeEntry, error) {
+	if entry.Start.IsZero() || entry.End == nil {
+		return TimeEntry{}, ErrBadTimeEntry
+	}
+	d := entry.End.Sub(entry.Start)
+	if d < time.Minute || d > maxTimeEntry || entry.End.After(now.Add(maxTimeEntryGap)) {
+		return TimeEntry{}, ErrBadTimeEntry
+	}
+	entry.TaskID = taskID
+	entry.Manual = true
+	entry.Note = truncate(strings.TrimSpace(entry.Note), maxTimeNote)
+	return entry, nil
+}
+
+// ManualEntry — запись «потратил d, закончил в end» для AddTimeEntry
+func ManualEntry(member int64, end time.Time, d time.Duration, note string) TimeEntry {
+	return TimeEntry{UserID: member, Start: end.Add(-d), End: &end, Note: note}
+}
+
+// nextTimeEntryID — номер следующей записи времени задачи
+func nextTimeEntryID(entries []TimeEntry) int {
+	if len(entries) == 0 {
+		return 1
+	}
+	return entries[len(entries)-1].ID + 1
+}
+
+// RunningTimer — идущий таймер участника member среди записей задачи
+func RunningTimer(entries []TimeEntry, member int64) (TimeEntry, bool) {
+	i := slices.IndexFunc(entries, func(e TimeEntry) bool { return e.Running() && e.UserID == member })
+	if i < 0 {
+		return TimeEntry{}, false
+	}
+	return entries[i], true
+}
+
+// ============================================================
+// ИТОГИ — всего и по дням
+// ============================================================
+
+// DayTime — сколько времени учтено за один день
+type DayTime struct {
+	Date     string // День в формате "2006-01-02" (в часовом поясе бота)
+	Duration time.Duration
+}
+
+// TimeSummary — итоги учёта времени задачи
+type TimeSummary struct {
+	Total   time.Duration
+	Days    []DayTime // По возрастанию даты
+	Running int       // Сколько таймеров идёт сейчас
+}
+
+// SummarizeTime считает итоги записей к моменту now
+// Запись, пересекающая полночь, делится между днями
+func SummarizeTime(entries []TimeEntry, now time.Time, loc *time.Location) TimeSummary {
+	var summary TimeSummary
+	byDay := make(map[string]time.Duration)
+	for _, e := range entries {
+		if e.Running() {
+			summary.Running++
+		}
+		summary.Total += e.Duration(now)
+
+		start, end := e.Start.In(loc), e.Start.Add(e.Duration(now)).In(loc)
+		for start.Before(end) {
+			y, m, d := start.Date()
+			midnight := time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+			chunkEnd := end
+			if midnight.Before(end) {
+				chunkEnd = midnight
+			}
+			byDay[start.Format("2006-01-02")] += chunkEnd.Sub(start)
+			start = chunkEnd
+		}
+	}
+	for date, d := range byDay {
+		summary.Days = append(summary.Days, DayTime{Date: date, Duration: d})
+	}
+	slices.SortFunc(summary.Days, func(a, b DayTime) int { return strings.Compare(a.Date, b.Date) })
+	return summary
+}
+
+// FormatTracked — учтённое время по-русски: "1 ч 20 мин", "45 мин", "0 мин"
+func FormatTracked(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	hours, minutes := minutes/60, minutes%60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%d мин", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%d ч", hours)
+	default:
+		return fmt.Sprintf("%d ч %d мин", hours, minutes)
+	}
+}
+
+// ============================================================
+// РАЗБОР ВРЕМЕНИ, ВВЕДЁННОГО ВРУЧНУЮ
+// "1:30", "1ч 30м", "1.5 часа", "45 мин", "90" (просто число — минуты)
+// ============================================================
+
+var (
+	clockDuration = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	partDuration  = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*(часов|часа|час|ч|h|минуты|минута|минут|мин|м|m)?`)
+)
+
+// ParseTrackedDuration разбирает длительность, введённую пользователем
+func ParseTrackedDuration(text string) (time.Duration, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if m := clockDuration.FindStringSubmatch(text); m != nil {
+		hours, _ := strconv.Atoi(m[1])
+		minutes, _ := strconv.Atoi(m[2])
+		return checkTracked(time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute)
+	}
+
+	var total time.Duration
+	rest := text
+	for _, m := range partDuration.FindAllStringSubmatch(text, -1) {
+		value, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+		if err != nil {
+			return 0, ErrBadTimeEntry
+		}
+		unit := time.Minute
+		if strings.HasPrefix(m[2], "ч") || m[2] == "h" {
+			unit = time.Hour
+		}
+		total += time.Duration(value * float64(unit))
+		rest = strings.Replace(rest, m[0], "", 1)
+	}
+	// Всё, кроме чисел с единицами, — лишнее: "завтра" или "1ч ну почти"
+	if total == 0 || strings.TrimSpace(rest) != "" {
+		return 0, ErrBadTimeEntry
+	}
+	return checkTracked(total.Round(time.Minute))
+}
+
+// checkTracked проверяет, что длительность годится для записи вручную
+func checkTracked(d time.Duration) (time.Duration, error) {
+	if d < time.Minute || d > maxTimeEntry {
+		return 0, ErrBadTimeEntry
+	}
+	return d, nil
+}
@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"log"
+	"slices"
+	"time"
+)
+
+// ============================================================
+// КОРЗИНА
+//
+// DeleteTask не стирает задачу, а убирает её в корзину (Task.DeletedAt),
+// поэтому случайное «✅ Да, удалить» можно отменить кнопкой
+// «↩️ Отменить» или восстановить задачу позже из /trash.
+//
+// Задача в корзине не видна в списке, поиске, тегах и напоминаниях
+// и сразу перестаёт блокировать другие задачи. Через trashRetention
+// после удаления фоновая очистка удаляет её навсегда (PurgeTrash);
+// история задачи при этом остаётся.
+// ============================================================
+
+// DefaultTrashRetention — сколько задача лежит в корзине по умолчанию
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// trashPurgeTick — как часто удалять из корзины задачи с истёкшим сроком
+const trashPurgeTick = time.Hour
+
+// sortTrash упорядочивает корзину: недавно удалённые — первыми
+func sortTrash(tasks []Task) {
+	slices.SortStableFunc(tasks, func(a, b Task) int {
+		return trashTime(b).Compare(trashTime(a))
+	})
+}
+
+// trashTime — когда задачу убрали в корзину (нулевое время, если не убирали)
+func trashTime(t Task) time.Time {
+	if t.DeletedAt == nil {
+		return time.Time{}
+	}
+	return *t.DeletedAt
+}
+
+// PurgeAt — когда задачу из корзины удалят навсегда
+func (t Task) PurgeAt(retention time.Duration) time.Time {
+	return trashTime(t).Add(retention)
+}
+
+// runTrashPurge удаляет старые задачи из корзины сразу после
+// запуска и затем каждые trashPurgeTick. Заодно стирает с диска
+// файлы вложений, которые больше никому не нужны (FileVault.Prune)
+func (b *Bot) runTrashPurge() {
+	ticker := time.NewTicker(trashPurgeTick)
+	defer ticker.Stop()
+
+	for {
+		purged, err := b.storage.PurgeTrash(time.Now().Add(-b.trashRetention))
+		if err != nil {
+			log.Printf("❌ Очистка корзины: ошибка хранилища: %v", err)
+		} else if purged > 0 {
+			log.Printf("🗑 Очистка корзины: удалено навсегда задач: %d", purged)
+		}
+		if b.files != nil {
+			if removed, err := b.files.Prune(b.storage); err != nil {
+				log.Printf("❌ Очистка файлов вложений: %v", err)
+			} else if removed > 0 {
+				log.Printf("🗑 Очистка файлов вложений: удалено файлов: %d", removed)
+			}
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ============================================================
+// ОБЩИЕ ПРОСТРАНСТВА (КОМАНДЫ)
+//
+// Задачи, проекты и статусы в хранилище лежат под ключом int64 —
+// «пространством». Личное пространство пользователя — его Telegram ID
+// (так было всегда, поэтому старые данные не нужно переносить).
+// Общее пространство команды получает отрицательный ID: ID
+// пользователей Telegram положительные, и ключи не пересекаются.
+//
+// Поэтому методы TaskStore по-прежнему принимают userID, но для
+// задач команды туда передаётся ID пространства. Бот и API сами
+// решают, с каким пространством сейчас работает пользователь, и
+// проверяют, что он в нём состоит (CheckAccess) и что его роль
+// позволяет действие (Authorize, см. permissions.go).
+//
+// Вступление — по ссылке-приглашению t.me/<бот>?start=join_<код>
+// ============================================================
+
+// maxWorkspaceNameRunes — ограничение длины названия пространства
+const maxWorkspaceNameRunes = 40
+
+// maxMemberNameRunes — до скольких символов обрезаем имя участника
+const maxMemberNameRunes = 40
+
+// joinPrefix — начало параметра /start в ссылке-приглашении
+const joinPrefix = "join_"
+
+// Ошибки общих пространств
+var (
+	ErrWorkspaceNotFound = errors.New("пространство не найдено")
+	ErrBadInvite         = errors.New("приглашение недействительно: попроси новую ссылку")
+	ErrBadWorkspaceName  = fmt.Errorf("название пространства — от 1 до %d символов", maxWorkspaceNameRunes)
+)
+
+// Workspace — общее пространство команды
+type Workspace struct {
+	ID         int64     `json:"id"` // Всегда отрицательный (см. выше)
+	Name       string    `json:"name"`
+	OwnerID    int64     `json:"owner_id"`    // Кто создал
+	InviteCode string    `json:"invite_code"` // Код для ссылки-приглашения
+	Members    []Member  `json:"members"`     // Участники в порядке вступления
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Member — участник пространства
+type Member struct {
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name,omitempty"` // Имя из Telegram (для списков участников)
+	Role   Role   `json:"role,omitempty"` // Что участнику можно (см. permissions.go)
+}
+
+// UnmarshalJSON читает и старый формат участника — просто ID
+// (так пространства хранились в журнале до появления имён)
+func (m *Member) UnmarshalJSON(data []byte) error {
+	var id int64
+	if err := json.Unmarshal(data, &id); err == nil {
+		*m = Member{UserID: id}
+		return nil
+	}
+	type plain Member // Без метода UnmarshalJSON — иначе рекурсия
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+// NewMember — участник с именем, обрезанным до разумной длины
+func NewMember(userID int64, name string) Member {
+	return Member{UserID: userID, Name: truncate(strings.TrimSpace(name), maxMemberNameRunes)}
+}
+
+// Label — имя участника для сообщений (если имени нет — ID)
+func (m Member) Label() string {
+	if m.Name != "" {
+		return m.Name
+	}
+	return MemberLabel(nil, m.UserID)
+}
+
+// Member возвращает участника пространства по ID пользователя
+func (w Workspace) Member(userID int64) (Member, bool) {
+	i := slices.IndexFunc(w.Members, func(m Member) bool { return m.UserID == userID })
+	if i < 0 {
+		return Member{}, false
+	}
+	return w.Members[i], true
+}
+
+// HasMember — состоит ли пользователь в пространстве
+func (w Workspace) HasMember(userID int64) bool {
+	_, ok := w.Member(userID)
+	return ok
+}
+
+// MemberIDs — ID участников в порядке вступления
+func (w Workspace) MemberIDs() []int64 {
+	ids := make([]int64, len(w.Members))
+	for i, m := range w.Members {
+		ids[i] = m.UserID
+	}
+	return ids
+}
+
+// clone возвращает копию пространства со своим срезом участников
+func (w Workspace) clone() Workspace {
+	w.Members = slices.Clone(w.Members)
+	return w
+}
+
+// IsShared — ключ хранилища принадлежит общему пространству, а не человеку
+func IsShared(space int64) bool {
+	return space < 0
+}
+
+// newWorkspace создаёт пространство с одним участником — создателем
+func newWorkspace(id int64, owner Member, name string, now time.Time) (Workspace, error) {
+	code, err := newInviteCode()
+	if err != nil {
+		return Workspace{}, err
+	}
+	owner.Role = RoleOwner
+	return Workspace{
+		ID:         id,
+		Name:       strings.TrimSpace(name),
+		OwnerID:    owner.UserID,
+		InviteCode: code,
+		Members:    []Member{owner},
+		CreatedAt:  now,
+	}, nil
+}
+
+// validateWorkspaceName проверяет название пространства
+func validateWorkspaceName(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxWorkspaceNameRunes {
+		return ErrBadWorkspaceName
+	}
+	return nil
+}
+
+// newInviteCode — случайный код приглашения (12 шестнадцатеричных символов)
+// Параметр /start допускает только латиницу, цифры, "_" и "-"
+func newInviteCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("код приглашения: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// nextWorkspaceID — ID нового пространства: на единицу меньше минимального
+func nextWorkspaceID(workspaces []Workspace) int64 {
+	id := int64(-1)
+	for _, w := range workspaces {
+		if w.ID <= id {
+			id = w.ID - 1
+		}
+	}
+	return id
+}
+
+// InviteLink — ссылка-приглашение в пространство
+func InviteLink(botUsername, code string) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s%s", botUsername, joinPrefix, code)
+}
+
+// ParseJoinPayload извлекает код приглашения из параметра /start
+// ("join_3fa85f64c2e1" → "3fa85f64c2e1")
+func ParseJoinPayload(payload string) (string, bool) {
+	code, found := strings.CutPrefix(strings.TrimSpace(payload), joinPrefix)
+	return code, found && code != ""
+}
+
+// CheckAccess проверяет, что пользователь может работать с задачами
+// пространства space: это его личное пространство или команда, в которой
+// он состоит. Иначе — ErrWorkspaceNotFound (не выдаём, что оно существует)
+func CheckAccess(store TaskStore, userID, space int64) error {
+	// Смотреть задачи может любой участник, даже наблюдатель
+	return Authorize(store, userID, space, PermView)
+}
+
+// Recipients — кому писать о задачах пространства (напоминания,
+// разблокировка): владельцу личного пространства или всем участникам команды
+func Recipients(store TaskStore, space int64) ([]int64, error) {
+	if !IsShared(space) {
+		return []int64{space}, nil
+	}
+	w, err := store.GetWorkspace(space)
+	if err != nil {
+		return nil, err
+	}
+	return w.MemberIDs(), nil
+}
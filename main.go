@@ -1,9 +1,17 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // База часовых поясов на случай, если в системе её нет
 
 	"mtuci-task-manager/api"
 	"mtuci-task-manager/bot"
@@ -33,6 +41,47 @@ func main() {
 		log.Printf("🌐 Mini App URL: %s", webAppURL)
 	}
 
+	// Часовой пояс, в котором бот понимает и показывает дедлайны
+	// (по умолчанию Москва)
+	tzName := os.Getenv("TIMEZONE")
+	if tzName == "" {
+		tzName = "Europe/Moscow"
+	}
+	location, err := time.LoadLocation(tzName)
+	if err != nil {
+		log.Fatalf("❌ Неизвестный часовой пояс TIMEZONE=%q: %v", tzName, err)
+	}
+
+	// За сколько до срока напоминать: список через запятую, например "24h,1h"
+	// (по умолчанию — за сутки и за час; "none" — только о просроченных)
+	reminderOffsets, err := parseOffsets(os.Getenv("REMINDER_OFFSETS"))
+	if err != nil {
+		log.Fatalf("❌ Неверный REMINDER_OFFSETS: %v", err)
+	}
+
+	// Сколько удалённые задачи лежат в корзине, например "720h"
+	// (по умолчанию 30 дней), затем удаляются навсегда
+	trashRetention, err := parseRetention(os.Getenv("TRASH_RETENTION"))
+	if err != nil {
+		log.Fatalf("❌ Неверный TRASH_RETENTION: %v", err)
+	}
+
+	// Папка для файлов, загруженных через Mini App (по умолчанию ./data/attachments)
+	// и сколько мегабайт может занять один пользователь (по умолчанию 100)
+	filesDir := os.Getenv("ATTACHMENTS_DIR")
+	if filesDir == "" {
+		filesDir = filepath.Join("data", "attachments")
+	}
+	filesQuota, err := parseQuota(os.Getenv("ATTACHMENTS_QUOTA_MB"))
+	if err != nil {
+		log.Fatalf("❌ Неверный ATTACHMENTS_QUOTA_MB: %v", err)
+	}
+	files, err := bot.NewFileVault(filesDir, filesQuota)
+	if err != nil {
+		log.Fatalf("❌ Ошибка открытия папки вложений: %v", err)
+	}
+	log.Printf("📎 Вложения: %s (до %s на пользователя)", filesDir, bot.FormatSize(files.Quota()))
+
 	// Порт HTTP-сервера (по умолчанию 8080)
 	port := os.Getenv("SERVER_PORT")
 	if port == "" {
@@ -42,13 +91,39 @@ func main() {
 	// ============================================================
 	// Создание общего хранилища задач
 	// Используется и ботом, и HTTP API
+	// Реализация выбирается переменной STORAGE_BACKEND
 	// ============================================================
-	storage := bot.NewStorage()
+	storage, err := openStorage(os.Getenv("STORAGE_BACKEND"))
+	if err != nil {
+		log.Fatalf("❌ Ошибка открытия хранилища: %v", err)
+	}
+	// Поисковый индекс поверх любого хранилища (/find в боте и
+	// /api/tasks/search): индекс живёт в памяти и строится при первом поиске
+	storage = bot.NewIndexedStore(storage)
+
+	// При Ctrl+C / SIGTERM аккуратно закрываем хранилище
+	// (файловое хранилище при этом сохраняет снимок на диск)
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		log.Println("🛑 Остановка...")
+		if err := storage.Close(); err != nil {
+			log.Printf("❌ Ошибка закрытия хранилища: %v", err)
+		}
+		os.Exit(0)
+	}()
 
 	// ============================================================
 	// Создание бота
 	// ============================================================
-	b, err := bot.New(token, storage, webAppURL)
+	b, err := bot.New(token, storage, bot.Options{
+		WebAppURL:       webAppURL,
+		Location:        location,
+		ReminderOffsets: reminderOffsets,
+		TrashRetention:  trashRetention,
+		Files:           files,
+	})
 	if err != nil {
 		log.Fatalf("❌ Ошибка создания бота: %v", err)
 	}
@@ -59,7 +134,18 @@ func main() {
 	//   - /api/*     — REST API для Mini App
 	//   - /*         — статические файлы фронтенда (папка web/)
 	// ============================================================
-	apiServer := api.NewServer(storage, token)
+	// Бот передаём как Notifier: изменения из Mini App тоже могут
+	// потребовать сообщения в чат (например, "задача разблокирована").
+	// Часовой пояс — тот же, что у бота (дни в правилах повторения).
+	// Через бота же API скачивает вложения, присланные в Telegram
+	apiServer := api.NewServer(storage, token, api.Options{
+		Notifier:       b,
+		Location:       location,
+		TrashRetention: trashRetention,
+		BotUsername:    b.Username(),
+		Files:          files,
+		Telegram:       b,
+	})
 	go func() {
 		router := apiServer.Router()
 		log.Printf("🌐 HTTP-сервер запущен на http://localhost:%s", port)
@@ -75,3 +161,110 @@ func main() {
 	log.Println("✅ Бот и HTTP-сервер запущены! Нажми Ctrl+C для остановки.")
 	b.Start()
 }
+
+// parseOffsets разбирает список интервалов "24h,1h,30m"
+// Пустая строка — интервалы по умолчанию (nil)
+func parseOffsets(value string) ([]time.Duration, error) {
+	switch strings.TrimSpace(value) {
+	case "":
+		return nil, nil
+	case "none":
+		return []time.Duration{}, nil
+	}
+	var offsets []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("интервал должен быть положительным: %q", part)
+		}
+		offsets = append(offsets, d)
+	}
+	return offsets, nil
+}
+
+// parseRetention разбирает срок хранения в корзине ("720h")
+// Пустая строка — срок по умолчанию (0)
+func parseRetention(value string) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("срок должен быть положительным: %q", value)
+	}
+	return d, nil
+}
+
+// parseQuota разбирает квоту на файлы в мегабайтах ("100")
+// Пустая строка — квота по умолчанию (0)
+func parseQuota(value string) (int64, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	mb, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if mb <= 0 {
+		return 0, fmt.Errorf("квота должна быть положительной: %q", value)
+	}
+	return mb << 20, nil
+}
+
+// ============================================================
+// openStorage создаёт хранилище задач по имени бэкенда
+//
+// Поддерживаемые значения STORAGE_BACKEND:
+// memory — в оперативной памяти (по умолчанию, данные теряются при перезапуске);
+// file — журнал + снимки на диске (папка STORAGE_DIR, по умолчанию ./data),
+// SNAPSHOT_EVERY — через сколько записей журнала делать снимок;
+// postgres — PostgreSQL по строке подключения DATABASE_URL;
+// sqlite — встроенный SQLite в файле SQLITE_PATH (по умолчанию ./data/tasks.db)
+// ============================================================
+func openStorage(backend string) (bot.TaskStore, error) {
+	switch backend {
+	case "", "memory":
+		log.Println("💾 Хранилище: в памяти (данные не сохраняются между перезапусками)")
+		return bot.NewStorage(), nil
+
+	case "file":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "data"
+		}
+		snapshotEvery := 0 // 0 — значение по умолчанию
+		if v := os.Getenv("SNAPSHOT_EVERY"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("неверный SNAPSHOT_EVERY %q", v)
+			}
+			snapshotEvery = n
+		}
+		return bot.NewFileStore(dir, snapshotEvery)
+
+	case "postgres":
+		databaseURL := os.Getenv("DATABASE_URL")
+		if databaseURL == "" {
+			return nil, fmt.Errorf("для STORAGE_BACKEND=postgres нужен DATABASE_URL")
+		}
+		return bot.OpenPostgres(databaseURL)
+
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "data/tasks.db"
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+		return bot.OpenSQLite(path)
+	default:
+		return nil, fmt.Errorf("неизвестный STORAGE_BACKEND %q", backend)
+	}
+}
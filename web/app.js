@@ -33,17 +33,9 @@ console.log('🔑 initData:', initData ? 'есть (' + initData.length + ' си
 async function api(method, path, body = null) {
     const options = {
         method,
-        headers: {
-            // Обход interstitial-страницы ngrok (бесплатный тариф)
-            'ngrok-skip-browser-warning': 'true',
-        },
+        headers: apiHeaders(),
     };
 
-    // Добавляем авторизацию (initData от Telegram)
-    if (initData) {
-        options.headers['Authorization'] = 'tma ' + initData;
-    }
-
     // Content-Type нужен только для запросов с телом (POST, PATCH)
     if (body) {
         options.headers['Content-Type'] = 'application/json';
@@ -71,11 +63,78 @@ async function api(method, path, body = null) {
     return data;
 }
 
+/**
+ * Заголовки для всех запросов к API: авторизация (initData от Telegram)
+ * и команда, задачами которой работаем (без неё — личные задачи)
+ */
+function apiHeaders() {
+    const headers = {
+        // Обход interstitial-страницы ngrok (бесплатный тариф)
+        'ngrok-skip-browser-warning': 'true',
+    };
+    if (initData) {
+        headers['Authorization'] = 'tma ' + initData;
+    }
+    if (activeWorkspace) {
+        headers['X-Workspace'] = activeWorkspace;
+    }
+    return headers;
+}
+
+/**
+ * Загрузить файл (multipart/form-data) — Content-Type с границей
+ * частей браузер проставит сам
+ */
+async function apiUpload(path, formData) {
+    console.log(`📡 POST ${API_BASE + path} (файл)`);
+    const response = await fetch(API_BASE + path, {
+        method: 'POST',
+        headers: apiHeaders(),
+        body: formData,
+    });
+    const data = await response.json().catch(() => ({}));
+    if (!response.ok) {
+        console.error('❌ API ошибка:', response.status, data);
+        throw new Error(data.error || 'Ошибка сервера');
+    }
+    return data;
+}
+
 // ============================================================
 // 3. СОСТОЯНИЕ ПРИЛОЖЕНИЯ
 // ============================================================
 let tasks = [];        // Массив задач пользователя
 let currentTask = null; // Текущая выбранная задача (для экрана деталей)
+let statuses = [];     // Статусы пользователя с сервера: [{code, label, category, next}, ...]
+let priorities = [];   // Список приоритетов с сервера: [{code, icon, label}, ...]
+let tags = [];         // Теги пользователя: [{name, count}, ...]
+let activeTag = '';    // Выбранный тег-фильтр ('' — все задачи)
+let projects = [];     // Проекты пользователя: [{id, label, archived, task_count}, ...] (первый — «Входящие»)
+let activeProject = null; // Выбранный проект (null — все проекты, 0 — «Входящие»)
+let searchQuery = '';  // Текст поиска ('' — без поиска)
+let searchTimer = null; // Таймер отложенного поиска (ждём, пока пользователь допечатает)
+let workspaces = [];   // Команды пользователя: [{id, name, members, role, invite_link}, ...]
+let activeWorkspace = localStorage.getItem('workspace') || ''; // ID команды ('' — личные задачи)
+let assignedView = '';  // 'to_me' — назначено мне, 'by_me' — назначено мной, '' — все задачи
+const myId = tg.initDataUnsafe && tg.initDataUnsafe.user ? tg.initDataUnsafe.user.id : null;
+
+// Роли в команде и что им можно (как на сервере, см. bot/permissions.go)
+const ROLE_LABELS = { owner: 'Владелец', admin: 'Администратор', member: 'Участник', viewer: 'Наблюдатель' };
+const ROLE_PERMISSIONS = {
+    owner: ['edit', 'delete', 'manage'],
+    admin: ['edit', 'delete', 'manage'],
+    member: ['edit'],
+    viewer: [],
+};
+
+// Готовые правила повторения (RRULE; часовой пояс подставит сервер)
+const RECURRENCE_PRESETS = [
+    { rule: '', label: 'Не повторять' },
+    { rule: 'FREQ=DAILY', label: 'Каждый день' },
+    { rule: 'FREQ=WEEKLY', label: 'Каждую неделю' },
+    { rule: 'FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR', label: 'По будням' },
+    { rule: 'FREQ=MONTHLY', label: 'Каждый месяц' },
+];
 
 // ============================================================
 // 4. УПРАВЛЕНИЕ ЭКРАНАМИ (навигация)
@@ -86,6 +145,7 @@ function showTaskList() {
     document.getElementById('task-list-view').classList.remove('hidden');
     document.getElementById('create-task-view').classList.add('hidden');
     document.getElementById('task-detail-view').classList.add('hidden');
+    document.getElementById('trash-view').classList.add('hidden');
     tg.BackButton.hide(); // На главном экране кнопка «Назад» не нужна
     loadTasks();
 }
@@ -95,11 +155,18 @@ function showCreateForm() {
     document.getElementById('task-list-view').classList.add('hidden');
     document.getElementById('create-task-view').classList.remove('hidden');
     document.getElementById('task-detail-view').classList.add('hidden');
+    document.getElementById('trash-view').classList.add('hidden');
     tg.BackButton.show(); // Показываем кнопку «Назад» в хедере Telegram
 
     // Очищаем форму
     document.getElementById('task-title').value = '';
     document.getElementById('task-description').value = '';
+    document.getElementById('task-deadline').value = '';
+    document.getElementById('task-tags').value = '';
+    document.getElementById('task-priority').value = 'normal';
+    document.getElementById('task-recurrence').innerHTML = renderRecurrenceOptions('');
+    // Новая задача — в выбранный проект (или во «Входящие»)
+    document.getElementById('task-project').innerHTML = renderProjectOptions(activeProject ?? 0);
     document.getElementById('task-title').focus();
 }
 
@@ -113,20 +180,146 @@ function showTaskDetail(taskId) {
     document.getElementById('task-list-view').classList.add('hidden');
     document.getElementById('create-task-view').classList.add('hidden');
     document.getElementById('task-detail-view').classList.remove('hidden');
+    document.getElementById('trash-view').classList.add('hidden');
     tg.BackButton.show();
 
     renderTaskDetail(task);
 }
 
+/** Показать корзину */
+function showTrash() {
+    document.getElementById('task-list-view').classList.add('hidden');
+    document.getElementById('create-task-view').classList.add('hidden');
+    document.getElementById('task-detail-view').classList.add('hidden');
+    document.getElementById('trash-view').classList.remove('hidden');
+    tg.BackButton.show();
+    loadTrash();
+}
+
 // ============================================================
 // 5. РЕНДЕРИНГ (отрисовка интерфейса)
 // ============================================================
 
+/** Отрисовать выбор пространства: личные задачи или команда */
+function renderWorkspaceSelect() {
+    const select = document.getElementById('workspace-select');
+    select.classList.toggle('hidden', workspaces.length === 0);
+    select.innerHTML = `
+        <option value="">👤 Личные задачи</option>
+        ${workspaces.map(w => `
+            <option value="${w.id}" ${String(w.id) === activeWorkspace ? 'selected' : ''}>
+                👥 ${escapeHtml(w.name)} (${w.members.length}) · ${ROLE_LABELS[w.role] || w.role}
+            </option>
+        `).join('')}
+    `;
+}
+
+/** Можно ли действие ('edit', 'delete', 'manage') в текущем пространстве */
+function can(action) {
+    const workspace = workspaces.find(w => String(w.id) === activeWorkspace);
+    if (!workspace) return true; // В личных задачах можно всё
+    return (ROLE_PERMISSIONS[workspace.role] || []).includes(action);
+}
+
+/** Участники текущей команды: [{user_id, name, role}, ...] (для личных задач — пусто) */
+function currentMembers() {
+    const workspace = workspaces.find(w => String(w.id) === activeWorkspace);
+    return workspace ? workspace.members : [];
+}
+
+/** Источник задачи из пересланного сообщения: "Иван (Группа БВТ) · 12.03.2026" */
+function sourceLabel(source) {
+    let label = source.sender;
+    if (source.chat && source.chat !== source.sender) label += ` (${source.chat})`;
+    return `${label} · ${formatDate(source.date)}`;
+}
+
+/** Имя исполнителя задачи ('' — не назначен) */
+function assigneeLabel(task) {
+    if (!task.assignee) return '';
+    const member = currentMembers().find(m => m.user_id === task.assignee);
+    return member && member.name ? member.name : 'id' + task.assignee;
+}
+
+/** Отрисовать фильтр «Назначено мне / мной» (только в команде) */
+function renderAssignedFilter() {
+    const filter = document.getElementById('assigned-filter');
+    filter.classList.toggle('hidden', !activeWorkspace || myId === null);
+    filter.innerHTML = `
+        <button class="tag-chip ${assignedView === '' ? 'active' : ''}" onclick="filterByAssigned('')">Все</button>
+        <button class="tag-chip ${assignedView === 'to_me' ? 'active' : ''}" onclick="filterByAssigned('to_me')">🙋 Назначено мне</button>
+        <button class="tag-chip ${assignedView === 'by_me' ? 'active' : ''}" onclick="filterByAssigned('by_me')">📤 Назначено мной</button>
+    `;
+}
+
+/** Отрисовать фильтр по проектам (проекты в архиве не показываем) */
+function renderProjectFilter() {
+    const filter = document.getElementById('project-filter');
+    filter.innerHTML = `
+        <button class="tag-chip ${activeProject === null ? 'active' : ''}" onclick="filterByProject(null)">Все</button>
+        ${projects.filter(p => !p.archived).map(p => `
+            <button class="tag-chip ${activeProject === p.id ? 'active' : ''}"
+                    onclick="filterByProject(${p.id})">
+                ${escapeHtml(p.label)} <span class="tag-count">${p.task_count}</span>
+            </button>
+        `).join('')}
+    `;
+}
+
+/** Варианты выбора проекта (<option>) — для формы и карточки задачи */
+function renderProjectOptions(current) {
+    return projects
+        .filter(p => !p.archived || p.id === current)
+        .map(p => `<option value="${p.id}" ${p.id === current ? 'selected' : ''}>${escapeHtml(p.label)}</option>`)
+        .join('');
+}
+
+/** Подпись проекта задачи */
+function projectLabel(task) {
+    const project = projects.find(p => p.id === task.project_id);
+    return project ? project.label : '📥 Входящие';
+}
+
+/** Отрисовать фильтр по тегам */
+function renderTagFilter() {
+    const filter = document.getElementById('tag-filter');
+    if (tags.length === 0) {
+        filter.classList.add('hidden');
+        return;
+    }
+    filter.classList.remove('hidden');
+    filter.innerHTML = `
+        <button class="tag-chip ${activeTag === '' ? 'active' : ''}" onclick="filterByTag('')">Все</button>
+        ${tags.map(t => `
+            <button class="tag-chip ${activeTag === t.name ? 'active' : ''}"
+                    onclick="filterByTag('${escapeHtml(t.name)}')">
+                #${escapeHtml(t.name)} <span class="tag-count">${t.count}</span>
+            </button>
+        `).join('')}
+    `;
+}
+
+/** Отрисовать теги задачи */
+function renderTags(task) {
+    if (!task.tags || task.tags.length === 0) return '';
+    return `<div class="task-tags">${task.tags.map(t => `<span class="tag">#${escapeHtml(t)}</span>`).join('')}</div>`;
+}
+
 /** Отрисовать список задач */
 function renderTasks() {
     const container = document.getElementById('tasks-container');
     const emptyState = document.getElementById('empty-state');
 
+    renderProjectFilter();
+    renderAssignedFilter();
+    renderTagFilter();
+
+    if (tasks.length === 0 && searchQuery) {
+        emptyState.classList.add('hidden');
+        container.classList.remove('hidden');
+        container.innerHTML = '<div class="loading">Ничего не нашлось</div>';
+        return;
+    }
     if (tasks.length === 0) {
         container.classList.add('hidden');
         emptyState.classList.remove('hidden');
@@ -139,10 +332,16 @@ function renderTasks() {
     container.innerHTML = tasks.map(task => `
         <div class="task-card" onclick="showTaskDetail(${task.id})">
             <div class="task-card-header">
-                <span class="task-card-title">${escapeHtml(task.title)}</span>
-                <span class="task-card-status">${escapeHtml(task.status)}</span>
+                <span class="task-card-title">${isBlocked(task) ? '⛔ ' : ''}${escapeHtml(priorityIcon(task))} ${escapeHtml(task.title)}</span>
+                <span class="task-card-status">${escapeHtml(task.status_label)}</span>
             </div>
             ${task.description ? `<div class="task-card-desc">${escapeHtml(task.description)}</div>` : ''}
+            ${renderDeadline(task, 'task-card-deadline')}
+            ${task.checklist_progress
+                ? `<div class="task-card-progress">☑️ ${task.checklist_progress.done}/${task.checklist_progress.total}</div>`
+                : ''}
+            ${renderTags(task)}
+            ${task.assignee ? `<div class="task-card-assignee">👤 ${escapeHtml(assigneeLabel(task))}</div>` : ''}
             <div class="task-card-date">${formatDate(task.created_at)}</div>
         </div>
     `).join('');
@@ -150,39 +349,193 @@ function renderTasks() {
 
 /** Отрисовать детали задачи */
 function renderTaskDetail(task) {
-    const statusKey = getStatusKey(task.status);
-
     const content = document.getElementById('task-detail-content');
     content.innerHTML = `
         <div class="task-detail-title">${escapeHtml(task.title)}</div>
-        <div class="task-detail-status">${escapeHtml(task.status)}</div>
+        <div class="task-detail-status">${escapeHtml(task.status_label)}</div>
+        <div class="task-detail-priority">Приоритет: ${escapeHtml(task.priority_label)}</div>
+        <div class="task-detail-project">Проект: ${escapeHtml(projectLabel(task))}</div>
+        ${renderDeadline(task, 'task-detail-deadline')}
+        ${task.recurrence_label
+            ? `<div class="task-detail-recurrence">🔁 ${escapeHtml(task.recurrence_label)}</div>`
+            : ''}
+        ${renderTags(task)}
         ${task.description
             ? `<div class="task-detail-desc">${escapeHtml(task.description)}</div>`
             : ''}
+        ${task.source
+            ? `<div class="task-detail-source">📨 Из сообщения: ${escapeHtml(sourceLabel(task.source))}</div>`
+            : ''}
         <div class="task-detail-date">Создана: ${formatDate(task.created_at)}</div>
 
+        ${renderChecklist(task)}
+
+        ${renderDependencies(task)}
+
         <div class="section-title">Изменить статус</div>
         <div class="task-actions">
-            <button class="btn-status ${statusKey === 'new' ? 'active' : ''}"
-                    onclick="changeStatus(${task.id}, 'new')">
-                🆕 Новая
-            </button>
-            <button class="btn-status ${statusKey === 'progress' ? 'active' : ''}"
-                    onclick="changeStatus(${task.id}, 'progress')">
-                🔄 В работе
-            </button>
-            <button class="btn-status ${statusKey === 'done' ? 'active' : ''}"
-                    onclick="changeStatus(${task.id}, 'done')">
-                ✅ Выполнена
+            ${allowedStatuses(task).map(s => `
+                <button class="btn-status ${task.status === s.code ? 'active' : ''}"
+                        onclick="changeStatus(${task.id}, '${s.code}')">
+                    ${escapeHtml(s.label)}
+                </button>
+            `).join('')}
+        </div>
+
+        <div class="section-title">Приоритет</div>
+        <div class="task-actions">
+            ${priorities.map(p => `
+                <button class="btn-status ${task.priority === p.code ? 'active' : ''}"
+                        onclick="changePriority(${task.id}, '${p.code}')">
+                    ${escapeHtml(p.label)}
+                </button>
+            `).join('')}
+        </div>
+
+        <div class="section-title">Проект</div>
+        <select class="recurrence-select" onchange="changeProject(${task.id}, this.value)">
+            ${renderProjectOptions(task.project_id)}
+        </select>
+
+        ${activeWorkspace ? `
+            <div class="section-title">Исполнитель</div>
+            <select class="recurrence-select" onchange="changeAssignee(${task.id}, this.value)">
+                <option value="0">— не назначен —</option>
+                ${currentMembers().map(m => `
+                    <option value="${m.user_id}" ${task.assignee === m.user_id ? 'selected' : ''}>
+                        👤 ${escapeHtml(m.name || 'id' + m.user_id)}
+                    </option>
+                `).join('')}
+            </select>
+        ` : ''}
+
+        <div class="section-title">Повтор</div>
+        <select class="recurrence-select" onchange="changeRecurrence(${task.id}, this.value)">
+            ${renderRecurrenceOptions(task.recurrence || '')}
+        </select>
+
+        <div class="section-title">Учёт времени</div>
+        <div id="task-time"></div>
+        ${can('edit') ? `
+            <form class="checklist-add" onsubmit="addTime(event, ${task.id})">
+                <input type="number" id="time-minutes" min="1" max="1440" placeholder="Минут вручную...">
+                <button type="submit" class="btn-secondary">Добавить</button>
+            </form>
+        ` : ''}
+
+        <div class="section-title">Вложения</div>
+        <div id="task-attachments"></div>
+        ${can('edit') ? `
+            <form class="checklist-add" onsubmit="uploadAttachment(event, ${task.id})">
+                <input type="file" id="attachment-file">
+                <button type="submit" class="btn-secondary">Загрузить</button>
+            </form>
+        ` : ''}
+
+        <div class="section-title">Обсуждение</div>
+        <div id="task-comments"></div>
+        ${can('edit') ? `
+            <form class="checklist-add" onsubmit="addComment(event, ${task.id})">
+                <input type="text" id="comment-new" placeholder="Комментарий...">
+                <button type="submit" class="btn-secondary">Отправить</button>
+            </form>
+        ` : ''}
+
+        <div class="section-title">История</div>
+        <div id="task-history">
+            <button class="btn-secondary" onclick="loadHistory(${task.id})">🕓 Показать историю</button>
+        </div>
+
+        ${can('delete') ? `
+            <button class="btn-delete" onclick="deleteTask(${task.id})">
+                🗑 Удалить задачу
             </button>
+        ` : ''}
+    `;
+    loadTime(task);
+    loadAttachments(task.id);
+    loadComments(task.id);
+}
+
+/** Отрисовать чек-лист задачи с формой добавления пункта */
+function renderChecklist(task) {
+    const items = task.checklist || [];
+    const progress = task.checklist_progress;
+    return `
+        <div class="section-title">Чек-лист${progress ? ` (${progress.done}/${progress.total})` : ''}</div>
+        <div class="checklist">
+            ${items.map(item => `
+                <div class="checklist-item ${item.done ? 'done' : ''}">
+                    <label>
+                        <input type="checkbox" ${item.done ? 'checked' : ''}
+                               onchange="toggleChecklistItem(${task.id}, ${item.id})">
+                        <span>${escapeHtml(item.text)}</span>
+                    </label>
+                    <button class="btn-item-remove" onclick="removeChecklistItem(${task.id}, ${item.id})">✕</button>
+                </div>
+            `).join('')}
+            <form class="checklist-add" onsubmit="addChecklistItem(event, ${task.id})">
+                <input type="text" id="checklist-new" placeholder="Новый пункт...">
+                <button type="submit" class="btn-secondary">Добавить</button>
+            </form>
         </div>
+    `;
+}
 
-        <button class="btn-delete" onclick="deleteTask(${task.id})">
-            🗑 Удалить задачу
-        </button>
+/** Отрисовать блокирующие задачи и выбор новой */
+function renderDependencies(task) {
+    const blockedBy = task.blocked_by || [];
+    // Кандидаты: невыполненные задачи, кроме самой задачи и уже выбранных
+    const candidates = tasks.filter(t =>
+        t.id !== task.id && !isDone(t) && !blockedBy.includes(t.id));
+    return `
+        <div class="section-title">Зависит от</div>
+        <div class="dependencies">
+            ${blockedBy.map(id => {
+                const blocker = tasks.find(t => t.id === id);
+                const title = blocker ? blocker.title : `Задача #${id}`;
+                const done = blocker && isDone(blocker);
+                return `
+                    <div class="dependency ${done ? 'done' : ''}">
+                        <span>${done ? '✅' : '⏳'} ${escapeHtml(title)}</span>
+                        <button class="btn-item-remove" onclick="removeDependency(${task.id}, ${id})">✕</button>
+                    </div>
+                `;
+            }).join('')}
+            ${candidates.length > 0 ? `
+                <select class="dependency-add" onchange="addDependency(${task.id}, this.value)">
+                    <option value="">+ Добавить блокирующую задачу...</option>
+                    ${candidates.map(t => `<option value="${t.id}">${escapeHtml(t.title)}</option>`).join('')}
+                </select>
+            ` : ''}
+        </div>
     `;
 }
 
+/** Варианты повтора для <select>; своё правило (из бота) показываем как есть */
+function renderRecurrenceOptions(current) {
+    // Сервер возвращает правило с часовым поясом — сравниваем без TZID
+    const rule = current.replace(/;TZID=[^;]*/, '');
+    const options = [...RECURRENCE_PRESETS];
+    if (rule && !options.some(p => p.rule === rule)) {
+        options.push({ rule: current, label: currentTask?.recurrence_label || rule });
+    }
+    return options.map(p => `
+        <option value="${escapeHtml(p.rule)}" ${p.rule === rule || p.rule === current ? 'selected' : ''}>
+            ${escapeHtml(p.label)}
+        </option>
+    `).join('');
+}
+
+/** Отрисовать срок задачи (просроченный — красным) */
+function renderDeadline(task, className) {
+    if (!task.deadline) return '';
+    if (isOverdue(task)) {
+        return `<div class="${className} overdue">🔥 Просрочено: ${formatDate(task.deadline)}</div>`;
+    }
+    return `<div class="${className}">⏰ До ${formatDate(task.deadline)}</div>`;
+}
+
 // ============================================================
 // 6. РАБОТА С API
 // ============================================================
@@ -190,8 +543,47 @@ function renderTaskDetail(task) {
 /** Загрузить задачи с сервера */
 async function loadTasks() {
     try {
-        tasks = await api('GET', '/tasks');
-        if (!Array.isArray(tasks)) tasks = [];
+        // Команды — до остального: от выбора зависят все запросы ниже
+        workspaces = await api('GET', '/workspaces');
+        // Если из выбранной команды вышли — возвращаемся к личным задачам
+        if (activeWorkspace && !workspaces.some(w => String(w.id) === activeWorkspace)) {
+            selectWorkspace('');
+        }
+        renderWorkspaceSelect();
+        // Наблюдатель только смотрит — кнопка новой задачи ему не нужна
+        document.getElementById('add-task-btn').classList.toggle('hidden', !can('edit'));
+
+        // Список статусов нужен один раз — для кнопок смены статуса
+        if (statuses.length === 0) {
+            statuses = await api('GET', '/statuses');
+        }
+        if (priorities.length === 0) {
+            priorities = await api('GET', '/priorities');
+        }
+        tags = await api('GET', '/tags');
+        projects = await api('GET', '/projects');
+        // Если выбранного проекта больше нет — показываем все задачи
+        if (activeProject !== null && !projects.some(p => p.id === activeProject)) activeProject = null;
+        // Если выбранного тега больше нет — показываем все задачи
+        if (activeTag && !tags.some(t => t.name === activeTag)) activeTag = '';
+
+        if (searchQuery) {
+            // Поиск: сервер сортирует по релевантности, тег фильтруем здесь
+            tasks = await api('GET', '/tasks/search?q=' + encodeURIComponent(searchQuery));
+            if (!Array.isArray(tasks)) tasks = [];
+            if (activeTag) tasks = tasks.filter(t => (t.tags || []).includes(activeTag));
+            if (activeProject !== null) tasks = tasks.filter(t => t.project_id === activeProject);
+        } else {
+            // Сервер сортирует: сначала важные, затем по сроку
+            let path = '/tasks?sort=priority';
+            if (activeTag) path += '&tag=' + encodeURIComponent(activeTag);
+            if (activeProject !== null) path += '&project=' + activeProject;
+            tasks = await api('GET', path);
+            if (!Array.isArray(tasks)) tasks = [];
+        }
+        // «Назначено мне / мной» — в пределах текущей команды
+        if (assignedView === 'to_me') tasks = tasks.filter(t => t.assignee === myId);
+        if (assignedView === 'by_me') tasks = tasks.filter(t => t.assigned_by === myId && t.assignee && t.assignee !== myId);
         renderTasks();
     } catch (err) {
         console.error('Ошибка загрузки задач:', err);
@@ -200,10 +592,13 @@ async function loadTasks() {
     }
 }
 
-/** Создать новую задачу */
-async function createTask(title, description) {
+/** Создать новую задачу (deadline — строка ISO 8601 или пустая) */
+async function createTask(title, description, deadline, priority, tags, recurrence, projectId) {
     try {
-        await api('POST', '/tasks', { title, description });
+        const body = { title, description, priority, tags, project_id: projectId };
+        if (deadline) body.deadline = deadline;
+        if (recurrence) body.recurrence = recurrence;
+        await api('POST', '/tasks', body);
 
         // Тактильная обратная связь (вибрация)
         try { tg.HapticFeedback.notificationOccurred('success'); } catch(e) {}
@@ -218,11 +613,16 @@ async function createTask(title, description) {
 /** Изменить статус задачи */
 async function changeStatus(taskId, status) {
     try {
-        await api('PATCH', `/tasks/${taskId}/status`, { status });
+        const result = await api('PATCH', `/tasks/${taskId}/status`, { status });
 
         // Лёгкая вибрация
         try { tg.HapticFeedback.impactOccurred('light'); } catch(e) {}
 
+        // Повторяющаяся задача: сервер уже создал следующую
+        if (result.next_task) {
+            tg.showAlert(`🔁 Следующий повтор: до ${formatDate(result.next_task.deadline)}`);
+        }
+
         // Перезагружаем задачи и обновляем экран деталей
         await loadTasks();
         const updated = tasks.find(t => t.id === taskId);
@@ -236,6 +636,413 @@ async function changeStatus(taskId, status) {
     }
 }
 
+/** Перезагрузить задачи и перерисовать открытую карточку */
+async function reloadTaskDetail(taskId) {
+    await loadTasks();
+    const updated = tasks.find(t => t.id === taskId);
+    if (updated) {
+        currentTask = updated;
+        renderTaskDetail(updated);
+    }
+}
+
+/** Добавить пункт чек-листа (из формы в карточке задачи) */
+async function addChecklistItem(event, taskId) {
+    event.preventDefault();
+    const input = document.getElementById('checklist-new');
+    const text = input.value.trim();
+    if (!text) return;
+    try {
+        await api('POST', `/tasks/${taskId}/checklist`, { text });
+        await reloadTaskDetail(taskId);
+    } catch (err) {
+        console.error('Ошибка добавления пункта:', err);
+        tg.showAlert('Ошибка добавления пункта: ' + err.message);
+    }
+}
+
+/** Отметить пункт чек-листа или снять отметку */
+async function toggleChecklistItem(taskId, itemId) {
+    try {
+        await api('POST', `/tasks/${taskId}/checklist/${itemId}/toggle`);
+        try { tg.HapticFeedback.selectionChanged(); } catch(e) {}
+        await reloadTaskDetail(taskId);
+    } catch (err) {
+        console.error('Ошибка обновления пункта:', err);
+        tg.showAlert('Ошибка обновления пункта: ' + err.message);
+    }
+}
+
+/** Удалить пункт чек-листа */
+async function removeChecklistItem(taskId, itemId) {
+    try {
+        await api('DELETE', `/tasks/${taskId}/checklist/${itemId}`);
+        await reloadTaskDetail(taskId);
+    } catch (err) {
+        console.error('Ошибка удаления пункта:', err);
+        tg.showAlert('Ошибка удаления пункта: ' + err.message);
+    }
+}
+
+/** Добавить блокирующую задачу (value — ID из выпадающего списка) */
+async function addDependency(taskId, value) {
+    if (!value) return;
+    try {
+        await api('POST', `/tasks/${taskId}/dependencies`, { blocker_id: Number(value) });
+        await reloadTaskDetail(taskId);
+    } catch (err) {
+        console.error('Ошибка добавления зависимости:', err);
+        tg.showAlert('Ошибка добавления зависимости: ' + err.message);
+        renderTaskDetail(currentTask); // Сбрасываем выбор в списке
+    }
+}
+
+/** Убрать блокирующую задачу */
+async function removeDependency(taskId, blockerId) {
+    try {
+        await api('DELETE', `/tasks/${taskId}/dependencies/${blockerId}`);
+        await reloadTaskDetail(taskId);
+    } catch (err) {
+        console.error('Ошибка удаления зависимости:', err);
+        tg.showAlert('Ошибка удаления зависимости: ' + err.message);
+    }
+}
+
+/** Учтённое время: "1 ч 20 мин" */
+function formatTracked(seconds) {
+    const minutes = Math.floor(seconds / 60);
+    const hours = Math.floor(minutes / 60);
+    if (hours === 0) return `${minutes} мин`;
+    return minutes % 60 ? `${hours} ч ${minutes % 60} мин` : `${hours} ч`;
+}
+
+/** Загрузить учёт времени задачи: итог, кнопку таймера и время по дням */
+async function loadTime(task) {
+    const container = document.getElementById('task-time');
+    try {
+        const report = await api('GET', `/tasks/${task.id}/time`);
+        const timer = can('edit') && (report.running || !isDone(task))
+            ? `<button class="btn-secondary" onclick="toggleTimer(${task.id}, ${report.running})">
+                   ${report.running ? '⏹ Остановить таймер' : '⏱ Запустить таймер'}
+               </button>`
+            : '';
+        container.innerHTML = `
+            <div class="time-total">Всего: ${formatTracked(report.total_seconds)}</div>
+            ${timer}
+            ${report.days.map(d => `
+                <div class="history-event">
+                    ${d.date.split('-').reverse().join('.')} — ${formatTracked(d.seconds)}
+                </div>
+            `).join('')}
+        `;
+    } catch (err) {
+        console.error('Ошибка загрузки учёта времени:', err);
+        container.innerHTML = '';
+    }
+}
+
+/** Запустить или остановить свой таймер задачи */
+async function toggleTimer(taskId, running) {
+    try {
+        await api('POST', `/tasks/${taskId}/timer/${running ? 'stop' : 'start'}`);
+        try { tg.HapticFeedback.impactOccurred('light'); } catch(e) {}
+        await loadTime(currentTask);
+    } catch (err) {
+        console.error('Ошибка таймера:', err);
+        tg.showAlert('Ошибка таймера: ' + err.message);
+    }
+}
+
+/** Добавить время вручную (заканчивается сейчас) */
+async function addTime(event, taskId) {
+    event.preventDefault();
+    const input = document.getElementById('time-minutes');
+    const minutes = Number(input.value);
+    if (!minutes) return;
+    try {
+        await api('POST', `/tasks/${taskId}/time`, { minutes });
+        input.value = '';
+        await loadTime(currentTask);
+    } catch (err) {
+        console.error('Ошибка добавления времени:', err);
+        tg.showAlert('Ошибка добавления времени: ' + err.message);
+    }
+}
+
+/** Загрузить список вложений задачи */
+async function loadAttachments(taskId) {
+    const container = document.getElementById('task-attachments');
+    try {
+        const attachments = await api('GET', `/tasks/${taskId}/attachments`);
+        if (!Array.isArray(attachments) || attachments.length === 0) {
+            container.innerHTML = '<div class="history-empty">Файлов пока нет</div>';
+            return;
+        }
+        container.innerHTML = attachments.map(a => `
+            <div class="checklist-item">
+                <a href="#" class="attachment-link" data-name="${escapeHtml(a.name)}"
+                   onclick="downloadAttachment(event, ${taskId}, ${a.id}, this.dataset.name)">
+                    ${a.kind === 'photo' ? '🖼' : '📄'} ${escapeHtml(a.name)}
+                    <span class="history-date">
+                        ${a.size ? formatSize(a.size) + ' · ' : ''}${a.author_name ? escapeHtml(a.author_name) + ' · ' : ''}${formatDate(a.created_at)}
+                    </span>
+                    ${a.caption ? `<span class="attachment-caption">${escapeHtml(a.caption)}</span>` : ''}
+                </a>
+                ${can('edit') ? `<button class="btn-item-remove" onclick="deleteAttachment(${taskId}, ${a.id})">✕</button>` : ''}
+            </div>
+        `).join('');
+    } catch (err) {
+        console.error('Ошибка загрузки вложений:', err);
+        container.innerHTML = '';
+    }
+}
+
+/** Загрузить выбранный файл на сервер (место считается по квоте пользователя) */
+async function uploadAttachment(event, taskId) {
+    event.preventDefault();
+    const input = document.getElementById('attachment-file');
+    const file = input.files[0];
+    if (!file) return;
+    const formData = new FormData();
+    formData.append('file', file);
+    try {
+        await apiUpload(`/tasks/${taskId}/attachments`, formData);
+        input.value = '';
+        await loadAttachments(taskId);
+    } catch (err) {
+        console.error('Ошибка загрузки файла:', err);
+        tg.showAlert('Ошибка загрузки файла: ' + err.message);
+    }
+}
+
+/**
+ * Скачать вложение: ссылка требует заголовков авторизации, поэтому
+ * получаем файл через fetch и сохраняем его из памяти
+ */
+async function downloadAttachment(event, taskId, attachmentId, name) {
+    event.preventDefault();
+    try {
+        const path = `/tasks/${taskId}/attachments/${attachmentId}`;
+        const response = await fetch(API_BASE + path, { headers: apiHeaders() });
+        if (!response.ok) {
+            const data = await response.json().catch(() => ({}));
+            throw new Error(data.error || 'Ошибка сервера');
+        }
+        const blob = await response.blob();
+        const link = document.createElement('a');
+        link.href = URL.createObjectURL(blob);
+        link.download = name || 'file';
+        link.click();
+        setTimeout(() => URL.revokeObjectURL(link.href), 1000);
+    } catch (err) {
+        console.error('Ошибка скачивания файла:', err);
+        tg.showAlert('Ошибка скачивания файла: ' + err.message);
+    }
+}
+
+/** Убрать вложение (файл на сервере удаляется и освобождает место) */
+async function deleteAttachment(taskId, attachmentId) {
+    try {
+        await api('DELETE', `/tasks/${taskId}/attachments/${attachmentId}`);
+        await loadAttachments(taskId);
+    } catch (err) {
+        console.error('Ошибка удаления вложения:', err);
+        tg.showAlert('Ошибка удаления вложения: ' + err.message);
+    }
+}
+
+/** Загрузить комментарии к задаче (новые — внизу, как в чате) */
+async function loadComments(taskId) {
+    const container = document.getElementById('task-comments');
+    try {
+        const comments = await api('GET', `/tasks/${taskId}/comments`);
+        if (!Array.isArray(comments) || comments.length === 0) {
+            container.innerHTML = '<div class="history-empty">Комментариев пока нет</div>';
+            return;
+        }
+        container.innerHTML = comments.map(c => `
+            <div class="history-event">
+                <span class="history-date">
+                    ${escapeHtml(c.author_name || 'Вы')} · ${formatDate(c.created_at)}
+                </span>
+                ${escapeHtml(c.text)}
+            </div>
+        `).join('');
+    } catch (err) {
+        console.error('Ошибка загрузки комментариев:', err);
+        container.innerHTML = '';
+    }
+}
+
+/** Добавить комментарий (участники команды получат его в Telegram) */
+async function addComment(event, taskId) {
+    event.preventDefault();
+    const input = document.getElementById('comment-new');
+    const text = input.value.trim();
+    if (!text) return;
+    try {
+        await api('POST', `/tasks/${taskId}/comments`, { text });
+        input.value = '';
+        await loadComments(taskId);
+    } catch (err) {
+        console.error('Ошибка добавления комментария:', err);
+        tg.showAlert('Ошибка добавления комментария: ' + err.message);
+    }
+}
+
+/** Загрузить и показать историю изменений задачи (новые события сверху) */
+async function loadHistory(taskId) {
+    const container = document.getElementById('task-history');
+    try {
+        const events = await api('GET', `/tasks/${taskId}/history`);
+        if (!Array.isArray(events) || events.length === 0) {
+            container.innerHTML = '<div class="history-empty">История пока пуста</div>';
+            return;
+        }
+        container.innerHTML = events.slice().reverse().map(e => `
+            <div class="history-event">
+                <span class="history-date">${formatDate(e.at)}</span>
+                ${escapeHtml(e.text)}
+            </div>
+        `).join('');
+    } catch (err) {
+        tg.showAlert('Ошибка: ' + err.message);
+    }
+}
+
+/** Загрузить и показать задачи в корзине */
+async function loadTrash() {
+    const container = document.getElementById('trash-container');
+    container.innerHTML = '<div class="loading">Загрузка...</div>';
+    try {
+        const trash = await api('GET', '/trash');
+        if (!Array.isArray(trash) || trash.length === 0) {
+            container.innerHTML = '<div class="loading">Корзина пуста</div>';
+            return;
+        }
+        container.innerHTML = trash.map(task => `
+            <div class="task-card trash-card">
+                <div class="task-card-header">
+                    <span class="task-card-title">${escapeHtml(priorityIcon(task))} ${escapeHtml(task.title)}</span>
+                    <span class="task-card-status">${escapeHtml(task.status_label)}</span>
+                </div>
+                ${task.description ? `<div class="task-card-desc">${escapeHtml(task.description)}</div>` : ''}
+                <div class="task-card-date">Удалена ${formatDate(task.deleted_at)} · исчезнет ${formatDate(task.purge_at)}</div>
+                <button class="btn-primary" onclick="restoreTask(${task.id}, true)">♻️ Восстановить</button>
+            </div>
+        `).join('');
+    } catch (err) {
+        container.innerHTML = '';
+        tg.showAlert('Ошибка загрузки корзины: ' + err.message);
+    }
+}
+
+/** Вернуть задачу из корзины (fromTrash — обновить экран корзины, иначе — список) */
+async function restoreTask(taskId, fromTrash) {
+    try {
+        await api('POST', `/tasks/${taskId}/restore`);
+        try { tg.HapticFeedback.notificationOccurred('success'); } catch(e) {}
+        if (fromTrash) {
+            loadTrash();
+        } else {
+            loadTasks();
+        }
+    } catch (err) {
+        tg.showAlert('Ошибка восстановления: ' + err.message);
+    }
+}
+
+/** Запомнить выбранное пространство ('' — личные задачи) */
+function selectWorkspace(id) {
+    activeWorkspace = id;
+    if (id) {
+        localStorage.setItem('workspace', id);
+    } else {
+        localStorage.removeItem('workspace');
+    }
+    // Статусы, проекты и теги у каждого пространства свои
+    statuses = [];
+    activeProject = null;
+    activeTag = '';
+    assignedView = '';
+}
+
+/** Переключиться на личные задачи или задачи команды */
+function changeWorkspace(id) {
+    selectWorkspace(id);
+    loadTasks();
+}
+
+/** Показать только задачи с тегом ('' — все задачи) */
+function filterByTag(tag) {
+    activeTag = tag;
+    loadTasks();
+}
+
+/** Показать только задачи проекта (null — все проекты) */
+function filterByProject(projectId) {
+    activeProject = projectId;
+    loadTasks();
+}
+
+/** Показать задачи, назначенные мне ('to_me') или мной ('by_me'); '' — все */
+function filterByAssigned(view) {
+    assignedView = view;
+    loadTasks();
+}
+
+/** Назначить исполнителя задачи (0 — снять) */
+async function changeAssignee(taskId, userId) {
+    try {
+        await api('PATCH', `/tasks/${taskId}`, { assignee_id: Number(userId) });
+        await reloadTaskDetail(taskId);
+    } catch (err) {
+        console.error('Ошибка назначения исполнителя:', err);
+        tg.showAlert('Ошибка назначения исполнителя: ' + err.message);
+    }
+}
+
+/** Перенести задачу в другой проект */
+async function changeProject(taskId, projectId) {
+    try {
+        await api('PATCH', `/tasks/${taskId}`, { project_id: Number(projectId) });
+        await reloadTaskDetail(taskId);
+    } catch (err) {
+        console.error('Ошибка переноса задачи:', err);
+        tg.showAlert('Ошибка переноса задачи: ' + err.message);
+    }
+}
+
+/** Изменить приоритет задачи */
+async function changePriority(taskId, priority) {
+    try {
+        await api('PATCH', `/tasks/${taskId}`, { priority });
+
+        try { tg.HapticFeedback.impactOccurred('light'); } catch(e) {}
+
+        await loadTasks();
+        const updated = tasks.find(t => t.id === taskId);
+        if (updated) {
+            currentTask = updated;
+            renderTaskDetail(updated);
+        }
+    } catch (err) {
+        console.error('Ошибка обновления приоритета:', err);
+        tg.showAlert('Ошибка обновления приоритета: ' + err.message);
+    }
+}
+
+/** Изменить правило повторения ('' — не повторять) */
+async function changeRecurrence(taskId, rule) {
+    try {
+        await api('PATCH', `/tasks/${taskId}`, { recurrence: rule || null });
+        await reloadTaskDetail(taskId);
+    } catch (err) {
+        console.error('Ошибка обновления повтора:', err);
+        tg.showAlert('Ошибка обновления повтора: ' + err.message);
+    }
+}
+
 /** Удалить задачу */
 function deleteTask(taskId) {
     tg.showConfirm('Удалить эту задачу?', async function(confirmed) {
@@ -247,6 +1054,17 @@ function deleteTask(taskId) {
             try { tg.HapticFeedback.notificationOccurred('success'); } catch(e) {}
 
             showTaskList();
+
+            // Задача в корзине — сразу предлагаем отменить удаление
+            tg.showPopup({
+                message: 'Задача перемещена в корзину',
+                buttons: [
+                    { id: 'undo', type: 'default', text: '↩️ Отменить' },
+                    { type: 'ok' },
+                ],
+            }, function(buttonId) {
+                if (buttonId === 'undo') restoreTask(taskId, false);
+            });
         } catch (err) {
             console.error('Ошибка удаления задачи:', err);
             tg.showAlert('Ошибка удаления задачи: ' + err.message);
@@ -258,12 +1076,37 @@ function deleteTask(taskId) {
 // 7. ВСПОМОГАТЕЛЬНЫЕ ФУНКЦИИ
 // ============================================================
 
-/** Определить короткий ключ статуса по полному тексту */
-function getStatusKey(status) {
-    if (status.includes('Новая')) return 'new';
-    if (status.includes('В работе')) return 'progress';
-    if (status.includes('Выполнена')) return 'done';
-    return 'new';
+/** Задача завершена: статус из категории done (у пользователя их может быть несколько) */
+function isDone(task) {
+    return task.status_category === 'done';
+}
+
+/** Срок прошёл, а задача не выполнена */
+function isOverdue(task) {
+    return task.deadline && !isDone(task) && new Date(task.deadline) < new Date();
+}
+
+/** Задача ждёт невыполненные задачи (⛔) */
+function isBlocked(task) {
+    if (isDone(task) || !task.blocked_by) return false;
+    return task.blocked_by.some(id => {
+        const blocker = tasks.find(t => t.id === id);
+        return blocker && !isDone(blocker);
+    });
+}
+
+/** Кнопки статуса: текущий и те, в которые из него можно перейти */
+function allowedStatuses(task) {
+    const current = statuses.find(s => s.code === task.status);
+    const next = (current && current.next) || [];
+    return statuses.filter(s =>
+        s.code === task.status || next.length === 0 || next.includes(s.code));
+}
+
+/** Значок приоритета задачи (из списка приоритетов сервера) */
+function priorityIcon(task) {
+    const p = priorities.find(p => p.code === task.priority);
+    return p ? p.icon : '';
 }
 
 /** Форматировать дату в читаемый вид */
@@ -279,6 +1122,13 @@ function formatDate(dateStr) {
     });
 }
 
+/** Размер файла для людей: "340 КБ", "2.5 МБ" (как в боте) */
+function formatSize(bytes) {
+    if (bytes >= 1 << 20) return (bytes / (1 << 20)).toFixed(1) + ' МБ';
+    if (bytes >= 1 << 10) return Math.floor(bytes / (1 << 10)) + ' КБ';
+    return bytes + ' Б';
+}
+
 /** Экранировать HTML-спецсимволы (защита от XSS) */
 function escapeHtml(text) {
     if (!text) return '';
@@ -294,13 +1144,36 @@ function escapeHtml(text) {
 // Кнопка "Новая задача"
 document.getElementById('add-task-btn').addEventListener('click', showCreateForm);
 
+// Кнопка "Корзина"
+document.getElementById('trash-btn').addEventListener('click', showTrash);
+
+// Поиск по задачам — запрос уходит через 300 мс после последнего нажатия
+document.getElementById('search-input').addEventListener('input', function(e) {
+    clearTimeout(searchTimer);
+    searchTimer = setTimeout(() => {
+        searchQuery = e.target.value.trim();
+        loadTasks();
+    }, 300);
+});
+
 // Отправка формы создания задачи
 document.getElementById('create-task-form').addEventListener('submit', function(e) {
     e.preventDefault();
     const title = document.getElementById('task-title').value.trim();
     const description = document.getElementById('task-description').value.trim();
+    // datetime-local даёт местное время без пояса — toISOString переводит в UTC (RFC 3339)
+    const deadlineValue = document.getElementById('task-deadline').value;
+    const deadline = deadlineValue ? new Date(deadlineValue).toISOString() : '';
+    const priority = document.getElementById('task-priority').value;
+    // "#матан, работа" → ["матан", "работа"] (остальное проверит сервер)
+    const tagList = document.getElementById('task-tags').value
+        .split(/[\s,]+/)
+        .map(t => t.replace(/^#/, ''))
+        .filter(Boolean);
+    const recurrence = document.getElementById('task-recurrence').value;
+    const projectId = Number(document.getElementById('task-project').value);
     if (title) {
-        createTask(title, description);
+        createTask(title, description, deadline, priority, tagList, recurrence, projectId);
     }
 });
 
@@ -308,8 +1181,10 @@ document.getElementById('create-task-form').addEventListener('submit', function(
 tg.BackButton.onClick(function() {
     const detailView = document.getElementById('task-detail-view');
     const createView = document.getElementById('create-task-view');
+    const trashView = document.getElementById('trash-view');
 
-    if (!detailView.classList.contains('hidden') || !createView.classList.contains('hidden')) {
+    if (!detailView.classList.contains('hidden') || !createView.classList.contains('hidden') ||
+        !trashView.classList.contains('hidden')) {
         showTaskList();
     } else {
         tg.close();